@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"github.com/gin-gonic/gin"
 	"reimbursement-backend/config"
 	"reimbursement-backend/internal/database"
+	"reimbursement-backend/internal/drafts"
 	"reimbursement-backend/internal/handlers"
 	"reimbursement-backend/internal/middleware"
 	"reimbursement-backend/internal/models"
 	"reimbursement-backend/internal/repository"
+	"reimbursement-backend/internal/sla"
 	"reimbursement-backend/pkg/utils"
 )
 
@@ -26,7 +29,7 @@ func main() {
 	defer db.Close()
 
 	// Run migrations
-	if err := db.RunMigrations(); err != nil {
+	if err := db.RunMigrations(cfg.Currency.Base); err != nil {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
@@ -36,14 +39,61 @@ func main() {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db.DB)
 	reimbRepo := repository.NewReimbursementRepository(db.DB)
+	paymentRepo := repository.NewPaymentRepository(db.DB)
+	chainRepo := repository.NewApprovalChainRepository(db.DB)
+	delegationRepo := repository.NewDelegationRepository(db.DB)
+	notificationRepo := repository.NewNotificationRepository(db.DB)
+	ruleRepo := repository.NewDutyRuleRepository(db.DB)
+	commentRepo := repository.NewCommentRepository(db.DB)
+	eventRepo := repository.NewEventRepository(db.DB)
+	versionRepo := repository.NewVersionRepository(db.DB)
+	rateRepo := repository.NewExchangeRateRepository(db.DB)
+	reportRepo := repository.NewReportRepository(db.DB)
+	mileageRepo := repository.NewMileageRateRepository(db.DB)
+	perDiemRepo := repository.NewPerDiemRateRepository(db.DB)
+	categoryRepo := repository.NewCategoryRepository(db.DB)
+	costCenterRepo := repository.NewCostCenterRepository(db.DB)
+	projectRepo := repository.NewProjectRepository(db.DB)
+	advanceRepo := repository.NewAdvanceRepository(db.DB)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(userRepo, cfg)
-	reimbHandler := handlers.NewReimbursementHandler(reimbRepo, userRepo)
+	submission := models.SubmissionWindow{
+		MaxAgeDays:    cfg.Submission.MaxAgeDays,
+		MonthCloseDay: cfg.Submission.MonthCloseDay,
+		BlockLate:     cfg.Submission.BlockLate,
+	}
+	reimbHandler := handlers.NewReimbursementHandler(reimbRepo, userRepo, chainRepo, delegationRepo, ruleRepo, eventRepo, versionRepo, rateRepo, mileageRepo, perDiemRepo, categoryRepo, costCenterRepo, projectRepo, advanceRepo, cfg.Currency.Base, cfg.Mileage.MonthlyCapKm, submission)
+	paymentHandler := handlers.NewPaymentHandler(paymentRepo, reimbRepo)
+	chainHandler := handlers.NewApprovalChainHandler(chainRepo, userRepo, categoryRepo)
+	delegationHandler := handlers.NewDelegationHandler(delegationRepo, userRepo)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo)
+	ruleHandler := handlers.NewDutyRuleHandler(ruleRepo)
+	commentHandler := handlers.NewCommentHandler(commentRepo, reimbRepo, "./uploads")
+	rateHandler := handlers.NewExchangeRateHandler(rateRepo, cfg.Currency.Base)
+	reportHandler := handlers.NewReportHandler(reportRepo, cfg.Currency.Base)
+	mileageHandler := handlers.NewMileageRateHandler(mileageRepo)
+	perDiemHandler := handlers.NewPerDiemRateHandler(perDiemRepo)
+	categoryHandler := handlers.NewCategoryHandler(categoryRepo)
+	costCenterHandler := handlers.NewCostCenterHandler(costCenterRepo, userRepo)
+	projectHandler := handlers.NewProjectHandler(projectRepo, costCenterRepo)
+	advanceHandler := handlers.NewAdvanceHandler(advanceRepo, userRepo, delegationRepo)
 	uploadHandler := handlers.NewUploadHandler("./uploads")
 
+	// Start the SLA worker that reminds approvers and escalates stale claims
+	if cfg.SLA.Enabled {
+		worker := sla.NewWorker(db.DB, cfg.SLA, reimbRepo, userRepo, chainRepo, notificationRepo)
+		go worker.Run(context.Background())
+	}
+
+	// Start the clean-up of drafts that were never submitted
+	if cfg.Drafts.RetentionDays > 0 {
+		cleaner := drafts.NewCleaner(cfg.Drafts, reimbRepo)
+		go cleaner.Run(context.Background())
+	}
+
 	// Setup router
-	router := setupRouter(cfg, authHandler, reimbHandler, uploadHandler)
+	router := setupRouter(cfg, authHandler, reimbHandler, paymentHandler, chainHandler, delegationHandler, notificationHandler, ruleHandler, commentHandler, rateHandler, reportHandler, mileageHandler, perDiemHandler, categoryHandler, costCenterHandler, projectHandler, advanceHandler, uploadHandler)
 
 	// Start server
 	addr := cfg.Server.Host + ":" + cfg.Server.Port
@@ -53,7 +103,7 @@ func main() {
 	}
 }
 
-func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, reimbHandler *handlers.ReimbursementHandler, uploadHandler *handlers.UploadHandler) *gin.Engine {
+func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, reimbHandler *handlers.ReimbursementHandler, paymentHandler *handlers.PaymentHandler, chainHandler *handlers.ApprovalChainHandler, delegationHandler *handlers.DelegationHandler, notificationHandler *handlers.NotificationHandler, ruleHandler *handlers.DutyRuleHandler, commentHandler *handlers.CommentHandler, rateHandler *handlers.ExchangeRateHandler, reportHandler *handlers.ReportHandler, mileageHandler *handlers.MileageRateHandler, perDiemHandler *handlers.PerDiemRateHandler, categoryHandler *handlers.CategoryHandler, costCenterHandler *handlers.CostCenterHandler, projectHandler *handlers.ProjectHandler, advanceHandler *handlers.AdvanceHandler, uploadHandler *handlers.UploadHandler) *gin.Engine {
 	router := gin.Default()
 
 	// Apply CORS middleware
@@ -82,15 +132,51 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, reimbHan
 		protected.GET("/reimbursements", reimbHandler.GetAll)
 		protected.GET("/reimbursements/:id", reimbHandler.GetByID)
 		protected.GET("/reimbursements/stats", reimbHandler.GetStats)
+		protected.GET("/reimbursements/:id/payments", paymentHandler.GetPayments)
+		protected.GET("/reimbursements/:id/history", reimbHandler.GetHistory)
+		protected.GET("/reimbursements/:id/versions", reimbHandler.GetVersions)
+		protected.GET("/reimbursements/:id/versions/diff", reimbHandler.GetVersionDiff)
+		protected.GET("/notifications", notificationHandler.GetMine)
+		protected.GET("/reimbursements/:id/comments", commentHandler.GetComments)
+		protected.POST("/reimbursements/:id/comments", commentHandler.CreateComment)
+		protected.GET("/reimbursements/:id/comments/unread-count", commentHandler.GetUnreadCount)
+		protected.POST("/upload/attachment", uploadHandler.UploadAttachment)
+		protected.GET("/exchange-rates", rateHandler.GetAll)
+		protected.GET("/mileage-rates", mileageHandler.GetAll)
+		protected.GET("/per-diem-rates", perDiemHandler.GetAll)
+		protected.GET("/categories", categoryHandler.GetAll)
+		protected.GET("/cost-centers", costCenterHandler.GetAll)
+		protected.GET("/projects", projectHandler.GetAll)
+		protected.GET("/cash-advances", advanceHandler.GetAll)
+		protected.GET("/cash-advances/:id", advanceHandler.GetByID)
+		protected.GET("/cash-advances/:id/history", advanceHandler.GetHistory)
 
 		// Reimbursements - Employee only
 		employee := protected.Group("")
 		employee.Use(middleware.RequireRole(models.RoleEmployee))
 		{
 			employee.POST("/reimbursements", reimbHandler.Create)
+			employee.POST("/reimbursements/drafts", reimbHandler.SaveDraft)
+			employee.POST("/reimbursements/:id/submit", reimbHandler.Submit)
 			employee.PUT("/reimbursements/:id", reimbHandler.Update)
-			employee.DELETE("/reimbursements/:id", reimbHandler.Delete)
+			employee.POST("/reimbursements/:id/cancel", reimbHandler.Cancel)
+			employee.DELETE("/reimbursements/:id", reimbHandler.Cancel)
+			employee.POST("/reimbursements/:id/resubmit", reimbHandler.Resubmit)
 			employee.POST("/upload/receipt", uploadHandler.UploadReceipt)
+			employee.POST("/cash-advances", advanceHandler.Create)
+			employee.POST("/cash-advances/:id/cancel", advanceHandler.Cancel)
+		}
+
+		// Reimbursements - Approvers (the approval chain decides who acts on each step)
+		approver := protected.Group("")
+		approver.Use(middleware.RequireRole(models.RoleManager, models.RoleFinance))
+		{
+			approver.POST("/reimbursements/:id/approve", reimbHandler.Approve)
+			approver.POST("/reimbursements/bulk-approve", reimbHandler.BulkApprove)
+			approver.GET("/delegations", delegationHandler.GetMine)
+			approver.POST("/delegations", delegationHandler.Create)
+			approver.DELETE("/delegations/:id", delegationHandler.Revoke)
+			approver.POST("/cash-advances/:id/approve", advanceHandler.Decide)
 		}
 
 		// Reimbursements - Manager only
@@ -98,7 +184,8 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, reimbHan
 		manager.Use(middleware.RequireRole(models.RoleManager))
 		{
 			manager.GET("/manager/pending", reimbHandler.GetPendingForManager)
-			manager.POST("/manager/reimbursements/:id/approve", reimbHandler.ManagerApproval)
+			manager.POST("/manager/reimbursements/:id/approve", reimbHandler.Approve)
+			manager.POST("/manager/reimbursements/bulk-approve", reimbHandler.BulkApprove)
 		}
 
 		// Reimbursements - Finance only
@@ -106,7 +193,38 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, reimbHan
 		finance.Use(middleware.RequireRole(models.RoleFinance))
 		{
 			finance.GET("/finance/pending", reimbHandler.GetPendingForFinance)
-			finance.POST("/finance/reimbursements/:id/approve", reimbHandler.FinanceApproval)
+			finance.POST("/finance/reimbursements/:id/approve", reimbHandler.Approve)
+			finance.POST("/finance/reimbursements/bulk-approve", reimbHandler.BulkApprove)
+			finance.GET("/finance/awaiting-payment", paymentHandler.GetAwaitingPayment)
+			finance.POST("/finance/reimbursements/:id/pay", paymentHandler.MarkPaid)
+			finance.POST("/finance/reimbursements/:id/reverse-payment", paymentHandler.ReversePayment)
+			finance.PUT("/users/:id/manager", authHandler.SetManager)
+			finance.POST("/approval-chains", chainHandler.Create)
+			finance.DELETE("/approval-chains/:id", chainHandler.Deactivate)
+			finance.PUT("/duty-rules/:code", ruleHandler.Update)
+			finance.GET("/finance/duty-violations", ruleHandler.GetViolations)
+			finance.DELETE("/admin/reimbursements/:id", reimbHandler.Purge)
+			finance.POST("/exchange-rates", rateHandler.Save)
+			finance.POST("/exchange-rates/import", rateHandler.Import)
+			finance.DELETE("/exchange-rates/:id", rateHandler.Delete)
+			finance.GET("/finance/reports/tax", reportHandler.GetTaxReport)
+			finance.GET("/finance/reports/allocations", reportHandler.GetAllocationReport)
+			finance.GET("/finance/reports/advances", reportHandler.GetAdvanceReport)
+			finance.POST("/finance/cash-advances/:id/disburse", advanceHandler.Disburse)
+			finance.POST("/finance/cash-advances/:id/settle", advanceHandler.Settle)
+			finance.POST("/mileage-rates", mileageHandler.Save)
+			finance.DELETE("/mileage-rates/:id", mileageHandler.Delete)
+			finance.POST("/per-diem-rates", perDiemHandler.Save)
+			finance.DELETE("/per-diem-rates/:id", perDiemHandler.Delete)
+			finance.POST("/categories", categoryHandler.Create)
+			finance.PUT("/categories/:code", categoryHandler.Update)
+			finance.DELETE("/categories/:code", categoryHandler.Deactivate)
+			finance.POST("/cost-centers", costCenterHandler.Create)
+			finance.PUT("/cost-centers/:id", costCenterHandler.Update)
+			finance.DELETE("/cost-centers/:id", costCenterHandler.Deactivate)
+			finance.POST("/projects", projectHandler.Create)
+			finance.PUT("/projects/:id", projectHandler.Update)
+			finance.DELETE("/projects/:id", projectHandler.Deactivate)
 		}
 
 		// Admin routes - Manager and Finance
@@ -114,6 +232,9 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, reimbHan
 		admin.Use(middleware.RequireRole(models.RoleManager, models.RoleFinance))
 		{
 			admin.GET("/users", authHandler.GetAllUsers)
+			admin.GET("/approval-chains", chainHandler.GetAll)
+			admin.GET("/approval-chains/:id", chainHandler.GetByID)
+			admin.GET("/duty-rules", ruleHandler.GetAll)
 		}
 	}
 
@@ -128,8 +249,8 @@ func seedDefaultUsers(db *database.Database, userRepo *repository.UserRepository
 		email    string
 		role     models.UserRole
 	}{
-		{"karyawan", "karyawan123", "Employee User", "karyawan@company.com", models.RoleEmployee},
 		{"manager", "manager123", "Manager User", "manager@company.com", models.RoleManager},
+		{"karyawan", "karyawan123", "Employee User", "karyawan@company.com", models.RoleEmployee},
 		{"finance", "finance123", "Finance User", "finance@company.com", models.RoleFinance},
 	}
 
@@ -150,11 +271,17 @@ func seedDefaultUsers(db *database.Database, userRepo *repository.UserRepository
 		})
 	}
 
+	// Default employees report to the default manager
+	var defaultManagerID *int
+
 	for _, u := range defaultUsers {
 		// Check if user exists
-		_, err := userRepo.GetByUsername(u.username)
+		existing, err := userRepo.GetByUsername(u.username)
 		if err == nil {
 			// User already exists
+			if existing.Role == models.RoleManager && defaultManagerID == nil {
+				defaultManagerID = &existing.ID
+			}
 			continue
 		}
 
@@ -173,11 +300,17 @@ func seedDefaultUsers(db *database.Database, userRepo *repository.UserRepository
 			Email:    u.email,
 			Role:     u.role,
 		}
+		if u.role == models.RoleEmployee {
+			user.ManagerID = defaultManagerID
+		}
 
 		if err := userRepo.Create(user); err != nil {
 			log.Printf("Failed to create user %s: %v", u.username, err)
 		} else {
 			log.Printf("Created default user: %s", u.username)
+			if u.role == models.RoleManager && defaultManagerID == nil {
+				defaultManagerID = &user.ID
+			}
 		}
 	}
 }
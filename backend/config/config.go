@@ -2,14 +2,21 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	SLA        SLAConfig
+	Currency   CurrencyConfig
+	Mileage    MileageConfig
+	Submission SubmissionConfig
+	Drafts     DraftConfig
 }
 
 type ServerConfig struct {
@@ -31,8 +38,55 @@ type JWTConfig struct {
 	ExpireHour int
 }
 
+// SLAConfig controls the background worker that chases reimbursements
+// waiting too long on an approval step.
+type SLAConfig struct {
+	Enabled              bool
+	CheckIntervalMinutes int
+	Pending              StageSLA
+	ApprovedManager      StageSLA
+}
+
+// StageSLA holds the thresholds for one approval stage, counted from when
+// the claim entered its current step. A threshold of 0 disables that action.
+type StageSLA struct {
+	RemindAfterHours   int
+	EscalateAfterHours int
+}
+
+// CurrencyConfig holds the company base currency. Claims in other currencies
+// are converted to it, and stats and payments use the converted amounts.
+type CurrencyConfig struct {
+	Base string
+}
+
+// MileageConfig limits mileage claims. MonthlyCapKm is the most kilometres
+// an employee may claim for trips in one month; 0 disables the cap.
+type MileageConfig struct {
+	MonthlyCapKm int
+}
+
+// SubmissionConfig limits how late an expense may be claimed. An expense is
+// late if it is claimed more than MaxAgeDays days after it was spent, or
+// after its month was closed on day MonthCloseDay of the following month, or
+// its last day if shorter; 0 disables either check. Claims with late expenses are refused if
+// BlockLate, and flagged for the approvers otherwise.
+type SubmissionConfig struct {
+	MaxAgeDays    int
+	MonthCloseDay int
+	BlockLate     bool
+}
+
+// DraftConfig controls the clean-up of drafts their submitters never
+// submitted. Drafts last saved more than RetentionDays days ago are removed
+// every CheckIntervalMinutes; 0 keeps them.
+type DraftConfig struct {
+	RetentionDays        int
+	CheckIntervalMinutes int
+}
+
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8080"),
 			Host: getEnv("SERVER_HOST", "0.0.0.0"),
@@ -49,7 +103,39 @@ func Load() *Config {
 			Secret:     getEnv("JWT_SECRET", "your-secret-key-change-this-in-production"),
 			ExpireHour: getEnvAsInt("JWT_EXPIRE_HOUR", 24),
 		},
+		SLA: SLAConfig{
+			Enabled:              getEnvAsBool("SLA_ENABLED", true),
+			CheckIntervalMinutes: getEnvAsInt("SLA_CHECK_INTERVAL_MINUTES", 15),
+			Pending: StageSLA{
+				RemindAfterHours:   getEnvAsInt("SLA_PENDING_REMIND_HOURS", 24),
+				EscalateAfterHours: getEnvAsInt("SLA_PENDING_ESCALATE_HOURS", 72),
+			},
+			ApprovedManager: StageSLA{
+				RemindAfterHours:   getEnvAsInt("SLA_APPROVED_MANAGER_REMIND_HOURS", 24),
+				EscalateAfterHours: getEnvAsInt("SLA_APPROVED_MANAGER_ESCALATE_HOURS", 72),
+			},
+		},
+		Currency: CurrencyConfig{
+			Base: strings.ToUpper(getEnv("BASE_CURRENCY", "IDR")),
+		},
+		Mileage: MileageConfig{
+			MonthlyCapKm: getEnvAsInt("MILEAGE_MONTHLY_CAP_KM", 2000),
+		},
+		Submission: SubmissionConfig{
+			MaxAgeDays:    getEnvAsInt("SUBMISSION_MAX_AGE_DAYS", 90),
+			MonthCloseDay: getEnvAsInt("SUBMISSION_MONTH_CLOSE_DAY", 0),
+			BlockLate:     strings.ToLower(getEnv("SUBMISSION_LATE_ACTION", "flag")) == "block",
+		},
+		Drafts: DraftConfig{
+			RetentionDays:        getEnvAsInt("DRAFT_RETENTION_DAYS", 30),
+			CheckIntervalMinutes: getEnvAsInt("DRAFT_CHECK_INTERVAL_MINUTES", 60),
+		},
+	}
+
+	if day := cfg.Submission.MonthCloseDay; day < 0 || day > 31 {
+		log.Fatalf("SUBMISSION_MONTH_CLOSE_DAY must be a day of the month or 0, got %d", day)
 	}
+	return cfg
 }
 
 func (c *DatabaseConfig) ConnectionString() string {
@@ -73,3 +159,11 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"log"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"reimbursement-backend/config"
 )
 
@@ -39,7 +39,9 @@ func (d *Database) Close() error {
 	return d.DB.Close()
 }
 
-func (d *Database) RunMigrations() error {
+// RunMigrations brings the schema up to date. baseCurrency is the currency
+// claims made before multi-currency support are recorded in.
+func (d *Database) RunMigrations(baseCurrency string) error {
 	// This is a simple migration runner
 	// In production, consider using a proper migration tool like golang-migrate
 	
@@ -100,6 +102,470 @@ func (d *Database) RunMigrations() error {
 		`DROP TRIGGER IF EXISTS update_reimbursements_updated_at ON reimbursements`,
 		`CREATE TRIGGER update_reimbursements_updated_at BEFORE UPDATE ON reimbursements
 			FOR EACH ROW EXECUTE FUNCTION update_updated_at_column()`,
+
+		`CREATE TABLE IF NOT EXISTS reimbursement_payments (
+			id SERIAL PRIMARY KEY,
+			reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+			amount DECIMAL(12, 2) NOT NULL,
+			method VARCHAR(20) NOT NULL CHECK (method IN ('bank_transfer', 'cash', 'payroll')),
+			reference VARCHAR(100) NOT NULL,
+			payment_date DATE NOT NULL,
+			paid_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			reversed_at TIMESTAMP,
+			reversed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			reversal_reason TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursement_payments_reimbursement_id ON reimbursement_payments(reimbursement_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_reimbursement_payments_active ON reimbursement_payments(reimbursement_id) WHERE reversed_at IS NULL`,
+
+		`CREATE TABLE IF NOT EXISTS approval_chains (
+			id SERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			category VARCHAR(50),
+			min_amount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+			max_amount DECIMAL(12, 2),
+			priority INTEGER NOT NULL DEFAULT 0,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK (max_amount IS NULL OR max_amount > min_amount)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS approval_chain_steps (
+			id SERIAL PRIMARY KEY,
+			chain_id INTEGER NOT NULL REFERENCES approval_chains(id) ON DELETE CASCADE,
+			step_order INTEGER NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			required_role VARCHAR(20) CHECK (required_role IN ('manager', 'finance')),
+			required_user_id INTEGER REFERENCES users(id) ON DELETE RESTRICT,
+			UNIQUE (chain_id, step_order),
+			CHECK ((required_role IS NULL) <> (required_user_id IS NULL))
+		)`,
+
+		`INSERT INTO approval_chains (name, min_amount, priority)
+			SELECT 'Standard', 0, 0
+			WHERE NOT EXISTS (SELECT 1 FROM approval_chains)`,
+		`INSERT INTO approval_chain_steps (chain_id, step_order, name, required_role)
+			SELECT c.id, v.step_order, v.name, v.required_role
+			FROM approval_chains c
+			CROSS JOIN (VALUES (1, 'Manager approval', 'manager'), (2, 'Finance approval', 'finance')) AS v(step_order, name, required_role)
+			WHERE c.name = 'Standard'
+			  AND NOT EXISTS (SELECT 1 FROM approval_chain_steps s WHERE s.chain_id = c.id)`,
+
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS approval_chain_id INTEGER REFERENCES approval_chains(id)`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS current_step INTEGER NOT NULL DEFAULT 1`,
+		`UPDATE reimbursements
+			SET approval_chain_id = (SELECT id FROM approval_chains WHERE name = 'Standard' ORDER BY id LIMIT 1),
+			    current_step = CASE WHEN status IN ('pending', 'rejected_manager') THEN 1 ELSE 2 END
+			WHERE approval_chain_id IS NULL AND status <> 'draft'`,
+
+		`CREATE TABLE IF NOT EXISTS reimbursement_approvals (
+			id SERIAL PRIMARY KEY,
+			reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+			step_order INTEGER NOT NULL,
+			step_name VARCHAR(100) NOT NULL,
+			approver_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			action VARCHAR(20) NOT NULL,
+			notes TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursement_approvals_reimbursement_id ON reimbursement_approvals(reimbursement_id)`,
+
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS manager_id INTEGER REFERENCES users(id) ON DELETE SET NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_users_manager_id ON users(manager_id)`,
+
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS current_approver_id INTEGER REFERENCES users(id) ON DELETE SET NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursements_current_approver_id ON reimbursements(current_approver_id)`,
+		`UPDATE reimbursements r
+			SET current_approver_id = s.required_user_id
+			FROM approval_chain_steps s
+			WHERE s.chain_id = r.approval_chain_id AND s.step_order = r.current_step
+			  AND s.required_user_id IS NOT NULL
+			  AND r.current_approver_id IS NULL
+			  AND r.status IN ('pending', 'approved_manager')`,
+
+		`CREATE TABLE IF NOT EXISTS delegations (
+			id SERIAL PRIMARY KEY,
+			delegator_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			delegate_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			start_date DATE NOT NULL,
+			end_date DATE NOT NULL,
+			reason TEXT,
+			revoked_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK (end_date >= start_date),
+			CHECK (delegator_id <> delegate_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_delegations_delegate_id ON delegations(delegate_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_delegations_delegator_id ON delegations(delegator_id)`,
+
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS manager_on_behalf_of_id INTEGER REFERENCES users(id) ON DELETE SET NULL`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS finance_on_behalf_of_id INTEGER REFERENCES users(id) ON DELETE SET NULL`,
+		`ALTER TABLE reimbursement_approvals ADD COLUMN IF NOT EXISTS on_behalf_of_id INTEGER REFERENCES users(id) ON DELETE SET NULL`,
+
+		// The status check is recreated so databases created before a status
+		// was added accept it. Add new statuses here rather than adding
+		// another pair.
+		`ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_status_check`,
+		`ALTER TABLE reimbursements ADD CONSTRAINT reimbursements_status_check CHECK (status IN (
+			'pending', 'approved_manager', 'rejected_manager', 'approved_finance', 'rejected_finance', 'completed',
+			'needs_revision', 'cancelled', 'draft'
+		))`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS revision_count INTEGER NOT NULL DEFAULT 0`,
+
+		// SLA tracking: when the claim entered its current step, and whether it
+		// is past its deadline with nobody to escalate to.
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS step_entered_at TIMESTAMP`,
+		`UPDATE reimbursements SET step_entered_at = COALESCE(updated_at, submitted_date) WHERE step_entered_at IS NULL`,
+		`ALTER TABLE reimbursements ALTER COLUMN step_entered_at SET DEFAULT CURRENT_TIMESTAMP`,
+		`ALTER TABLE reimbursements ALTER COLUMN step_entered_at SET NOT NULL`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS overdue BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursements_status_step_entered_at ON reimbursements(status, step_entered_at)`,
+
+		`CREATE TABLE IF NOT EXISTS reimbursement_notifications (
+			id SERIAL PRIMARY KEY,
+			reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+			recipient_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			recipient_role VARCHAR(50),
+			kind VARCHAR(50) NOT NULL CHECK (kind IN ('reminder', 'escalation', 'overdue')),
+			message TEXT NOT NULL,
+			step_order INTEGER NOT NULL,
+			step_entered_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (reimbursement_id, kind, step_order, step_entered_at)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursement_notifications_recipient_id ON reimbursement_notifications(recipient_id)`,
+
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS cancel_reason TEXT`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP`,
+
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS approved_amount DECIMAL(12, 2) CHECK (approved_amount > 0)`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS amount_adjustment_reason TEXT`,
+		`ALTER TABLE reimbursement_approvals ADD COLUMN IF NOT EXISTS approved_amount DECIMAL(12, 2)`,
+		`ALTER TABLE reimbursement_approvals ADD COLUMN IF NOT EXISTS adjustment_reason TEXT`,
+
+		`CREATE TABLE IF NOT EXISTS duty_rules (
+			code VARCHAR(50) PRIMARY KEY,
+			description TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO duty_rules (code, description) VALUES
+			('no_self_approval', 'The submitter cannot decide any step of their own reimbursement'),
+			('distinct_approvers', 'The same person cannot approve more than one step of a reimbursement')
+			ON CONFLICT (code) DO NOTHING`,
+
+		`CREATE TABLE IF NOT EXISTS reimbursement_comments (
+			id SERIAL PRIMARY KEY,
+			reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+			author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			body TEXT NOT NULL,
+			attachments TEXT[] NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursement_comments_reimbursement_id ON reimbursement_comments(reimbursement_id)`,
+		`CREATE TABLE IF NOT EXISTS reimbursement_comment_reads (
+			reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			last_read_comment_id INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (reimbursement_id, user_id)
+		)`,
+
+		// History of every change. There is deliberately no foreign key to
+		// reimbursements so the history of a purged claim is kept, nor to
+		// users: the append-only trigger would refuse the ON DELETE action, and
+		// the history should keep who acted anyway.
+		`CREATE TABLE IF NOT EXISTS reimbursement_events (
+			id SERIAL PRIMARY KEY,
+			reimbursement_id INTEGER NOT NULL,
+			action VARCHAR(50) NOT NULL,
+			actor_id INTEGER,
+			actor_role VARCHAR(20),
+			from_status VARCHAR(50),
+			to_status VARCHAR(50),
+			notes TEXT,
+			ip_address VARCHAR(45),
+			user_agent TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursement_events_reimbursement_id ON reimbursement_events(reimbursement_id)`,
+		`ALTER TABLE reimbursement_events DROP CONSTRAINT IF EXISTS reimbursement_events_actor_id_fkey`,
+		`CREATE OR REPLACE FUNCTION reject_reimbursement_event_change()
+		RETURNS TRIGGER AS $$
+		BEGIN
+			RAISE EXCEPTION 'reimbursement_events is append-only';
+		END;
+		$$ language 'plpgsql'`,
+		`DROP TRIGGER IF EXISTS reimbursement_events_append_only ON reimbursement_events`,
+		`CREATE TRIGGER reimbursement_events_append_only BEFORE UPDATE OR DELETE ON reimbursement_events
+			FOR EACH ROW EXECUTE FUNCTION reject_reimbursement_event_change()`,
+		`INSERT INTO reimbursement_events (reimbursement_id, action, actor_id, actor_role, to_status, notes, created_at)
+			SELECT r.id, 'created', r.employee_id, 'employee', 'pending', 'Recorded when the history was introduced', r.submitted_date
+			FROM reimbursements r
+			WHERE NOT EXISTS (SELECT 1 FROM reimbursement_events e WHERE e.reimbursement_id = r.id)`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+		`CREATE TABLE IF NOT EXISTS reimbursement_versions (
+			id SERIAL PRIMARY KEY,
+			reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+			version INTEGER NOT NULL,
+			name VARCHAR(200),
+			title VARCHAR(200) NOT NULL,
+			description TEXT NOT NULL,
+			category VARCHAR(50) NOT NULL,
+			amount DECIMAL(12, 2) NOT NULL,
+			receipt_url TEXT NOT NULL,
+			edited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (reimbursement_id, version)
+		)`,
+		`INSERT INTO reimbursement_versions (reimbursement_id, version, name, title, description, category, amount, receipt_url, edited_by, created_at)
+			SELECT r.id, r.version, r.name, r.title, r.description, r.category, r.amount, r.receipt_url, r.employee_id, r.updated_at
+			FROM reimbursements r
+			WHERE NOT EXISTS (SELECT 1 FROM reimbursement_versions v WHERE v.reimbursement_id = r.id)
+			  AND r.status <> 'draft'`,
+		`ALTER TABLE reimbursement_approvals ADD COLUMN IF NOT EXISTS version INTEGER`,
+		`CREATE TABLE IF NOT EXISTS reimbursement_lines (
+			id SERIAL PRIMARY KEY,
+			reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+			line_no INTEGER NOT NULL,
+			category VARCHAR(50) NOT NULL CHECK (category IN ('transport', 'accommodation', 'meals', 'office_supply', 'other')),
+			description TEXT NOT NULL,
+			amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+			expense_date DATE NOT NULL,
+			receipt_url TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'rejected')),
+			rejection_reason TEXT,
+			decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			decided_at TIMESTAMP,
+			UNIQUE (reimbursement_id, line_no)
+		)`,
+		`INSERT INTO reimbursement_lines (reimbursement_id, line_no, category, description, amount, expense_date, receipt_url, status)
+			SELECT r.id, 1, r.category, r.description, r.amount, r.submitted_date::date, r.receipt_url,
+			       CASE WHEN r.status IN ('approved_finance', 'completed') THEN 'accepted' ELSE 'pending' END
+			FROM reimbursements r
+			WHERE NOT EXISTS (SELECT 1 FROM reimbursement_lines l WHERE l.reimbursement_id = r.id)
+			  AND r.status <> 'draft'`,
+		`ALTER TABLE reimbursement_versions ADD COLUMN IF NOT EXISTS lines JSONB`,
+		`UPDATE reimbursement_versions v SET lines = (
+			SELECT COALESCE(jsonb_agg(jsonb_build_object(
+				'line_no', l.line_no, 'category', l.category, 'description', l.description, 'amount', l.amount,
+				'expense_date', to_char(l.expense_date, 'YYYY-MM-DD'), 'receipt_url', l.receipt_url
+			) ORDER BY l.line_no), '[]'::jsonb)
+			FROM reimbursement_lines l WHERE l.reimbursement_id = v.reimbursement_id
+		) WHERE v.lines IS NULL`,
+		`CREATE TABLE IF NOT EXISTS exchange_rates (
+			id SERIAL PRIMARY KEY,
+			currency VARCHAR(3) NOT NULL,
+			rate_date DATE NOT NULL,
+			rate DECIMAL(18, 8) NOT NULL CHECK (rate > 0),
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (currency, rate_date)
+		)`,
+		// Currencies are introduced once, when reimbursements has no currency
+		// yet: existing claims were made in the base currency at rate 1.
+		// Drafts have no currency until they are submitted.
+		fmt.Sprintf(`DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'reimbursements' AND column_name = 'currency') THEN
+				ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT %[1]s;
+				ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS original_amount DECIMAL(12, 2);
+				ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18, 8) NOT NULL DEFAULT 1;
+				UPDATE reimbursement_lines SET original_amount = amount WHERE original_amount IS NULL;
+				ALTER TABLE reimbursement_lines ALTER COLUMN original_amount SET NOT NULL;
+				ALTER TABLE reimbursements ADD COLUMN currency VARCHAR(3);
+				ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS original_amount DECIMAL(12, 2);
+				ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18, 8);
+				UPDATE reimbursements r SET currency = %[1]s, original_amount = r.amount, exchange_rate = 1
+					WHERE r.status <> 'draft'
+					AND NOT EXISTS (SELECT 1 FROM reimbursement_lines l WHERE l.reimbursement_id = r.id AND l.currency <> %[1]s);
+				UPDATE reimbursement_versions SET lines = (
+					SELECT jsonb_agg(e || jsonb_build_object('currency', %[1]s, 'original_amount', e->'amount') ORDER BY (e->>'line_no')::int)
+					FROM jsonb_array_elements(lines) e
+				) WHERE jsonb_array_length(lines) > 0 AND NOT (lines->0 ? 'currency');
+			END IF;
+		END
+		$$`, pq.QuoteLiteral(baseCurrency)),
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS net_amount DECIMAL(12, 2) CHECK (net_amount > 0)`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS tax_rate DECIMAL(5, 2) CHECK (tax_rate BETWEEN 0 AND 100)`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(12, 2) CHECK (tax_amount >= 0)`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS seller_tax_id VARCHAR(30)`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS tax_invoice_number VARCHAR(50)`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursement_lines_expense_date ON reimbursement_lines(expense_date)`,
+		`CREATE TABLE IF NOT EXISTS mileage_rates (
+			id SERIAL PRIMARY KEY,
+			vehicle_type VARCHAR(20) NOT NULL CHECK (vehicle_type IN ('car', 'motorcycle')),
+			rate_per_km DECIMAL(12, 2) NOT NULL CHECK (rate_per_km > 0),
+			effective_from DATE NOT NULL,
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (vehicle_type, effective_from)
+		)`,
+		`ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_category_check`,
+		`ALTER TABLE reimbursement_lines DROP CONSTRAINT IF EXISTS reimbursement_lines_category_check`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS origin VARCHAR(200)`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS destination VARCHAR(200)`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS distance_km DECIMAL(8, 1) CHECK (distance_km > 0)`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS vehicle_type VARCHAR(20)`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS mileage_rate DECIMAL(12, 2)`,
+		`CREATE TABLE IF NOT EXISTS per_diem_rates (
+			id SERIAL PRIMARY KEY,
+			location VARCHAR(100) NOT NULL,
+			daily_rate DECIMAL(12, 2) NOT NULL CHECK (daily_rate > 0),
+			breakfast_percent DECIMAL(5, 2) NOT NULL DEFAULT 0 CHECK (breakfast_percent BETWEEN 0 AND 100),
+			lunch_percent DECIMAL(5, 2) NOT NULL DEFAULT 0 CHECK (lunch_percent BETWEEN 0 AND 100),
+			dinner_percent DECIMAL(5, 2) NOT NULL DEFAULT 0 CHECK (dinner_percent BETWEEN 0 AND 100),
+			effective_from DATE NOT NULL,
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_per_diem_rates_location ON per_diem_rates(LOWER(location), effective_from)`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS trip_start DATE`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS trip_end DATE`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS location VARCHAR(100)`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS provided_meals JSONB`,
+		`ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS per_diem_breakdown JSONB`,
+		`CREATE TABLE IF NOT EXISTS categories (
+			code VARCHAR(50) PRIMARY KEY,
+			display_name VARCHAR(100) NOT NULL,
+			local_display_name VARCHAR(100),
+			gl_account VARCHAR(30),
+			receipt_required BOOLEAN NOT NULL DEFAULT TRUE,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO categories (code, display_name, local_display_name, receipt_required) VALUES
+			('transport', 'Transport', 'Perjalanan', TRUE),
+			('accommodation', 'Accommodation', 'Akomodasi', TRUE),
+			('meals', 'Meals', 'Makanan', TRUE),
+			('office_supply', 'Office Supplies', 'Perlengkapan Kantor', TRUE),
+			('mileage', 'Mileage', 'Jarak Tempuh', FALSE),
+			('per_diem', 'Per Diem', 'Uang Harian', FALSE),
+			('other', 'Other', 'Lainnya', TRUE)
+			ON CONFLICT (code) DO NOTHING`,
+		`INSERT INTO categories (code, display_name, active)
+			SELECT DISTINCT category, category, FALSE FROM approval_chains WHERE category IS NOT NULL
+			ON CONFLICT (code) DO NOTHING`,
+		`ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_category_fkey`,
+		`ALTER TABLE reimbursements ADD CONSTRAINT reimbursements_category_fkey FOREIGN KEY (category) REFERENCES categories(code)`,
+		`ALTER TABLE reimbursement_lines DROP CONSTRAINT IF EXISTS reimbursement_lines_category_fkey`,
+		`ALTER TABLE reimbursement_lines ADD CONSTRAINT reimbursement_lines_category_fkey FOREIGN KEY (category) REFERENCES categories(code)`,
+		`ALTER TABLE approval_chains DROP CONSTRAINT IF EXISTS approval_chains_category_fkey`,
+		`ALTER TABLE approval_chains ADD CONSTRAINT approval_chains_category_fkey FOREIGN KEY (category) REFERENCES categories(code)`,
+		`CREATE TABLE IF NOT EXISTS cost_centers (
+			id SERIAL PRIMARY KEY,
+			code VARCHAR(30) UNIQUE NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS projects (
+			id SERIAL PRIMARY KEY,
+			code VARCHAR(30) UNIQUE NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			client VARCHAR(100),
+			cost_center_id INTEGER REFERENCES cost_centers(id),
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS reimbursement_allocations (
+			id SERIAL PRIMARY KEY,
+			reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+			cost_center_id INTEGER NOT NULL REFERENCES cost_centers(id),
+			project_id INTEGER REFERENCES projects(id),
+			percent DECIMAL(5, 2) CHECK (percent > 0 AND percent <= 100),
+			amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursement_allocations_reimbursement ON reimbursement_allocations(reimbursement_id)`,
+		`ALTER TABLE reimbursement_versions ADD COLUMN IF NOT EXISTS allocations JSONB NOT NULL DEFAULT '[]'`,
+		`ALTER TABLE approval_chain_steps ADD COLUMN IF NOT EXISTS cost_center_owner BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS expense_date DATE`,
+		`UPDATE reimbursements r
+		SET expense_date = COALESCE(
+			(SELECT MIN(l.expense_date) FROM reimbursement_lines l WHERE l.reimbursement_id = r.id),
+			r.submitted_date::date)
+		WHERE expense_date IS NULL AND status <> 'draft'`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS late BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS late_reason TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursements_expense_date ON reimbursements(expense_date)`,
+
+		// Drafts keep the claim as sent in draft until they are submitted, so
+		// their category, expense date and amount are only required once
+		// they leave draft. The backfills above skip drafts for the same
+		// reason.
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS draft JSONB`,
+		`ALTER TABLE reimbursements ALTER COLUMN category DROP NOT NULL`,
+		`ALTER TABLE reimbursements ALTER COLUMN expense_date DROP NOT NULL`,
+		`ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_amount_check`,
+		`ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_submitted_check`,
+		`ALTER TABLE reimbursements ADD CONSTRAINT reimbursements_submitted_check CHECK (
+			status = 'draft' OR (category IS NOT NULL AND expense_date IS NOT NULL AND amount > 0)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS cash_advances (
+			id SERIAL PRIMARY KEY,
+			employee_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			employee_name VARCHAR(100) NOT NULL,
+			purpose TEXT NOT NULL,
+			amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+			status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'disbursed', 'settled', 'cancelled')),
+			approver_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			decided_at TIMESTAMP,
+			decision_notes TEXT,
+			disbursed_date DATE,
+			disbursed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			disbursement_method VARCHAR(20) CHECK (disbursement_method IN ('bank_transfer', 'cash', 'payroll')),
+			disbursement_reference VARCHAR(100),
+			claimed_amount DECIMAL(12, 2),
+			settled_date DATE,
+			settled_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			settlement_method VARCHAR(20) CHECK (settlement_method IN ('bank_transfer', 'cash', 'payroll')),
+			settlement_reference VARCHAR(100),
+			cancelled_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_cash_advances_employee_id ON cash_advances(employee_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_cash_advances_status ON cash_advances(status)`,
+		`DROP TRIGGER IF EXISTS update_cash_advances_updated_at ON cash_advances`,
+		`CREATE TRIGGER update_cash_advances_updated_at BEFORE UPDATE ON cash_advances
+			FOR EACH ROW EXECUTE FUNCTION update_updated_at_column()`,
+		`ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS advance_id INTEGER REFERENCES cash_advances(id) ON DELETE SET NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_reimbursements_advance_id ON reimbursements(advance_id)`,
+
+		// History of every cash advance change, append-only like
+		// reimbursement_events and for the same reasons without foreign keys.
+		`CREATE TABLE IF NOT EXISTS cash_advance_events (
+			id SERIAL PRIMARY KEY,
+			advance_id INTEGER NOT NULL,
+			action VARCHAR(50) NOT NULL,
+			actor_id INTEGER,
+			actor_role VARCHAR(20),
+			from_status VARCHAR(20),
+			to_status VARCHAR(20),
+			notes TEXT,
+			ip_address VARCHAR(45),
+			user_agent TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_cash_advance_events_advance_id ON cash_advance_events(advance_id)`,
+		`CREATE OR REPLACE FUNCTION reject_cash_advance_event_change()
+		RETURNS TRIGGER AS $$
+		BEGIN
+			RAISE EXCEPTION 'cash_advance_events is append-only';
+		END;
+		$$ language 'plpgsql'`,
+		`DROP TRIGGER IF EXISTS cash_advance_events_append_only ON cash_advance_events`,
+		`CREATE TRIGGER cash_advance_events_append_only BEFORE UPDATE OR DELETE ON cash_advance_events
+			FOR EACH ROW EXECUTE FUNCTION reject_cash_advance_event_change()`,
 	}
 
 	for _, migration := range migrations {
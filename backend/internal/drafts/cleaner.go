@@ -0,0 +1,56 @@
+package drafts
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"reimbursement-backend/config"
+	"reimbursement-backend/internal/repository"
+)
+
+// Cleaner removes drafts that have not been saved for longer than the
+// retention period, so claims started and then abandoned do not pile up.
+type Cleaner struct {
+	cfg       config.DraftConfig
+	reimbRepo *repository.ReimbursementRepository
+}
+
+func NewCleaner(cfg config.DraftConfig, reimbRepo *repository.ReimbursementRepository) *Cleaner {
+	return &Cleaner{cfg: cfg, reimbRepo: reimbRepo}
+}
+
+// Run cleans up once and then every CheckIntervalMinutes until ctx is done.
+func (c *Cleaner) Run(ctx context.Context) {
+	interval := time.Duration(c.cfg.CheckIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Clean(); err != nil {
+			log.Printf("Draft clean-up failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Clean removes the drafts last saved more than RetentionDays days ago.
+// Instances cleaning up at the same time each remove different drafts, so
+// no lock is needed.
+func (c *Cleaner) Clean() error {
+	removed, err := c.reimbRepo.DeleteStaleDrafts(c.cfg.RetentionDays)
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		log.Printf("Drafts: removed %d drafts not submitted within %d days", removed, c.cfg.RetentionDays)
+	}
+	return nil
+}
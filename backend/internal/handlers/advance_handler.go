@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type AdvanceHandler struct {
+	advanceRepo    *repository.AdvanceRepository
+	userRepo       *repository.UserRepository
+	delegationRepo *repository.DelegationRepository
+}
+
+func NewAdvanceHandler(advanceRepo *repository.AdvanceRepository, userRepo *repository.UserRepository, delegationRepo *repository.DelegationRepository) *AdvanceHandler {
+	return &AdvanceHandler{
+		advanceRepo:    advanceRepo,
+		userRepo:       userRepo,
+		delegationRepo: delegationRepo,
+	}
+}
+
+// Create requests a cash advance. It waits for the nearest manager above the
+// employee, or for finance if there is none.
+func (h *AdvanceHandler) Create(c *gin.Context) {
+	var req models.CreateAdvanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	user, err := h.userRepo.GetByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
+		return
+	}
+	approverID, err := h.userRepo.FindManagerAbove(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find an approver"})
+		return
+	}
+
+	advance := &models.CashAdvance{
+		EmployeeID:   user.ID,
+		EmployeeName: user.FullName,
+		Purpose:      req.Purpose,
+		Amount:       req.Amount,
+		Status:       models.AdvancePending,
+		ApproverID:   approverID,
+	}
+
+	if err := h.advanceRepo.Create(advance, actorFrom(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cash advance"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, advance)
+}
+
+// GetAll lists the cash advances the user may see, optionally only those in
+// ?status. Employees see their own, managers those of their reporting
+// subtree or waiting on them, and finance all of them.
+func (h *AdvanceHandler) GetAll(c *gin.Context) {
+	userRole, _ := c.Get("role")
+	userID, _ := c.Get("user_id")
+	status := models.AdvanceStatus(c.Query("status"))
+
+	var advances []models.CashAdvance
+	var err error
+	switch userRole {
+	case models.RoleEmployee:
+		advances, err = h.advanceRepo.GetByEmployeeID(userID.(int), status)
+	case models.RoleManager:
+		advances, err = h.advanceRepo.GetVisibleToManager(userID.(int), status)
+	default:
+		advances, err = h.advanceRepo.GetAll(status)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cash advances"})
+		return
+	}
+
+	c.JSON(http.StatusOK, advances)
+}
+
+// GetByID returns a cash advance with the claims filed for it.
+func (h *AdvanceHandler) GetByID(c *gin.Context) {
+	advance, ok := h.advanceFor(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, advance)
+}
+
+// GetHistory returns every change made to a cash advance, oldest first.
+func (h *AdvanceHandler) GetHistory(c *gin.Context) {
+	advance, ok := h.advanceFor(c)
+	if !ok {
+		return
+	}
+
+	events, err := h.advanceRepo.GetEvents(advance.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// Decide approves or rejects a pending cash advance, or lets finance reject
+// an approved one it has not paid out.
+func (h *AdvanceHandler) Decide(c *gin.Context) {
+	advance, ok := h.advanceFor(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdvanceDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	to := models.AdvanceApproved
+	if req.Action == "reject" {
+		to = models.AdvanceRejected
+	}
+	userRole, _ := c.Get("role")
+	if !models.CanAdvanceTransition(advance.Status, to, userRole.(models.UserRole)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cash advance is not awaiting your decision"})
+		return
+	}
+	if advance.Status == models.AdvancePending {
+		userID, _ := c.Get("user_id")
+		allowed, err := h.mayDecide(advance, userID.(int), userRole.(models.UserRole))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check delegation"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not the approver of this cash advance"})
+			return
+		}
+	}
+
+	from := advance.Status
+	advance.Status = to
+	advance.DecisionNotes = req.Notes
+	if err := h.advanceRepo.Decide(advance, from, actorFrom(c)); err != nil {
+		respondAdvanceWriteError(c, err, "Failed to process decision")
+		return
+	}
+
+	c.JSON(http.StatusOK, advance)
+}
+
+// mayDecide reports whether a user may decide a pending advance: its
+// approver or their active delegate, or finance when the employee has no
+// manager above them.
+func (h *AdvanceHandler) mayDecide(advance *models.CashAdvance, userID int, role models.UserRole) (bool, error) {
+	if advance.ApproverID == nil {
+		return role == models.RoleFinance, nil
+	}
+	if role != models.RoleManager {
+		return false, nil
+	}
+	if *advance.ApproverID == userID {
+		return true, nil
+	}
+	return h.delegationRepo.IsActive(*advance.ApproverID, userID)
+}
+
+// Cancel withdraws the caller's cash advance before it is paid out.
+func (h *AdvanceHandler) Cancel(c *gin.Context) {
+	advance, ok := h.advanceFor(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if advance.EmployeeID != userID.(int) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	userRole, _ := c.Get("role")
+	if !models.CanAdvanceTransition(advance.Status, models.AdvanceCancelled, userRole.(models.UserRole)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only cash advances not paid out yet can be cancelled"})
+		return
+	}
+
+	if err := h.advanceRepo.Cancel(advance.ID, advance.Status, actorFrom(c)); err != nil {
+		respondAdvanceWriteError(c, err, "Failed to cancel cash advance")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cash advance cancelled successfully"})
+}
+
+// Disburse records that finance paid an approved cash advance out to the
+// employee. From then on claims can be filed against it.
+func (h *AdvanceHandler) Disburse(c *gin.Context) {
+	advance, ok := h.advanceFor(c)
+	if !ok {
+		return
+	}
+
+	var req models.DisburseAdvanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	disbursedDate, _ := time.Parse("2006-01-02", req.DisbursedDate)
+	if disbursedDate.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Disbursement date cannot be in the future"})
+		return
+	}
+
+	userRole, _ := c.Get("role")
+	if !models.CanAdvanceTransition(advance.Status, models.AdvanceDisbursed, userRole.(models.UserRole)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cash advance must be approved first"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	disbursedBy := userID.(int)
+	advance.DisbursedDate = &disbursedDate
+	advance.DisbursedBy = &disbursedBy
+	advance.DisbursementMethod = &req.Method
+	advance.DisbursementReference = &req.Reference
+
+	if err := h.advanceRepo.Disburse(advance, actorFrom(c)); err != nil {
+		respondAdvanceWriteError(c, err, "Failed to record disbursement")
+		return
+	}
+
+	c.JSON(http.StatusOK, advance)
+}
+
+// Settle closes a disbursed cash advance against the claims filed for it
+// once they are all decided. The finance-approved claims are completed, and
+// the advance records what was returned by the employee or topped up to
+// them.
+func (h *AdvanceHandler) Settle(c *gin.Context) {
+	advance, ok := h.advanceFor(c)
+	if !ok {
+		return
+	}
+
+	var req models.SettleAdvanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settledDate, _ := time.Parse("2006-01-02", req.SettledDate)
+	if settledDate.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Settlement date cannot be in the future"})
+		return
+	}
+
+	userRole, _ := c.Get("role")
+	if !models.CanAdvanceTransition(advance.Status, models.AdvanceSettled, userRole.(models.UserRole)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only disbursed cash advances can be settled"})
+		return
+	}
+
+	// A balance changes hands, so record how
+	if advance.Balance != 0 && (req.Method == "" || req.Reference == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "method and reference are required to record the return or top-up"})
+		return
+	}
+
+	advance.SettledDate = &settledDate
+	if req.Method != "" {
+		advance.SettlementMethod = &req.Method
+	}
+	if req.Reference != "" {
+		advance.SettlementReference = &req.Reference
+	}
+
+	if err := h.advanceRepo.Settle(advance, actorFrom(c)); err != nil {
+		if errors.Is(err, repository.ErrAdvanceClaimsOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Claims filed for this cash advance are still being approved"})
+			return
+		}
+		respondAdvanceWriteError(c, err, "Failed to settle cash advance")
+		return
+	}
+
+	advance, _ = h.advanceRepo.GetByID(advance.ID)
+	c.JSON(http.StatusOK, advance)
+}
+
+// advanceFor loads the cash advance named in the path if the user may see
+// it, answering the request itself otherwise.
+func (h *AdvanceHandler) advanceFor(c *gin.Context) (*models.CashAdvance, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	advance, err := h.advanceRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cash advance not found"})
+		return nil, false
+	}
+
+	userRole, _ := c.Get("role")
+	userID, _ := c.Get("user_id")
+	visible := true
+	switch userRole {
+	case models.RoleEmployee:
+		visible = advance.EmployeeID == userID.(int)
+	case models.RoleManager:
+		visible, err = h.advanceRepo.IsVisibleToManager(id, userID.(int))
+		visible = err == nil && visible
+	}
+	if !visible {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+	return advance, true
+}
+
+// respondAdvanceWriteError reports a failed cash advance write as
+// respondWriteError does for reimbursements.
+func respondAdvanceWriteError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrStatusConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cash advance was changed by someone else, please reload and try again"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type ApprovalChainHandler struct {
+	chainRepo    *repository.ApprovalChainRepository
+	userRepo     *repository.UserRepository
+	categoryRepo *repository.CategoryRepository
+}
+
+func NewApprovalChainHandler(chainRepo *repository.ApprovalChainRepository, userRepo *repository.UserRepository, categoryRepo *repository.CategoryRepository) *ApprovalChainHandler {
+	return &ApprovalChainHandler{
+		chainRepo:    chainRepo,
+		userRepo:     userRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+func (h *ApprovalChainHandler) GetAll(c *gin.Context) {
+	chains, err := h.chainRepo.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approval chains"})
+		return
+	}
+
+	c.JSON(http.StatusOK, chains)
+}
+
+func (h *ApprovalChainHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	chain, err := h.chainRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval chain not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, chain)
+}
+
+func (h *ApprovalChainHandler) Create(c *gin.Context) {
+	var req models.CreateApprovalChainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.MaxAmount != nil && *req.MaxAmount <= req.MinAmount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_amount must be greater than min_amount"})
+		return
+	}
+
+	if req.Category != nil {
+		if _, err := h.categoryRepo.Get(*req.Category); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category"})
+			return
+		}
+	}
+
+	chain := &models.ApprovalChain{
+		Name:      req.Name,
+		Category:  req.Category,
+		MinAmount: req.MinAmount,
+		MaxAmount: req.MaxAmount,
+		Priority:  req.Priority,
+	}
+
+	for _, s := range req.Steps {
+		// A step is decided either by one named user or by a role, never both.
+		if (s.RequiredRole == nil) == (s.RequiredUserID == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each step needs exactly one of required_role or required_user_id"})
+			return
+		}
+		// The role decides a cost-center owner step when there is no owner
+		if s.CostCenterOwner && s.RequiredRole == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A cost_center_owner step needs a required_role to fall back to"})
+			return
+		}
+		if s.RequiredUserID != nil {
+			user, err := h.userRepo.GetByID(*s.RequiredUserID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Step approver not found"})
+				return
+			}
+			if user.Role == models.RoleEmployee {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Step approver must be a manager or finance user"})
+				return
+			}
+		}
+		chain.Steps = append(chain.Steps, models.ApprovalStep{
+			Name:            s.Name,
+			RequiredRole:    s.RequiredRole,
+			RequiredUserID:  s.RequiredUserID,
+			CostCenterOwner: s.CostCenterOwner,
+		})
+	}
+
+	if err := h.chainRepo.Create(chain); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create approval chain"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, chain)
+}
+
+// Deactivate stops a chain from being picked for new claims. Claims already
+// routed through it are not affected.
+func (h *ApprovalChainHandler) Deactivate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := h.chainRepo.SetActive(id, false); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval chain not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Approval chain deactivated successfully"})
+}
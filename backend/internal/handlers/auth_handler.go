@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"reimbursement-backend/config"
@@ -80,3 +82,38 @@ func (h *AuthHandler) GetAllUsers(c *gin.Context) {
 
 	c.JSON(http.StatusOK, users)
 }
+
+// SetManager changes who a user reports to. Claims the user submits from
+// then on are routed to the nearest manager up that reporting line.
+func (h *AuthHandler) SetManager(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var req models.SetManagerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.ManagerID != nil {
+		if _, err := h.userRepo.GetByID(*req.ManagerID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Manager not found"})
+			return
+		}
+	}
+
+	if err := h.userRepo.SetManager(id, req.ManagerID); err != nil {
+		if errors.Is(err, repository.ErrReportingCycle) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A user cannot report to themselves or to someone in their own team"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	user, _ := h.userRepo.GetByID(id)
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+// categoryCode matches a category code: lower case words joined by
+// underscores.
+var categoryCode = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+type CategoryHandler struct {
+	categoryRepo *repository.CategoryRepository
+}
+
+func NewCategoryHandler(categoryRepo *repository.CategoryRepository) *CategoryHandler {
+	return &CategoryHandler{categoryRepo: categoryRepo}
+}
+
+// GetAll lists the active categories. Finance may ask for inactive ones too
+// with ?all=true.
+func (h *CategoryHandler) GetAll(c *gin.Context) {
+	role, _ := c.Get("role")
+	includeInactive := c.Query("all") == "true" && role == models.RoleFinance
+
+	categories, err := h.categoryRepo.GetAll(includeInactive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+func (h *CategoryHandler) Create(c *gin.Context) {
+	var req models.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !categoryCode.MatchString(req.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category code must be lower case words joined by underscores, e.g. office_supply"})
+		return
+	}
+
+	category := &models.Category{
+		Code:             models.ReimbursementCategory(req.Code),
+		DisplayName:      req.DisplayName,
+		LocalDisplayName: req.LocalDisplayName,
+		GLAccount:        req.GLAccount,
+		ReceiptRequired:  req.ReceiptRequired == nil || *req.ReceiptRequired,
+		Active:           true,
+	}
+
+	if err := h.categoryRepo.Create(category); err != nil {
+		if err == repository.ErrCategoryExists {
+			c.JSON(http.StatusConflict, gin.H{"error": "A category with this code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// Update edits a category. Its code cannot change, since claims refer to it.
+func (h *CategoryHandler) Update(c *gin.Context) {
+	category, err := h.categoryRepo.Get(models.ReimbursementCategory(c.Param("code")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var req models.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.DisplayName != nil {
+		category.DisplayName = *req.DisplayName
+	}
+	if req.LocalDisplayName != nil {
+		category.LocalDisplayName = req.LocalDisplayName
+	}
+	if req.GLAccount != nil {
+		category.GLAccount = req.GLAccount
+	}
+	if req.ReceiptRequired != nil {
+		category.ReceiptRequired = *req.ReceiptRequired
+	}
+	if req.Active != nil {
+		category.Active = *req.Active
+	}
+
+	if err := h.categoryRepo.Update(category); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// Deactivate stops a category from being chosen for new expenses. Claims
+// already using it keep it.
+func (h *CategoryHandler) Deactivate(c *gin.Context) {
+	category, err := h.categoryRepo.Get(models.ReimbursementCategory(c.Param("code")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	category.Active = false
+	if err := h.categoryRepo.Update(category); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deactivated successfully"})
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type CommentHandler struct {
+	commentRepo *repository.CommentRepository
+	reimbRepo   *repository.ReimbursementRepository
+	uploadDir   string
+}
+
+func NewCommentHandler(commentRepo *repository.CommentRepository, reimbRepo *repository.ReimbursementRepository, uploadDir string) *CommentHandler {
+	return &CommentHandler{
+		commentRepo: commentRepo,
+		reimbRepo:   reimbRepo,
+		uploadDir:   uploadDir,
+	}
+}
+
+// GetComments returns the thread of a reimbursement and marks it as read for
+// the current user.
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	reimb, ok := h.visibleReimbursement(c)
+	if !ok {
+		return
+	}
+
+	comments, err := h.commentRepo.GetByReimbursementID(reimb.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := h.commentRepo.MarkRead(reimb.ID, userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark comments as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// CreateComment posts a comment on a reimbursement that is still waiting for
+// a decision.
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	reimb, ok := h.visibleReimbursement(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Attachments must be files uploaded through /upload/attachment
+	for _, url := range req.Attachments {
+		if !isUploadedFile(h.uploadDir, url) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Attachment %q is not an uploaded file", url)})
+			return
+		}
+	}
+
+	if !reimb.Status.IsUndecided() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Comments are closed once a reimbursement has been decided"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	comment := &models.Comment{
+		ReimbursementID: reimb.ID,
+		AuthorID:        userID.(int),
+		Body:            req.Body,
+		Attachments:     req.Attachments,
+	}
+	if err := h.commentRepo.Create(comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post comment"})
+		return
+	}
+
+	// The author has obviously read the thread up to their own comment
+	h.commentRepo.MarkRead(reimb.ID, userID.(int))
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// GetUnreadCount returns how many comments others have posted on a
+// reimbursement since the current user last read its thread.
+func (h *CommentHandler) GetUnreadCount(c *gin.Context) {
+	reimb, ok := h.visibleReimbursement(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	unread, err := h.commentRepo.CountUnread(reimb.ID, userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CommentUnreadCount{ReimbursementID: reimb.ID, Unread: unread})
+}
+
+// visibleReimbursement loads the reimbursement in the :id parameter and
+// checks the current user may see it, like ReimbursementHandler.GetByID.
+// It writes the error response and returns false otherwise.
+func (h *CommentHandler) visibleReimbursement(c *gin.Context) (*models.Reimbursement, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	reimb, err := h.reimbRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement not found"})
+		return nil, false
+	}
+
+	if !canView(c, h.reimbRepo, reimb) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+
+	return reimb, true
+}
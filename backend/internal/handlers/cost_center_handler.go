@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type CostCenterHandler struct {
+	costCenterRepo *repository.CostCenterRepository
+	userRepo       *repository.UserRepository
+}
+
+func NewCostCenterHandler(costCenterRepo *repository.CostCenterRepository, userRepo *repository.UserRepository) *CostCenterHandler {
+	return &CostCenterHandler{
+		costCenterRepo: costCenterRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// GetAll lists the active cost centers. Finance may ask for inactive ones too
+// with ?all=true.
+func (h *CostCenterHandler) GetAll(c *gin.Context) {
+	role, _ := c.Get("role")
+	includeInactive := c.Query("all") == "true" && role == models.RoleFinance
+
+	costCenters, err := h.costCenterRepo.GetAll(includeInactive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cost centers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, costCenters)
+}
+
+func (h *CostCenterHandler) Create(c *gin.Context) {
+	var req models.CreateCostCenterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.OwnerID != nil && !h.checkOwner(c, *req.OwnerID) {
+		return
+	}
+
+	costCenter := &models.CostCenter{
+		Code:    req.Code,
+		Name:    req.Name,
+		OwnerID: req.OwnerID,
+		Active:  true,
+	}
+
+	if err := h.costCenterRepo.Create(costCenter); err != nil {
+		if err == repository.ErrCostCenterExists {
+			c.JSON(http.StatusConflict, gin.H{"error": "A cost center with this code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cost center"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, costCenter)
+}
+
+// Update edits a cost center. Its code cannot change.
+func (h *CostCenterHandler) Update(c *gin.Context) {
+	costCenter, ok := h.costCenter(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCostCenterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != nil {
+		costCenter.Name = *req.Name
+	}
+	if req.OwnerID != nil {
+		if !h.checkOwner(c, *req.OwnerID) {
+			return
+		}
+		costCenter.OwnerID = req.OwnerID
+	}
+	if req.Active != nil {
+		costCenter.Active = *req.Active
+	}
+
+	if err := h.costCenterRepo.Update(costCenter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cost center"})
+		return
+	}
+
+	c.JSON(http.StatusOK, costCenter)
+}
+
+// Deactivate stops a cost center from being charged by new allocations.
+// Claims already charged to it keep their allocations.
+func (h *CostCenterHandler) Deactivate(c *gin.Context) {
+	costCenter, ok := h.costCenter(c)
+	if !ok {
+		return
+	}
+
+	costCenter.Active = false
+	if err := h.costCenterRepo.Update(costCenter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate cost center"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cost center deactivated successfully"})
+}
+
+// costCenter loads the cost center named by the :id parameter, answering the
+// request itself if it cannot.
+func (h *CostCenterHandler) costCenter(c *gin.Context) (*models.CostCenter, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	costCenter, err := h.costCenterRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cost center not found"})
+		return nil, false
+	}
+	return costCenter, true
+}
+
+// checkOwner checks that a cost center owner can approve claims, answering
+// the request itself if not.
+func (h *CostCenterHandler) checkOwner(c *gin.Context, ownerID int) bool {
+	owner, err := h.userRepo.GetByID(ownerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cost center owner not found"})
+		return false
+	}
+	if owner.Role == models.RoleEmployee {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cost center owner must be a manager or finance user"})
+		return false
+	}
+	return true
+}
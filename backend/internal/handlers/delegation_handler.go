@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type DelegationHandler struct {
+	delegationRepo *repository.DelegationRepository
+	userRepo       *repository.UserRepository
+}
+
+func NewDelegationHandler(delegationRepo *repository.DelegationRepository, userRepo *repository.UserRepository) *DelegationHandler {
+	return &DelegationHandler{
+		delegationRepo: delegationRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// Create registers a delegate who may act on the caller's approvals for a
+// date range, for example while they are on leave.
+func (h *DelegationHandler) Create(c *gin.Context) {
+	var req models.CreateDelegationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	startDate, _ := time.Parse("2006-01-02", req.StartDate)
+	endDate, _ := time.Parse("2006-01-02", req.EndDate)
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+	today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	if endDate.Before(today) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be in the past"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	if req.DelegateID == userID.(int) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot delegate to yourself"})
+		return
+	}
+
+	delegate, err := h.userRepo.GetByID(req.DelegateID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Delegate not found"})
+		return
+	}
+	// The delegate works the same queue, so they must hold the same role.
+	if delegate.Role != userRole.(models.UserRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Delegate must have the same role as you"})
+		return
+	}
+
+	delegation := &models.Delegation{
+		DelegatorID: userID.(int),
+		DelegateID:  req.DelegateID,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Reason:      req.Reason,
+	}
+
+	if err := h.delegationRepo.Create(delegation); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create delegation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, delegation)
+}
+
+// GetMine returns the delegations the caller has given or received.
+func (h *DelegationHandler) GetMine(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	delegations, err := h.delegationRepo.GetByUser(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch delegations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, delegations)
+}
+
+func (h *DelegationHandler) Revoke(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := h.delegationRepo.Revoke(id, userID.(int)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delegation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Delegation revoked successfully"})
+}
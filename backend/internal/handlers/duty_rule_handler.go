@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type DutyRuleHandler struct {
+	ruleRepo *repository.DutyRuleRepository
+}
+
+func NewDutyRuleHandler(ruleRepo *repository.DutyRuleRepository) *DutyRuleHandler {
+	return &DutyRuleHandler{ruleRepo: ruleRepo}
+}
+
+func (h *DutyRuleHandler) GetAll(c *gin.Context) {
+	rules, err := h.ruleRepo.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch separation-of-duties rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// Update switches a rule on or off.
+func (h *DutyRuleHandler) Update(c *gin.Context) {
+	var req models.UpdateDutyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.ruleRepo.SetEnabled(c.Param("code"), *req.Enabled); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule updated successfully"})
+}
+
+// GetViolations reports stored decisions that break a separation-of-duties
+// rule.
+func (h *DutyRuleHandler) GetViolations(c *gin.Context) {
+	violations, err := h.ruleRepo.GetViolations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check separation-of-duties violations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, violations)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+// currencyCode matches an ISO 4217 currency code.
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type ExchangeRateHandler struct {
+	rateRepo     *repository.ExchangeRateRepository
+	baseCurrency string
+}
+
+func NewExchangeRateHandler(rateRepo *repository.ExchangeRateRepository, baseCurrency string) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		rateRepo:     rateRepo,
+		baseCurrency: baseCurrency,
+	}
+}
+
+// GetAll lists the exchange rates, optionally of one ?currency.
+func (h *ExchangeRateHandler) GetAll(c *gin.Context) {
+	rates, err := h.rateRepo.GetAll(strings.ToUpper(c.Query("currency")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// Save creates the rate of a currency on a date, or replaces the existing
+// one.
+func (h *ExchangeRateHandler) Save(c *gin.Context) {
+	var req models.SaveExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Currency == h.baseCurrency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The base currency always has a rate of 1"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	createdBy := userID.(int)
+	rateDate, _ := time.Parse("2006-01-02", req.RateDate)
+	rate := &models.ExchangeRate{
+		Currency:  req.Currency,
+		RateDate:  rateDate,
+		Rate:      req.Rate,
+		CreatedBy: &createdBy,
+	}
+
+	if err := h.rateRepo.Save(rate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// Import saves the rates of an uploaded CSV file with the header
+// currency,rate_date,rate. Either every row is saved or, if any row is
+// invalid, none is and every problem is reported.
+func (h *ExchangeRateHandler) Import(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer f.Close()
+
+	rates, problems, err := h.parseRates(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rates file", "details": problems})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	createdBy := userID.(int)
+	for i := range rates {
+		rates[i].CreatedBy = &createdBy
+	}
+
+	if err := h.rateRepo.Import(rates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ImportExchangeRatesResponse{Imported: len(rates)})
+}
+
+func (h *ExchangeRateHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := h.rateRepo.Delete(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted successfully"})
+}
+
+// parseRates reads exchange rates from CSV. Invalid rows are returned as
+// problems naming their line; a file that is not CSV at all is an error.
+func (h *ExchangeRateHandler) parseRates(r io.Reader) ([]models.ExchangeRate, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, errors.New("The file must start with the header currency,rate_date,rate")
+	}
+	for i, want := range []string{"currency", "rate_date", "rate"} {
+		if strings.ToLower(strings.TrimSpace(header[i])) != want {
+			return nil, nil, errors.New("The file must start with the header currency,rate_date,rate")
+		}
+	}
+
+	var rates []models.ExchangeRate
+	var problems []string
+	seen := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		currency := strings.ToUpper(strings.TrimSpace(record[0]))
+		rateDate, dateErr := time.Parse("2006-01-02", strings.TrimSpace(record[1]))
+		rate, rateErr := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		switch {
+		case !currencyCode.MatchString(currency):
+			problems = append(problems, fmt.Sprintf("line %d: invalid currency %q", line, record[0]))
+		case currency == h.baseCurrency:
+			problems = append(problems, fmt.Sprintf("line %d: the base currency always has a rate of 1", line))
+		case dateErr != nil:
+			problems = append(problems, fmt.Sprintf("line %d: invalid date %q, expected YYYY-MM-DD", line, record[1]))
+		case rateErr != nil || rate <= 0:
+			problems = append(problems, fmt.Sprintf("line %d: invalid rate %q", line, record[2]))
+		default:
+			key := currency + " " + rateDate.Format("2006-01-02")
+			if first, ok := seen[key]; ok {
+				problems = append(problems, fmt.Sprintf("line %d: duplicate of line %d", line, first))
+				continue
+			}
+			seen[key] = line
+			rates = append(rates, models.ExchangeRate{Currency: currency, RateDate: rateDate, Rate: rate})
+		}
+	}
+
+	if len(rates) == 0 && len(problems) == 0 {
+		return nil, nil, errors.New("The file has no exchange rates")
+	}
+	return rates, problems, nil
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type MileageRateHandler struct {
+	mileageRepo *repository.MileageRateRepository
+}
+
+func NewMileageRateHandler(mileageRepo *repository.MileageRateRepository) *MileageRateHandler {
+	return &MileageRateHandler{mileageRepo: mileageRepo}
+}
+
+func (h *MileageRateHandler) GetAll(c *gin.Context) {
+	rates, err := h.mileageRepo.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mileage rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// Save creates the rate of a vehicle type effective from a date, or replaces
+// the existing one. Claims already made keep the rate they were priced at.
+func (h *MileageRateHandler) Save(c *gin.Context) {
+	var req models.SaveMileageRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	createdBy := userID.(int)
+	effectiveFrom, _ := time.Parse("2006-01-02", req.EffectiveFrom)
+	rate := &models.MileageRate{
+		VehicleType:   req.VehicleType,
+		RatePerKm:     req.RatePerKm,
+		EffectiveFrom: effectiveFrom,
+		CreatedBy:     &createdBy,
+	}
+
+	if err := h.mileageRepo.Save(rate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save mileage rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+func (h *MileageRateHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := h.mileageRepo.Delete(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mileage rate not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mileage rate deleted successfully"})
+}
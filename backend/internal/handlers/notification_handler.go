@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type NotificationHandler struct {
+	notificationRepo *repository.NotificationRepository
+}
+
+func NewNotificationHandler(notificationRepo *repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{notificationRepo: notificationRepo}
+}
+
+// GetMine returns the SLA reminders, escalations and overdue notices for the
+// current user.
+func (h *NotificationHandler) GetMine(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+
+	notifications, err := h.notificationRepo.GetForUser(userID.(int), userRole.(models.UserRole))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type PaymentHandler struct {
+	paymentRepo *repository.PaymentRepository
+	reimbRepo   *repository.ReimbursementRepository
+}
+
+func NewPaymentHandler(paymentRepo *repository.PaymentRepository, reimbRepo *repository.ReimbursementRepository) *PaymentHandler {
+	return &PaymentHandler{
+		paymentRepo: paymentRepo,
+		reimbRepo:   reimbRepo,
+	}
+}
+
+func (h *PaymentHandler) MarkPaid(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	reimb, err := h.reimbRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement not found"})
+		return
+	}
+
+	// Claims filed against a cash advance are paid by settling the advance
+	if reimb.AdvanceID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Reimbursement is settled against cash advance #%d", *reimb.AdvanceID)})
+		return
+	}
+
+	var req models.MarkPaidRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentDate, _ := time.Parse("2006-01-02", req.PaymentDate)
+	if paymentDate.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment date cannot be in the future"})
+		return
+	}
+
+	userRole, _ := c.Get("role")
+	if !models.CanTransition(reimb.Status, models.StatusCompleted, userRole.(models.UserRole)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reimbursement must be approved by finance first"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	paidBy := userID.(int)
+	payment := &models.Payment{
+		ReimbursementID: id,
+		Method:          req.Method,
+		Reference:       req.Reference,
+		PaymentDate:     paymentDate,
+		PaidBy:          &paidBy,
+	}
+
+	if err := h.paymentRepo.MarkPaid(payment, actorFrom(c)); err != nil {
+		respondWriteError(c, err, "Failed to record payment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, payment)
+}
+
+func (h *PaymentHandler) ReversePayment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	reimb, err := h.reimbRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement not found"})
+		return
+	}
+
+	if reimb.AdvanceID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reimbursement was settled against a cash advance and has no payment to reverse"})
+		return
+	}
+
+	var req models.ReversePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userRole, _ := c.Get("role")
+	if !models.CanTransition(reimb.Status, models.StatusApprovedFinance, userRole.(models.UserRole)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reimbursement has not been paid"})
+		return
+	}
+
+	if err := h.paymentRepo.Reverse(id, req.Reason, actorFrom(c)); err != nil {
+		respondWriteError(c, err, "Failed to reverse payment")
+		return
+	}
+
+	reimb, _ = h.reimbRepo.GetByID(id)
+	c.JSON(http.StatusOK, reimb)
+}
+
+func (h *PaymentHandler) GetPayments(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	reimb, err := h.reimbRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement not found"})
+		return
+	}
+
+	if !canView(c, h.reimbRepo, reimb) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	payments, err := h.paymentRepo.GetByReimbursementID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, payments)
+}
+
+func (h *PaymentHandler) GetAwaitingPayment(c *gin.Context) {
+	reimbursements, err := h.reimbRepo.GetByStatus(models.StatusApprovedFinance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reimbursements awaiting payment"})
+		return
+	}
+
+	// Claims filed against a cash advance wait for its settlement instead
+	awaiting := []models.Reimbursement{}
+	for _, reimb := range reimbursements {
+		if reimb.AdvanceID == nil {
+			awaiting = append(awaiting, reimb)
+		}
+	}
+
+	c.JSON(http.StatusOK, awaiting)
+}
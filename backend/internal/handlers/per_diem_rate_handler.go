@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type PerDiemRateHandler struct {
+	perDiemRepo *repository.PerDiemRateRepository
+}
+
+func NewPerDiemRateHandler(perDiemRepo *repository.PerDiemRateRepository) *PerDiemRateHandler {
+	return &PerDiemRateHandler{perDiemRepo: perDiemRepo}
+}
+
+func (h *PerDiemRateHandler) GetAll(c *gin.Context) {
+	rates, err := h.perDiemRepo.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch per-diem rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// Save creates the rate of a location effective from a date, or replaces
+// the existing one. Claims already made keep the breakdown they were priced
+// with.
+func (h *PerDiemRateHandler) Save(c *gin.Context) {
+	var req models.SavePerDiemRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	location := strings.TrimSpace(req.Location)
+	if location == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Location is required"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	createdBy := userID.(int)
+	effectiveFrom, _ := time.Parse("2006-01-02", req.EffectiveFrom)
+	rate := &models.PerDiemRate{
+		Location:         location,
+		DailyRate:        req.DailyRate,
+		BreakfastPercent: req.BreakfastPercent,
+		LunchPercent:     req.LunchPercent,
+		DinnerPercent:    req.DinnerPercent,
+		EffectiveFrom:    effectiveFrom,
+		CreatedBy:        &createdBy,
+	}
+
+	if err := h.perDiemRepo.Save(rate); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save per-diem rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+func (h *PerDiemRateHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := h.perDiemRepo.Delete(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Per-diem rate not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Per-diem rate deleted successfully"})
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type ProjectHandler struct {
+	projectRepo    *repository.ProjectRepository
+	costCenterRepo *repository.CostCenterRepository
+}
+
+func NewProjectHandler(projectRepo *repository.ProjectRepository, costCenterRepo *repository.CostCenterRepository) *ProjectHandler {
+	return &ProjectHandler{
+		projectRepo:    projectRepo,
+		costCenterRepo: costCenterRepo,
+	}
+}
+
+// GetAll lists the active projects. Finance may ask for inactive ones too
+// with ?all=true.
+func (h *ProjectHandler) GetAll(c *gin.Context) {
+	role, _ := c.Get("role")
+	includeInactive := c.Query("all") == "true" && role == models.RoleFinance
+
+	projects, err := h.projectRepo.GetAll(includeInactive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+func (h *ProjectHandler) Create(c *gin.Context) {
+	var req models.CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.CostCenterID != nil {
+		if _, err := h.costCenterRepo.GetByID(*req.CostCenterID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cost center not found"})
+			return
+		}
+	}
+
+	project := &models.Project{
+		Code:         req.Code,
+		Name:         req.Name,
+		Client:       req.Client,
+		CostCenterID: req.CostCenterID,
+		Active:       true,
+	}
+
+	if err := h.projectRepo.Create(project); err != nil {
+		if err == repository.ErrProjectExists {
+			c.JSON(http.StatusConflict, gin.H{"error": "A project with this code already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// Update edits a project. Its code cannot change.
+func (h *ProjectHandler) Update(c *gin.Context) {
+	project, ok := h.project(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != nil {
+		project.Name = *req.Name
+	}
+	if req.Client != nil {
+		project.Client = req.Client
+	}
+	if req.CostCenterID != nil {
+		if _, err := h.costCenterRepo.GetByID(*req.CostCenterID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cost center not found"})
+			return
+		}
+		project.CostCenterID = req.CostCenterID
+	}
+	if req.Active != nil {
+		project.Active = *req.Active
+	}
+
+	if err := h.projectRepo.Update(project); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// Deactivate stops a project from being charged by new allocations. Claims
+// already charged to it keep their allocations.
+func (h *ProjectHandler) Deactivate(c *gin.Context) {
+	project, ok := h.project(c)
+	if !ok {
+		return
+	}
+
+	project.Active = false
+	if err := h.projectRepo.Update(project); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project deactivated successfully"})
+}
+
+// project loads the project named by the :id parameter, answering the
+// request itself if it cannot.
+func (h *ProjectHandler) project(c *gin.Context) (*models.Project, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return nil, false
+	}
+
+	project, err := h.projectRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+	return project, true
+}
@@ -1,23 +1,62 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"reimbursement-backend/internal/models"
 	"reimbursement-backend/internal/repository"
 )
 
 type ReimbursementHandler struct {
-	reimbRepo *repository.ReimbursementRepository
-	userRepo  *repository.UserRepository
+	reimbRepo      *repository.ReimbursementRepository
+	userRepo       *repository.UserRepository
+	chainRepo      *repository.ApprovalChainRepository
+	delegationRepo *repository.DelegationRepository
+	ruleRepo       *repository.DutyRuleRepository
+	eventRepo      *repository.EventRepository
+	versionRepo    *repository.VersionRepository
+	rateRepo       *repository.ExchangeRateRepository
+	mileageRepo    *repository.MileageRateRepository
+	perDiemRepo    *repository.PerDiemRateRepository
+	categoryRepo   *repository.CategoryRepository
+	costCenterRepo *repository.CostCenterRepository
+	projectRepo    *repository.ProjectRepository
+	advanceRepo    *repository.AdvanceRepository
+	baseCurrency   string
+	mileageCapKm   int
+	submission     models.SubmissionWindow
 }
 
-func NewReimbursementHandler(reimbRepo *repository.ReimbursementRepository, userRepo *repository.UserRepository) *ReimbursementHandler {
+func NewReimbursementHandler(reimbRepo *repository.ReimbursementRepository, userRepo *repository.UserRepository, chainRepo *repository.ApprovalChainRepository, delegationRepo *repository.DelegationRepository, ruleRepo *repository.DutyRuleRepository, eventRepo *repository.EventRepository, versionRepo *repository.VersionRepository, rateRepo *repository.ExchangeRateRepository, mileageRepo *repository.MileageRateRepository, perDiemRepo *repository.PerDiemRateRepository, categoryRepo *repository.CategoryRepository, costCenterRepo *repository.CostCenterRepository, projectRepo *repository.ProjectRepository, advanceRepo *repository.AdvanceRepository, baseCurrency string, mileageCapKm int, submission models.SubmissionWindow) *ReimbursementHandler {
 	return &ReimbursementHandler{
-		reimbRepo: reimbRepo,
-		userRepo:  userRepo,
+		reimbRepo:      reimbRepo,
+		userRepo:       userRepo,
+		chainRepo:      chainRepo,
+		delegationRepo: delegationRepo,
+		ruleRepo:       ruleRepo,
+		eventRepo:      eventRepo,
+		versionRepo:    versionRepo,
+		rateRepo:       rateRepo,
+		mileageRepo:    mileageRepo,
+		perDiemRepo:    perDiemRepo,
+		categoryRepo:   categoryRepo,
+		costCenterRepo: costCenterRepo,
+		projectRepo:    projectRepo,
+		advanceRepo:    advanceRepo,
+		baseCurrency:   baseCurrency,
+		mileageCapKm:   mileageCapKm,
+		submission:     submission,
 	}
 }
 
@@ -35,39 +74,256 @@ func (h *ReimbursementHandler) Create(c *gin.Context) {
 		return
 	}
 
+	reimb, ok := h.claimFrom(c, &req, user.ID)
+	if !ok {
+		return
+	}
+
+	if err := h.reimbRepo.Create(reimb, actorFrom(c)); err != nil {
+		respondWriteError(c, err, "Failed to create reimbursement")
+		return
+	}
+
+	c.JSON(http.StatusCreated, reimb)
+}
+
+// claimFrom builds a new claim of employeeID from a complete request,
+// pricing, checking and routing it as submitted today. It answers the
+// request itself if the claim cannot be accepted.
+func (h *ReimbursementHandler) claimFrom(c *gin.Context, req *models.CreateReimbursementRequest, employeeID int) (*models.Reimbursement, bool) {
+	if len(req.Lines) == 0 && req.Category == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "category is required without lines"})
+		return nil, false
+	}
+	if len(req.Lines) == 0 && req.ExpenseDate == "" && req.Category != models.CategoryPerDiem {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expense_date is required without lines"})
+		return nil, false
+	}
+
 	reimb := &models.Reimbursement{
-		EmployeeID:   user.ID,
+		EmployeeID:   employeeID,
 		EmployeeName: req.Name, // Use the name from form input
 		Name:         req.Name,
 		Title:        req.Title,
 		Description:  req.Description,
-		Category:     req.Category,
-		Amount:       req.Amount,
-		ReceiptURL:   req.ReceiptURL,
 		Status:       models.StatusPending,
+		CurrentStep:  1,
+	}
+
+	// A claim without lines is a report of one line dated by the claim
+	var lines []models.ReimbursementLine
+	if len(req.Lines) > 0 {
+		lines = h.linesFrom(req.Lines)
+	} else {
+		expenseDate, _ := time.Parse("2006-01-02", req.ExpenseDate)
+		lines = []models.ReimbursementLine{{
+			Category:       req.Category,
+			Description:    req.Description,
+			Currency:       h.currencyOrBase(req.Currency),
+			OriginalAmount: req.Amount,
+			ExpenseDate:    expenseDate,
+			ReceiptURL:     req.ReceiptURL,
+			TaxDetails:     req.TaxDetails,
+			MileageDetails: req.MileageDetails,
+			PerDiemDetails: req.PerDiemDetails,
+		}}
+	}
+	if err := h.checkCategories(lines); err != nil {
+		respondLinesError(c, err)
+		return nil, false
+	}
+	if err := h.priceLines(lines, employeeID, 0); err != nil {
+		respondLinesError(c, err)
+		return nil, false
+	}
+	if err := validateTax(lines); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err := h.convert(lines); err != nil {
+		respondLinesError(c, err)
+		return nil, false
+	}
+	reimb.ApplyLines(lines)
+	if err := h.checkSubmissionWindow(reimb, lines, today()); err != nil {
+		respondLinesError(c, err)
+		return nil, false
+	}
+
+	if len(req.Allocations) > 0 {
+		allocations, err := h.allocate(req.Allocations, reimb.Amount, true)
+		if err != nil {
+			respondAllocationError(c, err)
+			return nil, false
+		}
+		reimb.Allocations = allocations
+	}
+
+	// A claim can only be filed against an advance the employee holds
+	if req.AdvanceID != nil {
+		advance, err := h.advanceRepo.GetByID(*req.AdvanceID)
+		if err != nil || advance.EmployeeID != employeeID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cash advance not found"})
+			return nil, false
+		}
+		if advance.Status != models.AdvanceDisbursed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": advanceNotOpenMessage})
+			return nil, false
+		}
+		reimb.AdvanceID = req.AdvanceID
+	}
+
+	if err := h.routeToChain(reimb); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No approval chain configured for this reimbursement"})
+		return nil, false
+	}
+	return reimb, true
+}
+
+// SaveDraft saves a claim as a draft for its submitter to finish later. The
+// fields given are checked as for Create, but required ones may be left out
+// until the draft is submitted.
+func (h *ReimbursementHandler) SaveDraft(c *gin.Context) {
+	var req models.CreateReimbursementRequest
+	if err := bindDraft(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if err := h.reimbRepo.Create(reimb); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reimbursement"})
+	userID, _ := c.Get("user_id")
+	reimb := &models.Reimbursement{
+		EmployeeID:   userID.(int),
+		EmployeeName: req.Name,
+		Name:         req.Name,
+		Title:        req.Title,
+		Description:  req.Description,
+		Status:       models.StatusDraft,
+		Draft:        &req,
+	}
+
+	if err := h.reimbRepo.CreateDraft(reimb, actorFrom(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save draft"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, reimb)
 }
 
+// Submit moves a draft into the approval workflow. The draft must now be
+// complete, and is checked and routed as a claim sent to Create would be.
+func (h *ReimbursementHandler) Submit(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	reimb, err := h.reimbRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement not found"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if reimb.EmployeeID != userID.(int) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	userRole, _ := c.Get("role")
+	if !models.CanTransition(reimb.Status, models.StatusPending, userRole.(models.UserRole)) || reimb.Draft == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only drafts can be submitted"})
+		return
+	}
+
+	if err := binding.Validator.ValidateStruct(reimb.Draft); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claim, ok := h.claimFrom(c, reimb.Draft, reimb.EmployeeID)
+	if !ok {
+		return
+	}
+	claim.ID = reimb.ID
+	claim.CreatedAt = reimb.CreatedAt
+
+	if err := h.reimbRepo.Submit(claim, actorFrom(c)); err != nil {
+		respondWriteError(c, err, "Failed to submit reimbursement")
+		return
+	}
+
+	c.JSON(http.StatusOK, claim)
+}
+
+// updateDraft replaces a draft with the claim sent, checked as for
+// SaveDraft.
+func (h *ReimbursementHandler) updateDraft(c *gin.Context, reimb *models.Reimbursement) {
+	var req models.CreateReimbursementRequest
+	if err := bindDraft(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reimb.EmployeeName = req.Name
+	reimb.Name = req.Name
+	reimb.Title = req.Title
+	reimb.Description = req.Description
+	reimb.Draft = &req
+
+	if err := h.reimbRepo.UpdateDraft(reimb, actorFrom(c)); err != nil {
+		respondWriteError(c, err, "Failed to save draft")
+		return
+	}
+
+	c.JSON(http.StatusOK, reimb)
+}
+
+// bindDraft binds a draft claim. The fields given are validated as for a
+// claim, but a field failing only its required check is let through.
+func bindDraft(c *gin.Context, req *models.CreateReimbursementRequest) error {
+	err := c.ShouldBindJSON(req)
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	var errs validator.ValidationErrors
+	for _, fe := range invalid {
+		if !strings.HasPrefix(fe.Tag(), "required") {
+			errs = append(errs, fe)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// GetAll lists the reimbursements the user may see, optionally limited to
+// those with an expense date from ?from through ?to (YYYY-MM-DD).
 func (h *ReimbursementHandler) GetAll(c *gin.Context) {
 	userRole, _ := c.Get("role")
 	userID, _ := c.Get("user_id")
 
+	period, ok := expensePeriod(c)
+	if !ok {
+		return
+	}
+
 	var reimbursements []models.Reimbursement
 	var err error
 
-	// Employees can only see their own reimbursements
-	if userRole == models.RoleEmployee {
-		reimbursements, err = h.reimbRepo.GetByEmployeeID(userID.(int))
-	} else {
-		// Managers and Finance can see all reimbursements
-		reimbursements, err = h.reimbRepo.GetAll()
+	switch userRole {
+	case models.RoleEmployee:
+		// Employees can only see their own reimbursements
+		reimbursements, err = h.reimbRepo.GetByEmployeeID(userID.(int), period)
+	case models.RoleManager:
+		// Managers see their reporting subtree and claims routed to them
+		reimbursements, err = h.reimbRepo.GetVisibleToManager(userID.(int), period)
+	default:
+		// Finance can see all reimbursements
+		reimbursements, err = h.reimbRepo.GetAll(period)
 	}
 
 	if err != nil {
@@ -91,14 +347,29 @@ func (h *ReimbursementHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	// Check if employee is accessing their own reimbursement
-	userRole, _ := c.Get("role")
-	userID, _ := c.Get("user_id")
-	if userRole == models.RoleEmployee && reimb.EmployeeID != userID.(int) {
+	if !canView(c, h.reimbRepo, reimb) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
+	reimb.Approvals, err = h.reimbRepo.GetApprovals(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch approvals"})
+		return
+	}
+
+	reimb.Lines, err = h.reimbRepo.GetLines(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lines"})
+		return
+	}
+
+	reimb.Allocations, err = h.reimbRepo.GetAllocations(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allocations"})
+		return
+	}
+
 	c.JSON(http.StatusOK, reimb)
 }
 
@@ -115,15 +386,22 @@ func (h *ReimbursementHandler) Update(c *gin.Context) {
 		return
 	}
 
-	// Only the employee who created it can update, and only if status is pending
+	// Only the employee who created it can update, and only while it is
+	// pending or returned for revision
 	userID, _ := c.Get("user_id")
 	if reimb.EmployeeID != userID.(int) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
-	if reimb.Status != models.StatusPending {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot update reimbursement that is not pending"})
+	// A draft is replaced as a whole by the claim sent
+	if reimb.Status == models.StatusDraft {
+		h.updateDraft(c, reimb)
+		return
+	}
+
+	if !reimb.Status.IsEditable() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot update reimbursement that is not pending or returned for revision"})
 		return
 	}
 
@@ -133,6 +411,19 @@ func (h *ReimbursementHandler) Update(c *gin.Context) {
 		return
 	}
 
+	current, err := h.reimbRepo.GetLines(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lines"})
+		return
+	}
+	currentAllocations, err := h.reimbRepo.GetAllocations(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allocations"})
+		return
+	}
+
+	before := *reimb
+
 	// Update fields if provided
 	if req.Name != "" {
 		reimb.Name = req.Name
@@ -143,58 +434,232 @@ func (h *ReimbursementHandler) Update(c *gin.Context) {
 	if req.Description != "" {
 		reimb.Description = req.Description
 	}
-	if req.Category != "" {
-		reimb.Category = req.Category
+
+	// The category, amount and receipt come from the lines. A one-line
+	// report is still edited through the claim's own fields.
+	var lines []models.ReimbursementLine
+	if len(req.Lines) > 0 {
+		lines = h.linesFrom(req.Lines)
+	} else if len(current) == 1 {
+		line := current[0]
+		if req.Description != "" {
+			line.Description = req.Description
+		}
+		if req.Category != "" {
+			line.Category = req.Category
+			if req.Category != models.CategoryMileage {
+				line.MileageDetails = models.MileageDetails{}
+			}
+			if req.Category != models.CategoryPerDiem {
+				line.PerDiemDetails = models.PerDiemDetails{}
+			}
+		}
+		if req.Currency != "" {
+			line.Currency = req.Currency
+		}
+		if req.Amount > 0 {
+			line.OriginalAmount = req.Amount
+		}
+		if req.ReceiptURL != "" {
+			line.ReceiptURL = req.ReceiptURL
+		}
+		if req.ExpenseDate != "" {
+			line.ExpenseDate, _ = time.Parse("2006-01-02", req.ExpenseDate)
+		}
+		if req.NetAmount != nil {
+			line.NetAmount = req.NetAmount
+		}
+		if req.TaxRate != nil {
+			line.TaxRate = req.TaxRate
+		}
+		if req.TaxAmount != nil {
+			line.TaxAmount = req.TaxAmount
+		}
+		if req.SellerTaxID != nil {
+			line.SellerTaxID = req.SellerTaxID
+		}
+		if req.TaxInvoiceNumber != nil {
+			line.TaxInvoiceNumber = req.TaxInvoiceNumber
+		}
+		if req.Origin != nil {
+			line.Origin = req.Origin
+		}
+		if req.Destination != nil {
+			line.Destination = req.Destination
+		}
+		if req.DistanceKm != nil {
+			line.DistanceKm = req.DistanceKm
+		}
+		if req.VehicleType != nil {
+			line.VehicleType = req.VehicleType
+		}
+		if req.TripStart != nil {
+			line.TripStart = req.TripStart
+		}
+		if req.TripEnd != nil {
+			line.TripEnd = req.TripEnd
+		}
+		if req.Location != nil {
+			line.Location = req.Location
+		}
+		if req.ProvidedMeals != nil {
+			line.ProvidedMeals = req.ProvidedMeals
+		}
+		lines = []models.ReimbursementLine{line}
+	} else if req.Category != "" || req.Currency != "" || req.Amount > 0 || req.ReceiptURL != "" || req.ExpenseDate != "" ||
+		!req.TaxDetails.IsEmpty() || !req.MileageDetails.IsEmpty() || !req.PerDiemDetails.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The category, amount, receipt, date, tax and trip of a multi-line report come from its lines; edit the lines instead"})
+		return
 	}
-	if req.Amount > 0 {
-		reimb.Amount = req.Amount
+	if lines != nil {
+		// Lines left as they were keep their category even if it has
+		// since been deactivated
+		if !models.SameLines(lines, current) {
+			if err := h.checkCategories(lines); err != nil {
+				respondLinesError(c, err)
+				return
+			}
+		}
+		if err := h.priceLines(lines, reimb.EmployeeID, reimb.ID); err != nil {
+			respondLinesError(c, err)
+			return
+		}
 	}
-	if req.ReceiptURL != "" {
-		reimb.ReceiptURL = req.ReceiptURL
+	if lines != nil && !models.SameLines(lines, current) {
+		if err := validateTax(lines); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := h.convert(lines); err != nil {
+			respondLinesError(c, err)
+			return
+		}
+		reimb.ApplyLines(lines)
+		// Lateness is judged against when the claim was first submitted
+		if err := h.checkSubmissionWindow(reimb, lines, dateOf(reimb.SubmittedDate)); err != nil {
+			respondLinesError(c, err)
+			return
+		}
 	}
 
-	if err := h.reimbRepo.Update(reimb); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reimbursement"})
+	// An amount approved for the old lines no longer applies
+	if reimb.Lines != nil {
+		reimb.ApprovedAmount = nil
+		reimb.AmountAdjustmentReason = nil
+	}
+
+	// Allocations given replace the claim's. Otherwise a new amount is split
+	// again by the kept allocations: percentages follow it, amounts must be
+	// given again. Kept allocations may use cost centers and projects that
+	// have since been deactivated.
+	if req.Allocations != nil {
+		allocations, err := h.allocate(req.Allocations, reimb.Amount, true)
+		if err != nil {
+			respondAllocationError(c, err)
+			return
+		}
+		if !models.SameAllocations(allocations, currentAllocations) {
+			reimb.Allocations = allocations
+		}
+	} else if len(currentAllocations) > 0 && reimb.Amount != before.Amount {
+		reimb.Allocations, err = h.allocate(allocationRequests(currentAllocations), reimb.Amount, false)
+		if err != nil {
+			respondAllocationError(c, err)
+			return
+		}
+	}
+
+	// An edit that changes nothing does not make a new version
+	if reimb.Name == before.Name && reimb.Title == before.Title && reimb.Description == before.Description &&
+		reimb.Lines == nil && reimb.Allocations == nil {
+		reimb.Lines = current
+		reimb.Allocations = currentAllocations
+		c.JSON(http.StatusOK, reimb)
 		return
 	}
 
+	// Nothing has been approved on a pending claim yet, so a changed amount
+	// or category may route it through a different chain. A claim returned
+	// for revision keeps its chain and goes back to the same step.
+	if reimb.Status == models.StatusPending {
+		if err := h.routeToChain(reimb); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No approval chain configured for this reimbursement"})
+			return
+		}
+	}
+
+	if err := h.reimbRepo.Update(reimb, actorFrom(c)); err != nil {
+		respondWriteError(c, err, "Failed to update reimbursement")
+		return
+	}
+	if reimb.Lines == nil {
+		reimb.Lines = current
+	}
+	if reimb.Allocations == nil {
+		reimb.Allocations = currentAllocations
+	}
+
 	c.JSON(http.StatusOK, reimb)
 }
 
-func (h *ReimbursementHandler) Delete(c *gin.Context) {
+// Cancel withdraws a reimbursement that is still waiting for its first
+// decision or was returned for revision. The claim is kept with status
+// cancelled so it stays in the submitter's history. A draft is deleted.
+func (h *ReimbursementHandler) Cancel(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
 
+	// The reason is optional, so an empty body is fine
+	var req models.CancelReimbursementRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	reimb, err := h.reimbRepo.GetByID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement not found"})
 		return
 	}
 
-	// Only the employee who created it can delete, and only if status is pending
+	// Only the employee who created it can cancel it
 	userID, _ := c.Get("user_id")
 	if reimb.EmployeeID != userID.(int) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
-	if reimb.Status != models.StatusPending {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete reimbursement that is not pending"})
+	// A draft never entered the workflow, so it is deleted instead
+	if reimb.Status == models.StatusDraft {
+		if err := h.reimbRepo.DeleteDraft(id, actorFrom(c)); err != nil {
+			respondWriteError(c, err, "Failed to delete draft")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Draft deleted successfully"})
 		return
 	}
 
-	if err := h.reimbRepo.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reimbursement"})
+	userRole, _ := c.Get("role")
+	if !models.CanTransition(reimb.Status, models.StatusCancelled, userRole.(models.UserRole)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot cancel reimbursement that is not pending or returned for revision"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Reimbursement deleted successfully"})
+	if err := h.reimbRepo.Cancel(id, reimb.Status, req.Reason, actorFrom(c)); err != nil {
+		respondWriteError(c, err, "Failed to cancel reimbursement")
+		return
+	}
+
+	reimb, _ = h.reimbRepo.GetByID(id)
+	c.JSON(http.StatusOK, reimb)
 }
 
-func (h *ReimbursementHandler) ManagerApproval(c *gin.Context) {
+// Purge permanently removes a cancelled reimbursement. It is an
+// administrative clean-up; employees cancel their claims instead.
+func (h *ReimbursementHandler) Purge(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
@@ -207,8 +672,30 @@ func (h *ReimbursementHandler) ManagerApproval(c *gin.Context) {
 		return
 	}
 
-	if reimb.Status != models.StatusPending {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Reimbursement is not pending"})
+	if reimb.Status != models.StatusCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only cancelled reimbursements can be purged"})
+		return
+	}
+
+	if err := h.reimbRepo.Purge(id, actorFrom(c)); err != nil {
+		respondWriteError(c, err, "Failed to purge reimbursement")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reimbursement purged successfully"})
+}
+
+// Approve decides the current step of a reimbursement's approval chain.
+func (h *ReimbursementHandler) Approve(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	reimb, err := h.reimbRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement not found"})
 		return
 	}
 
@@ -218,25 +705,267 @@ func (h *ReimbursementHandler) ManagerApproval(c *gin.Context) {
 		return
 	}
 
-	managerID, _ := c.Get("user_id")
+	if err := h.decide(reimb, req, actorFrom(c)); err != nil {
+		status, message := decisionFailure(err)
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
 
-	if req.Action == "approve" {
-		err = h.reimbRepo.ApproveByManager(id, managerID.(int), req.Notes)
-	} else {
-		err = h.reimbRepo.RejectByManager(id, managerID.(int), req.Notes)
+	// Fetch updated reimbursement
+	reimb, _ = h.reimbRepo.GetByID(id)
+	c.JSON(http.StatusOK, reimb)
+}
+
+// BulkApprove decides the current step of several reimbursements with the
+// same action. Each claim is decided on its own, exactly as through Approve,
+// so one failure does not stop the others; the response reports the outcome
+// for every ID.
+func (h *ReimbursementHandler) BulkApprove(c *gin.Context) {
+	var req models.BulkApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
+	var missing []string
+	for _, id := range req.IDs {
+		if _, ok := req.Versions[id]; !ok {
+			missing = append(missing, strconv.Itoa(id))
+		}
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "versions must give the version reviewed for every ID, missing: " + strings.Join(missing, ", ")})
+		return
+	}
+
+	actor := actorFrom(c)
+	response := models.BulkApprovalResponse{Results: []models.BulkApprovalResult{}}
+	seen := make(map[int]bool)
+	for _, id := range req.IDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		item := models.ApprovalRequest{Action: req.Action, Notes: req.Notes, Version: req.Versions[id]}
+		if notes, ok := req.ItemNotes[id]; ok {
+			item.Notes = &notes
+		}
+
+		result := models.BulkApprovalResult{ID: id, Status: http.StatusOK}
+		reimb, err := h.reimbRepo.GetByID(id)
+		if err == nil {
+			err = h.decide(reimb, item, actor)
+		} else {
+			err = &decisionError{status: http.StatusNotFound, message: "Reimbursement not found"}
+		}
+		if err != nil {
+			result.Status, result.Error = decisionFailure(err)
+			response.Failed++
+		} else {
+			result.Success = true
+			result.Reimbursement, _ = h.reimbRepo.GetByID(id)
+			response.Succeeded++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// decide applies one approver's decision to the current step of a
+// reimbursement. Failures the caller should report as-is are returned as
+// *decisionError; anything else comes from the repository.
+func (h *ReimbursementHandler) decide(reimb *models.Reimbursement, req models.ApprovalRequest, actor models.Actor) error {
+	userID, role := actor.UserID, actor.Role
+
+	if req.Action == "revise" && (req.Notes == nil || strings.TrimSpace(*req.Notes) == "") {
+		return &decisionError{status: http.StatusBadRequest, message: "Notes are required when returning a reimbursement for revision"}
+	}
+
+	if req.ApprovedAmount != nil {
+		if req.Action != "approve" {
+			return &decisionError{status: http.StatusBadRequest, message: "An approved amount can only be given when approving"}
+		}
+		if req.Justification == nil || strings.TrimSpace(*req.Justification) == "" {
+			return &decisionError{status: http.StatusBadRequest, message: "A justification is required when approving a lower amount"}
+		}
+		if *req.ApprovedAmount >= reimb.PayableAmount() {
+			return &decisionError{status: http.StatusBadRequest, message: "Approved amount must be lower than the amount currently approved"}
+		}
+	}
+
+	if len(req.Lines) > 0 {
+		if req.Action != "approve" {
+			return &decisionError{status: http.StatusBadRequest, message: "Line decisions can only be given when approving"}
+		}
+		if req.ApprovedAmount != nil {
+			return &decisionError{status: http.StatusBadRequest, message: "Give either an approved amount or line decisions, not both"}
+		}
+		approvedAmount, reason, err := h.decideLines(reimb, req.Lines)
+		if err != nil {
+			return err
+		}
+		req.ApprovedAmount, req.Justification = approvedAmount, reason
+	}
+
+	if !reimb.Status.IsAwaitingApproval() || reimb.ApprovalChainID == nil {
+		return &decisionError{status: http.StatusConflict, message: "Reimbursement is not awaiting approval"}
+	}
+
+	if req.Version != reimb.Version {
+		return &decisionError{
+			status:  http.StatusConflict,
+			message: fmt.Sprintf("Reimbursement was edited after version %d; review version %d before deciding", req.Version, reimb.Version),
+		}
+	}
+
+	chain, err := h.chainRepo.GetByID(*reimb.ApprovalChainID)
+	if err != nil || reimb.CurrentStep < 1 || reimb.CurrentStep > len(chain.Steps) {
+		return &decisionError{status: http.StatusInternalServerError, message: "Failed to load approval chain"}
+	}
+	step := chain.Steps[reimb.CurrentStep-1]
+
+	onBehalfOf, allowed, err := h.actingFor(reimb, step, userID, role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process approval"})
+		return &decisionError{status: http.StatusInternalServerError, message: "Failed to check delegations"}
+	}
+	if !allowed {
+		return &decisionError{status: http.StatusForbidden, message: "You are not the approver for the current step"}
+	}
+	if err := h.checkDuties(reimb, req.Action, userID, onBehalfOf); err != nil {
+		return err
+	}
+
+	approve := req.Action == "approve"
+	last := reimb.CurrentStep == len(chain.Steps)
+	target := models.StepOutcome(role, last, approve)
+	if req.Action == "revise" {
+		target = models.StatusNeedsRevision
+	}
+	if !models.CanTransition(reimb.Status, target, role) {
+		return &decisionError{status: http.StatusConflict, message: "Reimbursement is not awaiting approval"}
+	}
+
+	// A claim returned for revision comes back to the same step and approver.
+	nextStep := reimb.CurrentStep
+	var nextApproverID *int
+	if req.Action == "revise" {
+		nextApproverID = reimb.CurrentApproverID
+	}
+	if approve && !last {
+		nextStep++
+		nextApproverID, err = h.stepApprover(chain.Steps[nextStep-1], reimb)
+		if err != nil {
+			return &decisionError{status: http.StatusInternalServerError, message: "Failed to resolve next approver"}
+		}
+	}
+
+	return h.reimbRepo.DecideStep(&models.ApprovalDecision{
+		ReimbursementID:  reimb.ID,
+		Step:             step,
+		FromStatus:       reimb.Status,
+		ToStatus:         target,
+		NextStep:         nextStep,
+		NextApproverID:   nextApproverID,
+		ApproverID:       userID,
+		OnBehalfOfID:     onBehalfOf,
+		Action:           req.Action,
+		Notes:            req.Notes,
+		ApprovedAmount:   req.ApprovedAmount,
+		AdjustmentReason: req.Justification,
+		LineDecisions:    req.Lines,
+		Version:          reimb.Version,
+		Actor:            actor,
+	})
+}
+
+// decideLines checks an approver's line decisions against the lines of reimb.
+// When lines are rejected it returns the lower amount left to approve and the
+// rejection reasons, to be stored as a partial approval.
+func (h *ReimbursementHandler) decideLines(reimb *models.Reimbursement, decisions []models.LineDecision) (*models.Money, *string, error) {
+	lines, err := h.reimbRepo.GetLines(reimb.ID)
+	if err != nil {
+		return nil, nil, &decisionError{status: http.StatusInternalServerError, message: "Failed to fetch lines"}
+	}
+	byID := make(map[int]*models.ReimbursementLine, len(lines))
+	for i := range lines {
+		byID[lines[i].ID] = &lines[i]
+	}
+
+	seen := make(map[int]bool)
+	var reasons []string
+	for _, d := range decisions {
+		line, ok := byID[d.ID]
+		if !ok {
+			return nil, nil, &decisionError{status: http.StatusBadRequest, message: fmt.Sprintf("Line %d is not part of this reimbursement", d.ID)}
+		}
+		if seen[d.ID] {
+			return nil, nil, &decisionError{status: http.StatusBadRequest, message: fmt.Sprintf("Line %d is decided more than once", d.ID)}
+		}
+		seen[d.ID] = true
+		if line.Status == models.LineRejected {
+			return nil, nil, &decisionError{status: http.StatusConflict, message: fmt.Sprintf("Line %d has already been rejected", d.ID)}
+		}
+		if d.Action == "reject" {
+			if d.Reason == nil || strings.TrimSpace(*d.Reason) == "" {
+				return nil, nil, &decisionError{status: http.StatusBadRequest, message: fmt.Sprintf("A reason is required when rejecting line %d", d.ID)}
+			}
+			line.Status = models.LineRejected
+			reasons = append(reasons, fmt.Sprintf("Line %d: %s", line.LineNo, *d.Reason))
+		}
+	}
+
+	remaining := models.SumLines(lines)
+	if remaining == 0 {
+		return nil, nil, &decisionError{status: http.StatusBadRequest, message: "Every line is rejected; reject the reimbursement instead"}
+	}
+	if len(reasons) == 0 || remaining >= reimb.PayableAmount() {
+		return nil, nil, nil
+	}
+	reason := strings.Join(reasons, "; ")
+	return &remaining, &reason, nil
+}
+
+// Resubmit sends a reimbursement that was returned for revision back to the
+// approval step that returned it.
+func (h *ReimbursementHandler) Resubmit(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	reimb, err := h.reimbRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement not found"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if reimb.EmployeeID != userID.(int) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	target := models.ResubmitStatus(reimb.CurrentStep)
+	userRole, _ := c.Get("role")
+	if !models.CanTransition(reimb.Status, target, userRole.(models.UserRole)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reimbursement has not been returned for revision"})
+		return
+	}
+
+	if err := h.reimbRepo.Resubmit(id, reimb.Status, target, actorFrom(c)); err != nil {
+		respondWriteError(c, err, "Failed to resubmit reimbursement")
 		return
 	}
 
-	// Fetch updated reimbursement
 	reimb, _ = h.reimbRepo.GetByID(id)
 	c.JSON(http.StatusOK, reimb)
 }
 
-func (h *ReimbursementHandler) FinanceApproval(c *gin.Context) {
+// GetHistory returns every change made to a reimbursement, oldest first.
+func (h *ReimbursementHandler) GetHistory(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
@@ -249,33 +978,104 @@ func (h *ReimbursementHandler) FinanceApproval(c *gin.Context) {
 		return
 	}
 
-	if reimb.Status != models.StatusApprovedManager {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Reimbursement must be approved by manager first"})
+	if !canView(c, h.reimbRepo, reimb) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
-	var req models.ApprovalRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	events, err := h.eventRepo.GetByReimbursementID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
 		return
 	}
 
-	financeID, _ := c.Get("user_id")
+	c.JSON(http.StatusOK, events)
+}
 
-	if req.Action == "approve" {
-		err = h.reimbRepo.ApproveByFinance(id, financeID.(int), req.Notes)
-	} else {
-		err = h.reimbRepo.RejectByFinance(id, financeID.(int), req.Notes)
+// GetVersions returns every version of a reimbursement, oldest first.
+func (h *ReimbursementHandler) GetVersions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
 	}
 
+	reimb, err := h.reimbRepo.GetByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process approval"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement not found"})
 		return
 	}
 
-	// Fetch updated reimbursement
-	reimb, _ = h.reimbRepo.GetByID(id)
-	c.JSON(http.StatusOK, reimb)
+	if !canView(c, h.reimbRepo, reimb) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	versions, err := h.versionRepo.GetByReimbursementID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetVersionDiff compares two versions of a reimbursement field by field.
+// Without query parameters it compares the previous version with the
+// current one.
+func (h *ReimbursementHandler) GetVersionDiff(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	reimb, err := h.reimbRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement not found"})
+		return
+	}
+
+	if !canView(c, h.reimbRepo, reimb) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	to := reimb.Version
+	if s := c.Query("to"); s != "" {
+		if to, err = strconv.Atoi(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to version"})
+			return
+		}
+	}
+	from := to - 1
+	if from < 1 {
+		from = 1
+	}
+	if s := c.Query("from"); s != "" {
+		if from, err = strconv.Atoi(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from version"})
+			return
+		}
+	}
+
+	fromVersion, err := h.versionRepo.Get(id, from)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Version %d not found", from)})
+		return
+	}
+	toVersion, err := h.versionRepo.Get(id, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Version %d not found", to)})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.VersionDiff{
+		ReimbursementID: id,
+		From:            from,
+		To:              to,
+		Changes:         fromVersion.Diff(toVersion),
+	})
 }
 
 func (h *ReimbursementHandler) GetStats(c *gin.Context) {
@@ -285,11 +1085,14 @@ func (h *ReimbursementHandler) GetStats(c *gin.Context) {
 	var stats *models.ReimbursementStats
 	var err error
 
-	// Employees get their own stats, others get overall stats
-	if userRole == models.RoleEmployee {
+	// Employees get their own stats, managers their team's, finance overall stats
+	switch userRole {
+	case models.RoleEmployee:
 		employeeID := userID.(int)
 		stats, err = h.reimbRepo.GetStats(&employeeID)
-	} else {
+	case models.RoleManager:
+		stats, err = h.reimbRepo.GetStatsForManager(userID.(int))
+	default:
 		stats, err = h.reimbRepo.GetStats(nil)
 	}
 
@@ -298,11 +1101,35 @@ func (h *ReimbursementHandler) GetStats(c *gin.Context) {
 		return
 	}
 
+	stats.Currency = h.baseCurrency
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetPendingForManager returns the claims whose current approval step is
+// assigned to the calling manager.
 func (h *ReimbursementHandler) GetPendingForManager(c *gin.Context) {
-	reimbursements, err := h.reimbRepo.GetByStatus(models.StatusPending)
+	h.getPendingForRole(c, models.RoleManager)
+}
+
+// GetPendingForFinance returns the claims whose current approval step is
+// assigned to the calling finance user.
+func (h *ReimbursementHandler) GetPendingForFinance(c *gin.Context) {
+	h.getPendingForRole(c, models.RoleFinance)
+}
+
+func (h *ReimbursementHandler) getPendingForRole(c *gin.Context, role models.UserRole) {
+	var overdue *bool
+	if value := c.Query("overdue"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid overdue filter"})
+			return
+		}
+		overdue = &parsed
+	}
+
+	userID, _ := c.Get("user_id")
+	reimbursements, err := h.reimbRepo.GetPendingForApprover(userID.(int), role, overdue)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending reimbursements"})
 		return
@@ -311,12 +1138,681 @@ func (h *ReimbursementHandler) GetPendingForManager(c *gin.Context) {
 	c.JSON(http.StatusOK, reimbursements)
 }
 
-func (h *ReimbursementHandler) GetPendingForFinance(c *gin.Context) {
-	reimbursements, err := h.reimbRepo.GetByStatus(models.StatusApprovedManager)
+// linesFrom builds the lines of a report from a request. They still need
+// converting to the base currency.
+func (h *ReimbursementHandler) linesFrom(reqs []models.CreateLineRequest) []models.ReimbursementLine {
+	lines := make([]models.ReimbursementLine, len(reqs))
+	for i, l := range reqs {
+		expenseDate, _ := time.Parse("2006-01-02", l.ExpenseDate)
+		lines[i] = models.ReimbursementLine{
+			Category:       l.Category,
+			Description:    l.Description,
+			Currency:       h.currencyOrBase(l.Currency),
+			OriginalAmount: l.Amount,
+			ExpenseDate:    expenseDate,
+			ReceiptURL:     l.ReceiptURL,
+			TaxDetails:     l.TaxDetails,
+			MileageDetails: l.MileageDetails,
+			PerDiemDetails: l.PerDiemDetails,
+		}
+	}
+	return lines
+}
+
+// checkCategories checks that every line is in an active category, has an
+// amount unless it is computed, and has a receipt if its category needs one.
+func (h *ReimbursementHandler) checkCategories(lines []models.ReimbursementLine) error {
+	categories, err := h.categoryRepo.GetAll(false)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending reimbursements"})
+		return err
+	}
+	active := make(map[models.ReimbursementCategory]models.Category, len(categories))
+	for _, category := range categories {
+		active[category.Code] = category
+	}
+
+	for i, l := range lines {
+		category, ok := active[l.Category]
+		if !ok {
+			return &invalidLinesError{fmt.Sprintf("Line %d: unknown or inactive category %q", i+1, l.Category)}
+		}
+		if !l.Category.IsComputed() && l.OriginalAmount == 0 {
+			return &invalidLinesError{fmt.Sprintf("Line %d: amount is required", i+1)}
+		}
+		if category.ReceiptRequired && l.ReceiptURL == "" {
+			return &invalidLinesError{fmt.Sprintf("Line %d: a receipt is required for %s", i+1, category.DisplayName)}
+		}
+	}
+	return nil
+}
+
+// allocate splits total between the allocations of a claim, as
+// splitAllocations does with the current cost centers and projects.
+func (h *ReimbursementHandler) allocate(reqs []models.AllocationRequest, total models.Money, checkActive bool) ([]models.Allocation, error) {
+	if len(reqs) == 0 {
+		return []models.Allocation{}, nil
+	}
+
+	costCenters, err := h.costCenterRepo.GetAll(true)
+	if err != nil {
+		return nil, err
+	}
+	costCentersByID := make(map[int]models.CostCenter, len(costCenters))
+	for _, cc := range costCenters {
+		costCentersByID[cc.ID] = cc
+	}
+	projects, err := h.projectRepo.GetAll(true)
+	if err != nil {
+		return nil, err
+	}
+	projectsByID := make(map[int]models.Project, len(projects))
+	for _, p := range projects {
+		projectsByID[p.ID] = p
+	}
+	return splitAllocations(reqs, total, costCentersByID, projectsByID, checkActive)
+}
+
+// splitAllocations splits total between the allocations of a claim.
+// Allocations must all be percentages adding up to 100, the last one taking
+// the rounding remainder, or all amounts adding up to total. Each names a
+// cost center in costCenters, or a project in projects with one, and no cost
+// center and project pair twice. Deactivated cost centers and projects are
+// refused if checkActive.
+func splitAllocations(reqs []models.AllocationRequest, total models.Money, costCenters map[int]models.CostCenter, projects map[int]models.Project, checkActive bool) ([]models.Allocation, error) {
+	allocations := make([]models.Allocation, 0, len(reqs))
+	if len(reqs) == 0 {
+		return allocations, nil
+	}
+
+	type target struct{ costCenterID, projectID int }
+	seen := make(map[target]bool, len(reqs))
+	byPercent := reqs[0].Percent != nil
+	var basisPoints int
+	var allocated models.Money
+	for i, req := range reqs {
+		if (req.Percent == nil) == (req.Amount == nil) {
+			return nil, &invalidAllocationError{fmt.Sprintf("Allocation %d: give exactly one of percent or amount", i+1)}
+		}
+		if (req.Percent != nil) != byPercent {
+			return nil, &invalidAllocationError{"Allocations must be all percentages or all amounts"}
+		}
+
+		a := models.Allocation{ProjectID: req.ProjectID}
+		key := target{}
+		if req.ProjectID != nil {
+			project, ok := projects[*req.ProjectID]
+			if !ok || (checkActive && !project.Active) {
+				return nil, &invalidAllocationError{fmt.Sprintf("Allocation %d: unknown or inactive project %d", i+1, *req.ProjectID)}
+			}
+			a.ProjectCode = &project.Code
+			key.projectID = project.ID
+			if req.CostCenterID == nil {
+				req.CostCenterID = project.CostCenterID
+			}
+		}
+		if req.CostCenterID == nil {
+			return nil, &invalidAllocationError{fmt.Sprintf("Allocation %d: cost_center_id is required", i+1)}
+		}
+		costCenter, ok := costCenters[*req.CostCenterID]
+		if !ok || (checkActive && !costCenter.Active) {
+			return nil, &invalidAllocationError{fmt.Sprintf("Allocation %d: unknown or inactive cost center %d", i+1, *req.CostCenterID)}
+		}
+		a.CostCenterID, a.CostCenterCode = costCenter.ID, costCenter.Code
+		key.costCenterID = costCenter.ID
+		if seen[key] {
+			return nil, &invalidAllocationError{fmt.Sprintf("Allocation %d: this cost center and project are already allocated", i+1)}
+		}
+		seen[key] = true
+
+		if byPercent {
+			// Percentages are stored with two decimal places
+			bp := math.Round(*req.Percent * 100)
+			if math.Abs(bp-*req.Percent*100) > 1e-6 {
+				return nil, &invalidAllocationError{fmt.Sprintf("Allocation %d: percent may have at most 2 decimal places", i+1)}
+			}
+			percent := bp / 100
+			a.Percent = &percent
+			basisPoints += int(bp)
+			a.Amount = total.Convert(bp / 10000)
+		} else {
+			a.Amount = *req.Amount
+		}
+		allocated += a.Amount
+		allocations = append(allocations, a)
+	}
+
+	if byPercent {
+		if basisPoints != 10000 {
+			return nil, &invalidAllocationError{fmt.Sprintf("Allocations add up to %g%%, not 100%%", float64(basisPoints)/100)}
+		}
+		last := &allocations[len(allocations)-1]
+		last.Amount += total - allocated
+		if last.Amount <= 0 {
+			return nil, &invalidAllocationError{fmt.Sprintf("The amount %s is too small to split this way", total)}
+		}
+	} else if allocated != total {
+		return nil, &invalidAllocationError{fmt.Sprintf("Allocations add up to %s, not the amount %s", allocated, total)}
+	}
+	return allocations, nil
+}
+
+// allocationRequests turns stored allocations back into requests, to split
+// a new amount the same way.
+func allocationRequests(allocations []models.Allocation) []models.AllocationRequest {
+	reqs := make([]models.AllocationRequest, len(allocations))
+	for i, a := range allocations {
+		costCenterID, amount := a.CostCenterID, a.Amount
+		reqs[i] = models.AllocationRequest{CostCenterID: &costCenterID, ProjectID: a.ProjectID, Percent: a.Percent}
+		if a.Percent == nil {
+			reqs[i].Amount = &amount
+		}
+	}
+	return reqs
+}
+
+// invalidAllocationError reports allocations that cannot be accepted as
+// given.
+type invalidAllocationError struct {
+	message string
+}
+
+func (e *invalidAllocationError) Error() string {
+	return e.message
+}
+
+// respondAllocationError answers a request whose allocations could not be
+// split: 400 if the allocations are at fault, 500 otherwise.
+func respondAllocationError(c *gin.Context, err error) {
+	var invalid *invalidAllocationError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the allocations"})
+}
 
-	c.JSON(http.StatusOK, reimbursements)
+// validateTax checks that the tax breakdown of each line adds up to the
+// amount spent.
+func validateTax(lines []models.ReimbursementLine) error {
+	for i, l := range lines {
+		if err := l.TaxDetails.Validate(l.OriginalAmount, l.Currency); err != nil {
+			return fmt.Errorf("Line %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// expensePeriod parses the dates ?from through ?to (YYYY-MM-DD, either may be
+// left out), answering the request itself if they are invalid.
+func expensePeriod(c *gin.Context) (models.ExpensePeriod, bool) {
+	var period models.ExpensePeriod
+	if from := c.Query("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return period, false
+		}
+		period.From = &date
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return period, false
+		}
+		next := date.AddDate(0, 0, 1)
+		period.To = &next
+	}
+	return period, true
+}
+
+// checkSubmissionWindow refuses lines dated after today and checks the
+// others against the submission window for a claim submitted on the date
+// submitted. Late lines are refused if the window blocks them; otherwise the
+// claim is flagged late with the reasons.
+func (h *ReimbursementHandler) checkSubmissionWindow(reimb *models.Reimbursement, lines []models.ReimbursementLine, submitted time.Time) error {
+	var reasons []string
+	for i, l := range lines {
+		if l.ExpenseDate.After(today()) {
+			return &invalidLinesError{fmt.Sprintf("Line %d: expense date %s is in the future", i+1, l.ExpenseDate.Format("2006-01-02"))}
+		}
+		reason := h.submission.LateReason(l.ExpenseDate, submitted)
+		if reason == "" {
+			continue
+		}
+		if h.submission.BlockLate {
+			return &invalidLinesError{fmt.Sprintf("Line %d: %s", i+1, reason)}
+		}
+		reasons = append(reasons, fmt.Sprintf("Line %d: %s", i+1, reason))
+	}
+
+	reimb.Late = len(reasons) > 0
+	reimb.LateReason = nil
+	if reimb.Late {
+		reason := strings.Join(reasons, "; ")
+		reimb.LateReason = &reason
+	}
+	return nil
+}
+
+// today returns the current date, see dateOf.
+func today() time.Time {
+	return dateOf(time.Now())
+}
+
+// dateOf returns the date of t as midnight UTC, the way dates without a time
+// are parsed and read from the database.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (h *ReimbursementHandler) currencyOrBase(currency string) string {
+	if currency == "" {
+		return h.baseCurrency
+	}
+	return currency
+}
+
+// convert sets the exchange rate and base amount of each line, using the
+// rate of its currency in effect on its expense date.
+func (h *ReimbursementHandler) convert(lines []models.ReimbursementLine) error {
+	for i := range lines {
+		l := &lines[i]
+		l.ExchangeRate = 1
+		if l.Currency != h.baseCurrency {
+			rate, err := h.rateRepo.RateOn(l.Currency, l.ExpenseDate)
+			if err == repository.ErrNoExchangeRate {
+				return &missingRateError{currency: l.Currency, date: l.ExpenseDate}
+			}
+			if err != nil {
+				return err
+			}
+			l.ExchangeRate = rate.Rate
+		}
+		l.Amount = l.OriginalAmount.Convert(l.ExchangeRate)
+	}
+	return nil
+}
+
+// missingRateError reports a line whose currency has no exchange rate on its
+// expense date.
+type missingRateError struct {
+	currency string
+	date     time.Time
+}
+
+func (e *missingRateError) Error() string {
+	return fmt.Sprintf("No exchange rate for %s on or before %s", e.currency, e.date.Format("2006-01-02"))
+}
+
+// priceLines computes the amounts of the mileage and per-diem lines.
+func (h *ReimbursementHandler) priceLines(lines []models.ReimbursementLine, employeeID, excludeID int) error {
+	if err := h.pricePerDiem(lines); err != nil {
+		return err
+	}
+	return h.priceMileage(lines, employeeID, excludeID)
+}
+
+// pricePerDiem computes the amount of each per-diem line from the rate of
+// its location on the first day of the trip, less the provided meals, and
+// keeps the breakdown with the line. The line is dated the first day.
+func (h *ReimbursementHandler) pricePerDiem(lines []models.ReimbursementLine) error {
+	for i := range lines {
+		l := &lines[i]
+		if l.Category != models.CategoryPerDiem {
+			if !l.PerDiemDetails.IsEmpty() {
+				return &invalidLinesError{fmt.Sprintf("Line %d: only per-diem lines have a trip period", i+1)}
+			}
+			l.PerDiem = nil
+			continue
+		}
+		if l.TripStart == nil || l.TripEnd == nil || l.Location == nil || strings.TrimSpace(*l.Location) == "" {
+			return &invalidLinesError{fmt.Sprintf("Line %d: a per-diem line needs trip_start, trip_end and location", i+1)}
+		}
+		if l.Currency != h.baseCurrency {
+			return &invalidLinesError{fmt.Sprintf("Line %d: per diem is paid in %s", i+1, h.baseCurrency)}
+		}
+
+		start, _ := time.Parse("2006-01-02", *l.TripStart)
+		end, _ := time.Parse("2006-01-02", *l.TripEnd)
+		if end.Before(start) {
+			return &invalidLinesError{fmt.Sprintf("Line %d: trip_end is before trip_start", i+1)}
+		}
+		if end.Sub(start) >= models.MaxPerDiemDays*24*time.Hour {
+			return &invalidLinesError{fmt.Sprintf("Line %d: a per-diem trip is at most %d days", i+1, models.MaxPerDiemDays)}
+		}
+		seen := make(map[string]bool)
+		for _, m := range l.ProvidedMeals {
+			date, _ := time.Parse("2006-01-02", m.Date)
+			if date.Before(start) || date.After(end) {
+				return &invalidLinesError{fmt.Sprintf("Line %d: provided meals on %s are outside the trip", i+1, m.Date)}
+			}
+			if seen[m.Date] {
+				return &invalidLinesError{fmt.Sprintf("Line %d: provided meals on %s are listed twice", i+1, m.Date)}
+			}
+			seen[m.Date] = true
+		}
+		if len(l.ProvidedMeals) == 0 {
+			l.ProvidedMeals = nil
+		}
+
+		rate, err := h.perDiemRepo.RateOn(*l.Location, start)
+		if err == repository.ErrNoPerDiemRate {
+			return &invalidLinesError{fmt.Sprintf("Line %d: no per-diem rate for %s on or before %s", i+1, *l.Location, *l.TripStart)}
+		}
+		if err != nil {
+			return err
+		}
+		l.PerDiem = models.NewPerDiemBreakdown(rate, start, end, l.ProvidedMeals)
+		if l.PerDiem.Total == 0 {
+			return &invalidLinesError{fmt.Sprintf("Line %d: no allowance is due when every meal is provided", i+1)}
+		}
+		l.OriginalAmount = l.PerDiem.Total
+		l.ExpenseDate = start
+	}
+	return nil
+}
+
+// priceMileage computes the amount of each mileage line from its distance,
+// rounded to 0.1 km, and the rate of its vehicle type on its expense date.
+// It then checks the employee's monthly distance cap, not counting the
+// current lines of reimbursement excludeID, which is being edited.
+func (h *ReimbursementHandler) priceMileage(lines []models.ReimbursementLine, employeeID, excludeID int) error {
+	distances := make(map[time.Time]float64)
+	for i := range lines {
+		l := &lines[i]
+		if l.Category != models.CategoryMileage {
+			if !l.MileageDetails.IsEmpty() {
+				return &invalidLinesError{fmt.Sprintf("Line %d: only mileage lines have an origin, destination, distance or vehicle", i+1)}
+			}
+			l.MileageRate = nil
+			continue
+		}
+		if !l.MileageDetails.IsComplete() {
+			return &invalidLinesError{fmt.Sprintf("Line %d: a mileage line needs origin, destination, distance_km and vehicle_type", i+1)}
+		}
+		if l.Currency != h.baseCurrency {
+			return &invalidLinesError{fmt.Sprintf("Line %d: mileage is paid in %s", i+1, h.baseCurrency)}
+		}
+
+		distance := math.Round(*l.DistanceKm*10) / 10
+		rate, err := h.mileageRepo.RateOn(*l.VehicleType, l.ExpenseDate)
+		if err == repository.ErrNoMileageRate {
+			return &invalidLinesError{fmt.Sprintf("Line %d: no mileage rate for %s on or before %s", i+1, *l.VehicleType, l.ExpenseDate.Format("2006-01-02"))}
+		}
+		if err != nil {
+			return err
+		}
+		l.DistanceKm = &distance
+		l.MileageRate = &rate.RatePerKm
+		l.OriginalAmount = rate.RatePerKm.Convert(distance)
+
+		month := time.Date(l.ExpenseDate.Year(), l.ExpenseDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+		distances[month] += distance
+	}
+
+	if h.mileageCapKm <= 0 {
+		return nil
+	}
+	months := make([]time.Time, 0, len(distances))
+	for month := range distances {
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+	for _, month := range months {
+		used, err := h.mileageRepo.DistanceInMonth(employeeID, month, excludeID)
+		if err != nil {
+			return err
+		}
+		if total := math.Round((used+distances[month])*10) / 10; total > float64(h.mileageCapKm) {
+			return &invalidLinesError{fmt.Sprintf("Mileage in %s would total %g km, over the monthly cap of %d km (%g km already claimed)",
+				month.Format("2006-01"), total, h.mileageCapKm, used)}
+		}
+	}
+	return nil
+}
+
+// invalidLinesError reports lines that cannot be accepted as submitted.
+type invalidLinesError struct {
+	message string
+}
+
+func (e *invalidLinesError) Error() string {
+	return e.message
+}
+
+// respondLinesError answers a request whose lines could not be converted or
+// priced: 400 if the lines are at fault, 500 otherwise.
+func respondLinesError(c *gin.Context, err error) {
+	var missing *missingRateError
+	var invalid *invalidLinesError
+	if errors.As(err, &missing) || errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to price the lines"})
+}
+
+// routeToChain resolves the approval chain for a reimbursement from its
+// category and amount and puts it on the first step.
+func (h *ReimbursementHandler) routeToChain(reimb *models.Reimbursement) error {
+	chain, err := h.chainRepo.Resolve(reimb.Category, reimb.Amount)
+	if err != nil {
+		return err
+	}
+	approverID, err := h.stepApprover(chain.Steps[0], reimb)
+	if err != nil {
+		return err
+	}
+	reimb.ApprovalChainID = &chain.ID
+	reimb.CurrentStep = 1
+	reimb.CurrentApproverID = approverID
+	return nil
+}
+
+// stepApprover returns the user a chain step is assigned to for reimb, or nil
+// when anyone with the step's role may decide it. Cost-center owner steps go
+// to the owner of the claim's main cost center, and are otherwise handled by
+// their role. Manager steps go to the nearest manager up the submitter's
+// reporting line; if there is none the step stays open to all managers.
+func (h *ReimbursementHandler) stepApprover(step models.ApprovalStep, reimb *models.Reimbursement) (*int, error) {
+	if step.RequiredUserID != nil {
+		return step.RequiredUserID, nil
+	}
+	if step.CostCenterOwner {
+		ownerID, err := h.costCenterOwner(reimb)
+		if err != nil || ownerID != nil {
+			return ownerID, err
+		}
+	}
+	if step.RequiredRole != nil && *step.RequiredRole == models.RoleManager {
+		return h.userRepo.FindManagerAbove(reimb.EmployeeID)
+	}
+	return nil, nil
+}
+
+// costCenterOwner returns the owner of the cost center most of reimb is
+// charged to (the first one given on a tie), or nil if the claim has no
+// allocations, that cost center has no owner or the owner submitted the
+// claim.
+func (h *ReimbursementHandler) costCenterOwner(reimb *models.Reimbursement) (*int, error) {
+	allocations := reimb.Allocations
+	if allocations == nil && reimb.ID != 0 {
+		var err error
+		allocations, err = h.reimbRepo.GetAllocations(reimb.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(allocations) == 0 {
+		return nil, nil
+	}
+
+	charged := make(map[int]models.Money)
+	for _, a := range allocations {
+		charged[a.CostCenterID] += a.Amount
+	}
+	main := allocations[0].CostCenterID
+	for _, a := range allocations {
+		if charged[a.CostCenterID] > charged[main] {
+			main = a.CostCenterID
+		}
+	}
+
+	costCenter, err := h.costCenterRepo.GetByID(main)
+	if err != nil {
+		return nil, err
+	}
+	if costCenter.OwnerID == nil || *costCenter.OwnerID == reimb.EmployeeID {
+		return nil, nil
+	}
+	return costCenter.OwnerID, nil
+}
+
+// checkDuties applies the enabled separation-of-duties rules to a decision
+// by userID, acting for onBehalfOf if not nil. Both people count: a delegate
+// may not decide for the submitter, nor decide their own claim for someone
+// else.
+func (h *ReimbursementHandler) checkDuties(reimb *models.Reimbursement, action string, userID int, onBehalfOf *int) error {
+	actors := []int{userID}
+	if onBehalfOf != nil {
+		actors = append(actors, *onBehalfOf)
+	}
+
+	enabled, err := h.ruleRepo.IsEnabled(models.RuleNoSelfApproval)
+	if err != nil {
+		return &decisionError{status: http.StatusInternalServerError, message: "Failed to check separation-of-duties rules"}
+	}
+	if enabled {
+		for _, actor := range actors {
+			if actor == reimb.EmployeeID {
+				return &decisionError{status: http.StatusForbidden, message: "Separation of duties: the submitter cannot decide their own reimbursement"}
+			}
+		}
+	}
+
+	if action != "approve" {
+		return nil
+	}
+	enabled, err = h.ruleRepo.IsEnabled(models.RuleDistinctApprovers)
+	if err != nil {
+		return &decisionError{status: http.StatusInternalServerError, message: "Failed to check separation-of-duties rules"}
+	}
+	if !enabled {
+		return nil
+	}
+	approvals, err := h.reimbRepo.GetApprovals(reimb.ID)
+	if err != nil {
+		return &decisionError{status: http.StatusInternalServerError, message: "Failed to check separation-of-duties rules"}
+	}
+	for _, a := range approvals {
+		if a.Action != "approve" {
+			continue
+		}
+		for _, actor := range actors {
+			if (a.ApproverID != nil && *a.ApproverID == actor) || (a.OnBehalfOfID != nil && *a.OnBehalfOfID == actor) {
+				return &decisionError{status: http.StatusForbidden, message: "Separation of duties: the same person cannot approve more than one step of a reimbursement"}
+			}
+		}
+	}
+	return nil
+}
+
+// actingFor reports whether a user may decide the current step of a
+// reimbursement. The assigned approver may, and so may anyone they have
+// delegated to for today, in which case the assigned approver is returned as
+// the user being acted for. Unassigned steps are open to anyone the step
+// allows.
+func (h *ReimbursementHandler) actingFor(reimb *models.Reimbursement, step models.ApprovalStep, userID int, role models.UserRole) (*int, bool, error) {
+	if reimb.CurrentApproverID == nil {
+		return nil, step.CanAct(userID, role), nil
+	}
+	if *reimb.CurrentApproverID == userID {
+		return nil, true, nil
+	}
+	delegated, err := h.delegationRepo.IsActive(*reimb.CurrentApproverID, userID)
+	if err != nil || !delegated {
+		return nil, false, err
+	}
+	return reimb.CurrentApproverID, true, nil
+}
+
+// canView reports whether the current user may see a reimbursement.
+// Employees can only see their own, managers the ones in their scope (see
+// ReimbursementRepository.GetVisibleToManager) and finance everything but
+// drafts, which only their submitter sees.
+func canView(c *gin.Context, reimbRepo *repository.ReimbursementRepository, reimb *models.Reimbursement) bool {
+	userRole, _ := c.Get("role")
+	userID, _ := c.Get("user_id")
+	// Drafts are seen only by their submitter
+	if reimb.Status == models.StatusDraft {
+		return reimb.EmployeeID == userID.(int)
+	}
+	switch userRole {
+	case models.RoleEmployee:
+		return reimb.EmployeeID == userID.(int)
+	case models.RoleManager:
+		visible, err := reimbRepo.IsVisibleToManager(reimb.ID, userID.(int))
+		return err == nil && visible
+	default:
+		return true
+	}
+}
+
+const conflictMessage = "Reimbursement was changed by someone else, please reload and try again"
+
+const advanceNotOpenMessage = "Claims can only be filed against a cash advance that is paid out and not settled"
+
+// actorFrom describes the current user and their request, for the history
+// written with every change.
+func actorFrom(c *gin.Context) models.Actor {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("role")
+	return models.Actor{
+		UserID:    userID.(int),
+		Role:      userRole.(models.UserRole),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// respondWriteError reports a failed repository write. Status conflicts from
+// guarded writes become 409 so the client knows to reload, as does a claim
+// filed against a cash advance settled in the meantime; anything else is a
+// 500 with the given message.
+func respondWriteError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrStatusConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": conflictMessage})
+		return
+	}
+	if errors.Is(err, repository.ErrAdvanceNotOpen) {
+		c.JSON(http.StatusConflict, gin.H{"error": advanceNotOpenMessage})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// decisionError is an approval decision that was refused, with the HTTP
+// status and message to report for it.
+type decisionError struct {
+	status  int
+	message string
+}
+
+func (e *decisionError) Error() string {
+	return e.message
+}
+
+// decisionFailure returns the HTTP status and message for an error from
+// decide, mapping repository errors like respondWriteError does.
+func decisionFailure(err error) (int, string) {
+	var refused *decisionError
+	switch {
+	case errors.As(err, &refused):
+		return refused.status, refused.message
+	case errors.Is(err, repository.ErrStatusConflict):
+		return http.StatusConflict, conflictMessage
+	default:
+		return http.StatusInternalServerError, "Failed to process approval"
+	}
 }
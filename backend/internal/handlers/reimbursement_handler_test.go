@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"testing"
+
+	"reimbursement-backend/internal/models"
+)
+
+func TestSplitAllocations(t *testing.T) {
+	intPtr := func(id int) *int { return &id }
+	percent := func(p float64) *float64 { return &p }
+	amount := func(m models.Money) *models.Money { return &m }
+
+	costCenters := map[int]models.CostCenter{
+		1: {ID: 1, Code: "OPS", Active: true},
+		2: {ID: 2, Code: "SALES", Active: true},
+		3: {ID: 3, Code: "RND", Active: true},
+		4: {ID: 4, Code: "OLD", Active: false},
+	}
+	projects := map[int]models.Project{
+		10: {ID: 10, Code: "ACME", CostCenterID: intPtr(2), Active: true},
+		11: {ID: 11, Code: "NOCC", Active: true},
+	}
+
+	tests := []struct {
+		name        string
+		reqs        []models.AllocationRequest
+		total       models.Money
+		checkActive bool
+		want        []models.Money
+		wantErr     bool
+	}{
+		{
+			name:  "no allocations",
+			total: 100000,
+			want:  []models.Money{},
+		},
+		{
+			name:  "even percentages",
+			reqs:  []models.AllocationRequest{{CostCenterID: intPtr(1), Percent: percent(50)}, {CostCenterID: intPtr(2), Percent: percent(50)}},
+			total: 100000,
+			want:  []models.Money{50000, 50000},
+		},
+		{
+			name: "last allocation takes the rounding remainder",
+			reqs: []models.AllocationRequest{
+				{CostCenterID: intPtr(1), Percent: percent(33.33)},
+				{CostCenterID: intPtr(2), Percent: percent(33.33)},
+				{CostCenterID: intPtr(3), Percent: percent(33.34)},
+			},
+			total: 100,
+			want:  []models.Money{33, 33, 34},
+		},
+		{
+			name: "remainder keeps the total exact",
+			reqs: []models.AllocationRequest{
+				{CostCenterID: intPtr(1), Percent: percent(33.33)},
+				{CostCenterID: intPtr(2), Percent: percent(33.33)},
+				{CostCenterID: intPtr(3), Percent: percent(33.34)},
+			},
+			total: 1000001,
+			want:  []models.Money{333300, 333300, 333401},
+		},
+		{
+			name:    "percentages must add up to 100",
+			reqs:    []models.AllocationRequest{{CostCenterID: intPtr(1), Percent: percent(60)}, {CostCenterID: intPtr(2), Percent: percent(30)}},
+			total:   100000,
+			wantErr: true,
+		},
+		{
+			name:    "percent with more than 2 decimals",
+			reqs:    []models.AllocationRequest{{CostCenterID: intPtr(1), Percent: percent(33.333)}, {CostCenterID: intPtr(2), Percent: percent(66.667)}},
+			total:   100000,
+			wantErr: true,
+		},
+		{
+			name: "amount too small to split",
+			reqs: []models.AllocationRequest{
+				{CostCenterID: intPtr(1), Percent: percent(50)},
+				{CostCenterID: intPtr(2), Percent: percent(49.5)},
+				{CostCenterID: intPtr(3), Percent: percent(0.5)},
+			},
+			total:   1,
+			wantErr: true,
+		},
+		{
+			name:  "amounts adding up to total",
+			reqs:  []models.AllocationRequest{{CostCenterID: intPtr(1), Amount: amount(70000)}, {CostCenterID: intPtr(2), Amount: amount(30000)}},
+			total: 100000,
+			want:  []models.Money{70000, 30000},
+		},
+		{
+			name:    "amounts not adding up to total",
+			reqs:    []models.AllocationRequest{{CostCenterID: intPtr(1), Amount: amount(70000)}, {CostCenterID: intPtr(2), Amount: amount(20000)}},
+			total:   100000,
+			wantErr: true,
+		},
+		{
+			name:    "mixed percentages and amounts",
+			reqs:    []models.AllocationRequest{{CostCenterID: intPtr(1), Percent: percent(50)}, {CostCenterID: intPtr(2), Amount: amount(50000)}},
+			total:   100000,
+			wantErr: true,
+		},
+		{
+			name:    "both percent and amount",
+			reqs:    []models.AllocationRequest{{CostCenterID: intPtr(1), Percent: percent(100), Amount: amount(100000)}},
+			total:   100000,
+			wantErr: true,
+		},
+		{
+			name:  "project gives its cost center",
+			reqs:  []models.AllocationRequest{{ProjectID: intPtr(10), Percent: percent(100)}},
+			total: 100000,
+			want:  []models.Money{100000},
+		},
+		{
+			name:    "project without a cost center",
+			reqs:    []models.AllocationRequest{{ProjectID: intPtr(11), Percent: percent(100)}},
+			total:   100000,
+			wantErr: true,
+		},
+		{
+			name:    "unknown cost center",
+			reqs:    []models.AllocationRequest{{CostCenterID: intPtr(99), Percent: percent(100)}},
+			total:   100000,
+			wantErr: true,
+		},
+		{
+			name:    "same cost center twice",
+			reqs:    []models.AllocationRequest{{CostCenterID: intPtr(1), Percent: percent(50)}, {CostCenterID: intPtr(1), Percent: percent(50)}},
+			total:   100000,
+			wantErr: true,
+		},
+		{
+			name:        "inactive cost center refused",
+			reqs:        []models.AllocationRequest{{CostCenterID: intPtr(4), Percent: percent(100)}},
+			total:       100000,
+			checkActive: true,
+			wantErr:     true,
+		},
+		{
+			name:  "inactive cost center kept",
+			reqs:  []models.AllocationRequest{{CostCenterID: intPtr(4), Percent: percent(100)}},
+			total: 100000,
+			want:  []models.Money{100000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitAllocations(tt.reqs, tt.total, costCenters, projects, tt.checkActive)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("splitAllocations gave %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitAllocations returned %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitAllocations gave %d allocations, want %d", len(got), len(tt.want))
+			}
+			var sum models.Money
+			for i, a := range got {
+				if a.Amount != tt.want[i] {
+					t.Errorf("allocation %d amount = %s, want %s", i+1, a.Amount, tt.want[i])
+				}
+				sum += a.Amount
+			}
+			if len(got) > 0 && sum != tt.total {
+				t.Errorf("allocations add up to %s, want %s", sum, tt.total)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+type ReportHandler struct {
+	reportRepo   *repository.ReportRepository
+	baseCurrency string
+}
+
+func NewReportHandler(reportRepo *repository.ReportRepository, baseCurrency string) *ReportHandler {
+	return &ReportHandler{
+		reportRepo:   reportRepo,
+		baseCurrency: baseCurrency,
+	}
+}
+
+// GetTaxReport reports the reclaimable tax by month and category, optionally
+// limited to the months ?from through ?to (YYYY-MM).
+func (h *ReportHandler) GetTaxReport(c *gin.Context) {
+	report := models.TaxReport{From: c.Query("from"), To: c.Query("to"), Currency: h.baseCurrency}
+
+	from, to, ok := reportPeriod(c, report.From, report.To)
+	if !ok {
+		return
+	}
+
+	rows, err := h.reportRepo.TaxByMonth(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build tax report"})
+		return
+	}
+
+	report.Rows = rows
+	for _, row := range rows {
+		report.TotalNetAmount += row.NetAmount
+		report.TotalTaxAmount += row.TaxAmount
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetAllocationReport reports the payable amount of finance-approved claims
+// by month, cost center and project, optionally limited to the months ?from
+// through ?to (YYYY-MM).
+func (h *ReportHandler) GetAllocationReport(c *gin.Context) {
+	report := models.AllocationReport{From: c.Query("from"), To: c.Query("to"), Currency: h.baseCurrency}
+
+	from, to, ok := reportPeriod(c, report.From, report.To)
+	if !ok {
+		return
+	}
+
+	rows, err := h.reportRepo.AllocationsByMonth(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build allocation report"})
+		return
+	}
+
+	report.Rows = rows
+	for _, row := range rows {
+		report.TotalAmount += row.Amount
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetAdvanceReport lists the cash advances paid out and not yet settled,
+// with the balance each employee still holds.
+func (h *ReportHandler) GetAdvanceReport(c *gin.Context) {
+	rows, err := h.reportRepo.OutstandingAdvances()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build advance report"})
+		return
+	}
+
+	report := models.AdvanceReport{Currency: h.baseCurrency, Rows: rows}
+	for _, row := range rows {
+		report.TotalAmount += row.Amount
+		report.TotalClaimed += row.ClaimedAmount
+		report.TotalBalance += row.Balance
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// reportPeriod parses the months fromMonth through toMonth (YYYY-MM, either
+// may be empty) into the start of the first and the end of the last,
+// answering the request itself if they are invalid.
+func reportPeriod(c *gin.Context, fromMonth, toMonth string) (from, to *time.Time, ok bool) {
+	if fromMonth != "" {
+		month, err := time.Parse("2006-01", fromMonth)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from month, expected YYYY-MM"})
+			return nil, nil, false
+		}
+		from = &month
+	}
+	if toMonth != "" {
+		month, err := time.Parse("2006-01", toMonth)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to month, expected YYYY-MM"})
+			return nil, nil, false
+		}
+		// Up to the end of the month
+		end := month.AddDate(0, 1, 0)
+		to = &end
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return nil, nil, false
+	}
+	return from, to, true
+}
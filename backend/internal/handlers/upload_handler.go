@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -12,6 +13,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// uploadedName matches the names upload gives the files it stores.
+var uploadedName = regexp.MustCompile(`^[0-9]{8}-[0-9]{6}-[0-9a-f]{8}\.(jpg|jpeg|png|pdf|gif|webp)$`)
+
 type UploadHandler struct {
 	uploadDir string
 }
@@ -28,8 +32,18 @@ func NewUploadHandler(uploadDir string) *UploadHandler {
 }
 
 func (h *UploadHandler) UploadReceipt(c *gin.Context) {
+	h.upload(c, "receipt")
+}
+
+// UploadAttachment stores a file attached to a reimbursement comment.
+func (h *UploadHandler) UploadAttachment(c *gin.Context) {
+	h.upload(c, "attachment")
+}
+
+// upload saves the file sent in the given form field and returns its URL.
+func (h *UploadHandler) upload(c *gin.Context, field string) {
 	// Get the file from the request
-	file, err := c.FormFile("receipt")
+	file, err := c.FormFile(field)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
 		return
@@ -79,3 +93,14 @@ func (h *UploadHandler) UploadReceipt(c *gin.Context) {
 		"size":     file.Size,
 	})
 }
+
+// isUploadedFile reports whether url is the URL upload returned for a file
+// stored in uploadDir, as opposed to a link to anywhere else.
+func isUploadedFile(uploadDir, url string) bool {
+	name, ok := strings.CutPrefix(url, "/uploads/")
+	if !ok || !uploadedName.MatchString(name) {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(uploadDir, name))
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"time"
+)
+
+type AdvanceStatus string
+
+const (
+	AdvancePending   AdvanceStatus = "pending"
+	AdvanceApproved  AdvanceStatus = "approved"
+	AdvanceRejected  AdvanceStatus = "rejected"
+	AdvanceDisbursed AdvanceStatus = "disbursed"
+	AdvanceSettled   AdvanceStatus = "settled"
+	AdvanceCancelled AdvanceStatus = "cancelled"
+)
+
+// EventDisbursed is the cash advance event action of a payout. The other
+// transitions of an advance are recorded with the reimbursement event
+// actions of the same name.
+const EventDisbursed = "disbursed"
+
+// advanceTransitions is the cash advance lifecycle, in the form of
+// statusTransitions. A pending advance is decided by the nearest manager
+// above the employee (see CashAdvance.ApproverID), then finance pays it out.
+// Once disbursed it stays open until finance settles it against the claims
+// filed for it.
+var advanceTransitions = map[AdvanceStatus]map[AdvanceStatus][]UserRole{
+	AdvancePending: {
+		AdvanceApproved:  {RoleManager, RoleFinance},
+		AdvanceRejected:  {RoleManager, RoleFinance},
+		AdvanceCancelled: {RoleEmployee},
+	},
+	AdvanceApproved: {
+		AdvanceDisbursed: {RoleFinance},
+		AdvanceRejected:  {RoleFinance},
+		AdvanceCancelled: {RoleEmployee},
+	},
+	AdvanceDisbursed: {
+		AdvanceSettled: {RoleFinance},
+	},
+}
+
+// CanAdvanceTransition reports whether a user with the given role may move a
+// cash advance from one status to another.
+func CanAdvanceTransition(from, to AdvanceStatus, role UserRole) bool {
+	for _, allowed := range advanceTransitions[from][to] {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CashAdvance is money paid to an employee up front, usually for a long
+// trip, in the base currency. Claims filed for it (see
+// Reimbursement.AdvanceID) are not paid out one by one but settled against
+// it: ClaimedAmount is the payable amount of those approved by finance, and
+// the difference with Amount is either returned to the company or topped up
+// to the employee (see Reconcile). Until the advance is settled these are
+// what a settlement would record today.
+type CashAdvance struct {
+	ID                    int            `json:"id" db:"id"`
+	EmployeeID            int            `json:"employee_id" db:"employee_id"`
+	EmployeeName          string         `json:"employee_name" db:"employee_name"`
+	Purpose               string         `json:"purpose" db:"purpose"`
+	Amount                Money          `json:"amount" db:"amount"`
+	Status                AdvanceStatus  `json:"status" db:"status"`
+	ApproverID            *int           `json:"approver_id,omitempty" db:"approver_id"`
+	DecidedBy             *int           `json:"decided_by,omitempty" db:"decided_by"`
+	DecidedAt             *time.Time     `json:"decided_at,omitempty" db:"decided_at"`
+	DecisionNotes         *string        `json:"decision_notes,omitempty" db:"decision_notes"`
+	DisbursedDate         *time.Time     `json:"disbursed_date,omitempty" db:"disbursed_date"`
+	DisbursedBy           *int           `json:"disbursed_by,omitempty" db:"disbursed_by"`
+	DisbursementMethod    *PaymentMethod `json:"disbursement_method,omitempty" db:"disbursement_method"`
+	DisbursementReference *string        `json:"disbursement_reference,omitempty" db:"disbursement_reference"`
+	SettledDate           *time.Time     `json:"settled_date,omitempty" db:"settled_date"`
+	SettledBy             *int           `json:"settled_by,omitempty" db:"settled_by"`
+	SettlementMethod      *PaymentMethod `json:"settlement_method,omitempty" db:"settlement_method"`
+	SettlementReference   *string        `json:"settlement_reference,omitempty" db:"settlement_reference"`
+	CancelledAt           *time.Time     `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CreatedAt             time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at" db:"updated_at"`
+
+	ClaimedAmount Money          `json:"claimed_amount" db:"claimed_amount"`
+	Balance       Money          `json:"balance"`
+	ReturnAmount  Money          `json:"return_amount"`
+	TopUpAmount   Money          `json:"top_up_amount"`
+	Claims        []AdvanceClaim `json:"claims,omitempty"`
+}
+
+// Reconcile sets the balance of the advance from its ClaimedAmount: what is
+// left of the advance is to be returned by the employee, what was claimed
+// beyond it is to be topped up by the company.
+func (a *CashAdvance) Reconcile() {
+	a.Balance = a.Amount - a.ClaimedAmount
+	a.ReturnAmount, a.TopUpAmount = 0, 0
+	if a.Balance > 0 {
+		a.ReturnAmount = a.Balance
+	} else {
+		a.TopUpAmount = -a.Balance
+	}
+}
+
+// AdvanceEvent is one entry of the append-only history of a cash advance,
+// written in the same transaction as the change it describes, as
+// ReimbursementEvent is for claims.
+type AdvanceEvent struct {
+	ID         int            `json:"id" db:"id"`
+	AdvanceID  int            `json:"advance_id" db:"advance_id"`
+	Action     string         `json:"action" db:"action"`
+	ActorID    *int           `json:"actor_id,omitempty" db:"actor_id"`
+	ActorRole  *UserRole      `json:"actor_role,omitempty" db:"actor_role"`
+	FromStatus *AdvanceStatus `json:"from_status,omitempty" db:"from_status"`
+	ToStatus   *AdvanceStatus `json:"to_status,omitempty" db:"to_status"`
+	Notes      *string        `json:"notes,omitempty" db:"notes"`
+	IPAddress  *string        `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent  *string        `json:"user_agent,omitempty" db:"user_agent"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
+
+// AdvanceClaim is a claim filed against a cash advance, with the amount it
+// counts for once finance has approved it.
+type AdvanceClaim struct {
+	ID            int                 `json:"id"`
+	Title         string              `json:"title"`
+	Status        ReimbursementStatus `json:"status"`
+	ExpenseDate   *time.Time          `json:"expense_date,omitempty"`
+	PayableAmount Money               `json:"payable_amount"`
+}
+
+type CreateAdvanceRequest struct {
+	Purpose string `json:"purpose" binding:"required,max=500"`
+	Amount  Money  `json:"amount" binding:"required,gt=0"`
+}
+
+// AdvanceDecisionRequest approves or rejects a pending cash advance. Finance
+// may also reject an approved advance it has not paid out.
+type AdvanceDecisionRequest struct {
+	Action string  `json:"action" binding:"required,oneof=approve reject"`
+	Notes  *string `json:"notes"`
+}
+
+type DisburseAdvanceRequest struct {
+	DisbursedDate string        `json:"disbursed_date" binding:"required,datetime=2006-01-02"`
+	Method        PaymentMethod `json:"method" binding:"required,oneof=bank_transfer cash payroll"`
+	Reference     string        `json:"reference" binding:"required,max=100"`
+}
+
+// SettleAdvanceRequest closes a cash advance. Method and Reference record how
+// the balance was returned or topped up, and are required unless the claims
+// used up the advance exactly.
+type SettleAdvanceRequest struct {
+	SettledDate string        `json:"settled_date" binding:"required,datetime=2006-01-02"`
+	Method      PaymentMethod `json:"method" binding:"omitempty,oneof=bank_transfer cash payroll"`
+	Reference   string        `json:"reference" binding:"max=100"`
+}
+
+// AdvanceReportRow is a disbursed cash advance not settled yet. InApproval
+// is the claimed amount of the claims filed for it that are still being
+// approved.
+type AdvanceReportRow struct {
+	ID            int       `json:"id"`
+	EmployeeID    int       `json:"employee_id"`
+	EmployeeName  string    `json:"employee_name"`
+	Purpose       string    `json:"purpose"`
+	DisbursedDate time.Time `json:"disbursed_date"`
+	DaysOpen      int       `json:"days_open"`
+	Amount        Money     `json:"amount"`
+	ClaimedAmount Money     `json:"claimed_amount"`
+	InApproval    Money     `json:"in_approval"`
+	Balance       Money     `json:"balance"`
+}
+
+// AdvanceReport lists the outstanding cash advances, oldest first, in the
+// base currency. TotalBalance is what the employees still hold, net of
+// advances already overspent.
+type AdvanceReport struct {
+	Currency     string             `json:"currency"`
+	Rows         []AdvanceReportRow `json:"rows"`
+	TotalAmount  Money              `json:"total_amount"`
+	TotalClaimed Money              `json:"total_claimed"`
+	TotalBalance Money              `json:"total_balance"`
+}
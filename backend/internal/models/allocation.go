@@ -0,0 +1,148 @@
+package models
+
+import (
+	"reflect"
+	"time"
+)
+
+// CostCenter is a department expenses are charged to. Its Owner, a manager
+// or finance user, may be asked to approve claims charged to it (see
+// ApprovalStep.CostCenterOwner).
+type CostCenter struct {
+	ID        int       `json:"id" db:"id"`
+	Code      string    `json:"code" db:"code"`
+	Name      string    `json:"name" db:"name"`
+	OwnerID   *int      `json:"owner_id,omitempty" db:"owner_id"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateCostCenterRequest struct {
+	Code    string `json:"code" binding:"required,max=30"`
+	Name    string `json:"name" binding:"required,max=100"`
+	OwnerID *int   `json:"owner_id" binding:"omitempty,gt=0"`
+}
+
+// UpdateCostCenterRequest edits the fields given of a cost center. Setting
+// Active reactivates or deactivates it.
+type UpdateCostCenterRequest struct {
+	Name    *string `json:"name" binding:"omitempty,min=1,max=100"`
+	OwnerID *int    `json:"owner_id" binding:"omitempty,gt=0"`
+	Active  *bool   `json:"active"`
+}
+
+// Project is a client project expenses are charged to. CostCenterID is the
+// cost center its expenses are charged to when an allocation names only the
+// project.
+type Project struct {
+	ID           int       `json:"id" db:"id"`
+	Code         string    `json:"code" db:"code"`
+	Name         string    `json:"name" db:"name"`
+	Client       *string   `json:"client,omitempty" db:"client"`
+	CostCenterID *int      `json:"cost_center_id,omitempty" db:"cost_center_id"`
+	Active       bool      `json:"active" db:"active"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateProjectRequest struct {
+	Code         string  `json:"code" binding:"required,max=30"`
+	Name         string  `json:"name" binding:"required,max=100"`
+	Client       *string `json:"client" binding:"omitempty,max=100"`
+	CostCenterID *int    `json:"cost_center_id" binding:"omitempty,gt=0"`
+}
+
+// UpdateProjectRequest edits the fields given of a project. Setting Active
+// reactivates or deactivates it.
+type UpdateProjectRequest struct {
+	Name         *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Client       *string `json:"client" binding:"omitempty,max=100"`
+	CostCenterID *int    `json:"cost_center_id" binding:"omitempty,gt=0"`
+	Active       *bool   `json:"active"`
+}
+
+// Allocation charges part of a reimbursement to a cost center and, if set,
+// a project. Amount is the part of the claimed amount charged; allocations
+// given as a Percent keep it so they follow changes to the amount.
+type Allocation struct {
+	ID              int      `json:"id" db:"id"`
+	ReimbursementID int      `json:"reimbursement_id" db:"reimbursement_id"`
+	CostCenterID    int      `json:"cost_center_id" db:"cost_center_id"`
+	CostCenterCode  string   `json:"cost_center_code" db:"cost_center_code"`
+	ProjectID       *int     `json:"project_id,omitempty" db:"project_id"`
+	ProjectCode     *string  `json:"project_code,omitempty" db:"project_code"`
+	Percent         *float64 `json:"percent,omitempty" db:"percent"`
+	Amount          Money    `json:"amount" db:"amount"`
+}
+
+// AllocationRequest is one allocation of a claim, given either as a Percent
+// or as an Amount in the base currency; every allocation of a claim must use
+// the same one. CostCenterID may be left out if the project has a cost
+// center.
+type AllocationRequest struct {
+	CostCenterID *int     `json:"cost_center_id" binding:"omitempty,gt=0"`
+	ProjectID    *int     `json:"project_id" binding:"omitempty,gt=0"`
+	Percent      *float64 `json:"percent" binding:"omitempty,gt=0,lte=100"`
+	Amount       *Money   `json:"amount" binding:"omitempty,gt=0"`
+}
+
+// AllocationReportRow is what finance-approved claims dated in Month
+// charged to a cost center and project, in the base currency. Claims without
+// allocations are reported with no cost center.
+type AllocationReportRow struct {
+	Month          string  `json:"month"`
+	CostCenterCode *string `json:"cost_center_code"`
+	CostCenterName *string `json:"cost_center_name,omitempty"`
+	ProjectCode    *string `json:"project_code,omitempty"`
+	ProjectName    *string `json:"project_name,omitempty"`
+	Claims         int     `json:"claims"`
+	Amount         Money   `json:"amount"`
+}
+
+// AllocationReport aggregates the payable amount of finance-approved claims
+// by the month of their expense date, cost center and project. From and To
+// are the months covered, YYYY-MM, if limited.
+type AllocationReport struct {
+	From        string                `json:"from,omitempty"`
+	To          string                `json:"to,omitempty"`
+	Currency    string                `json:"currency"`
+	Rows        []AllocationReportRow `json:"rows"`
+	TotalAmount Money                 `json:"total_amount"`
+}
+
+// SameAllocations reports whether two sets of allocations charge the same
+// amounts to the same cost centers and projects.
+func SameAllocations(a, b []Allocation) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].CostCenterID != b[i].CostCenterID || a[i].Amount != b[i].Amount ||
+			!reflect.DeepEqual(a[i].ProjectID, b[i].ProjectID) || !reflect.DeepEqual(a[i].Percent, b[i].Percent) {
+			return false
+		}
+	}
+	return true
+}
+
+// ShareAllocations shares payable between allocations in proportion to their
+// amounts. Every share but the last is rounded to the cent and the last takes
+// what is left, so the shares add up to payable exactly.
+func ShareAllocations(payable Money, amounts []Money) []Money {
+	var whole Money
+	for _, a := range amounts {
+		whole += a
+	}
+	shares := make([]Money, len(amounts))
+	var shared Money
+	for i, a := range amounts {
+		if i == len(amounts)-1 || whole == 0 {
+			shares[i] = payable - shared
+			break
+		}
+		shares[i] = payable.Share(a, whole)
+		shared += shares[i]
+	}
+	return shares
+}
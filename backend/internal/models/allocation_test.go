@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestShareAllocations(t *testing.T) {
+	tests := []struct {
+		name    string
+		payable Money
+		amounts []Money
+		want    []Money
+	}{
+		{name: "no allocations", payable: 10000, amounts: []Money{}, want: []Money{}},
+		{name: "single allocation takes everything", payable: 7500, amounts: []Money{10000}, want: []Money{7500}},
+		{name: "uneven three-way split unchanged", payable: 10000, amounts: []Money{3333, 3333, 3334}, want: []Money{3333, 3333, 3334}},
+		{name: "uneven three-way split lowered", payable: 5000, amounts: []Money{3333, 3333, 3334}, want: []Money{1667, 1667, 1666}},
+		{name: "uneven three-way split of a cent less", payable: 9999, amounts: []Money{3333, 3333, 3334}, want: []Money{3333, 3333, 3333}},
+		{name: "even split of an odd amount", payable: 5001, amounts: []Money{5000, 5000}, want: []Money{2501, 2500}},
+		{name: "ratio beyond float precision", payable: 100000000000, amounts: []Money{1, 2}, want: []Money{33333333333, 66666666667}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ShareAllocations(tt.payable, tt.amounts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ShareAllocations gave %d shares, want %d", len(got), len(tt.want))
+			}
+			var sum Money
+			for i, share := range got {
+				if share != tt.want[i] {
+					t.Errorf("share %d = %s, want %s", i+1, share, tt.want[i])
+				}
+				sum += share
+			}
+			if len(got) > 0 && sum != tt.payable {
+				t.Errorf("shares add up to %s, want %s", sum, tt.payable)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+)
+
+// ApprovalChain is an ordered list of approval steps. A reimbursement is
+// routed through the active chain whose category and amount range match it;
+// chains are never edited once created so in-flight claims keep their steps.
+type ApprovalChain struct {
+	ID        int                    `json:"id" db:"id"`
+	Name      string                 `json:"name" db:"name"`
+	Category  *ReimbursementCategory `json:"category,omitempty" db:"category"`
+	MinAmount Money                  `json:"min_amount" db:"min_amount"`
+	MaxAmount *Money                 `json:"max_amount,omitempty" db:"max_amount"`
+	Priority  int                    `json:"priority" db:"priority"`
+	Active    bool                   `json:"active" db:"active"`
+	Steps     []ApprovalStep         `json:"steps"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+}
+
+// ApprovalStep is one step of a chain. It is decided either by a specific
+// user or by any user with the required role. A CostCenterOwner step is
+// assigned to the owner of the cost center the claim is mostly charged to,
+// and falls back to the required role if there is none.
+type ApprovalStep struct {
+	ID              int       `json:"id" db:"id"`
+	ChainID         int       `json:"chain_id" db:"chain_id"`
+	StepOrder       int       `json:"step_order" db:"step_order"`
+	Name            string    `json:"name" db:"name"`
+	RequiredRole    *UserRole `json:"required_role,omitempty" db:"required_role"`
+	RequiredUserID  *int      `json:"required_user_id,omitempty" db:"required_user_id"`
+	CostCenterOwner bool      `json:"cost_center_owner,omitempty" db:"cost_center_owner"`
+}
+
+// CanAct reports whether the given user may decide this step.
+func (s ApprovalStep) CanAct(userID int, role UserRole) bool {
+	if s.RequiredUserID != nil {
+		return *s.RequiredUserID == userID
+	}
+	return s.RequiredRole != nil && *s.RequiredRole == role
+}
+
+// Approval is a recorded decision on one step of a reimbursement.
+type Approval struct {
+	ID               int       `json:"id" db:"id"`
+	ReimbursementID  int       `json:"reimbursement_id" db:"reimbursement_id"`
+	StepOrder        int       `json:"step_order" db:"step_order"`
+	StepName         string    `json:"step_name" db:"step_name"`
+	ApproverID       *int      `json:"approver_id,omitempty" db:"approver_id"`
+	OnBehalfOfID     *int      `json:"on_behalf_of_id,omitempty" db:"on_behalf_of_id"`
+	Action           string    `json:"action" db:"action"`
+	Notes            *string   `json:"notes,omitempty" db:"notes"`
+	ApprovedAmount   *Money    `json:"approved_amount,omitempty" db:"approved_amount"`
+	AdjustmentReason *string   `json:"adjustment_reason,omitempty" db:"adjustment_reason"`
+	Version          *int      `json:"version,omitempty" db:"version"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
+// ApprovalDecision describes a decision about to be applied to the current
+// step of a reimbursement.
+type ApprovalDecision struct {
+	ReimbursementID  int
+	Step             ApprovalStep
+	FromStatus       ReimbursementStatus
+	ToStatus         ReimbursementStatus
+	NextStep         int
+	NextApproverID   *int
+	ApproverID       int
+	OnBehalfOfID     *int
+	Action           string
+	Notes            *string
+	ApprovedAmount   *Money
+	AdjustmentReason *string
+	LineDecisions    []LineDecision
+	Version          int
+	Actor            Actor
+}
+
+type CreateApprovalChainRequest struct {
+	Name      string                      `json:"name" binding:"required"`
+	Category  *ReimbursementCategory      `json:"category"`
+	MinAmount Money                       `json:"min_amount" binding:"gte=0"`
+	MaxAmount *Money                      `json:"max_amount" binding:"omitempty,gt=0"`
+	Priority  int                         `json:"priority"`
+	Steps     []CreateApprovalStepRequest `json:"steps" binding:"required,min=1,dive"`
+}
+
+type CreateApprovalStepRequest struct {
+	Name            string    `json:"name" binding:"required"`
+	RequiredRole    *UserRole `json:"required_role" binding:"omitempty,oneof=manager finance"`
+	RequiredUserID  *int      `json:"required_user_id"`
+	CostCenterOwner bool      `json:"cost_center_owner"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// Category is an expense category. Categories are managed by finance; a
+// deactivated category is kept for the claims that use it but cannot be
+// chosen for new expenses. GLAccount is the general ledger account the
+// category is booked to, and ReceiptRequired whether its expenses need a
+// receipt.
+type Category struct {
+	Code             ReimbursementCategory `json:"code" db:"code"`
+	DisplayName      string                `json:"display_name" db:"display_name"`
+	LocalDisplayName *string               `json:"local_display_name,omitempty" db:"local_display_name"`
+	GLAccount        *string               `json:"gl_account,omitempty" db:"gl_account"`
+	ReceiptRequired  bool                  `json:"receipt_required" db:"receipt_required"`
+	Active           bool                  `json:"active" db:"active"`
+	CreatedAt        time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// CreateCategoryRequest adds a category. Code is its permanent identifier,
+// in lower case with underscores, e.g. "medical". ReceiptRequired defaults
+// to true.
+type CreateCategoryRequest struct {
+	Code             string  `json:"code" binding:"required,max=50"`
+	DisplayName      string  `json:"display_name" binding:"required,max=100"`
+	LocalDisplayName *string `json:"local_display_name" binding:"omitempty,max=100"`
+	GLAccount        *string `json:"gl_account" binding:"omitempty,max=30"`
+	ReceiptRequired  *bool   `json:"receipt_required"`
+}
+
+// UpdateCategoryRequest edits the fields given of a category. Setting Active
+// reactivates or deactivates it.
+type UpdateCategoryRequest struct {
+	DisplayName      *string `json:"display_name" binding:"omitempty,min=1,max=100"`
+	LocalDisplayName *string `json:"local_display_name" binding:"omitempty,max=100"`
+	GLAccount        *string `json:"gl_account" binding:"omitempty,max=30"`
+	ReceiptRequired  *bool   `json:"receipt_required"`
+	Active           *bool   `json:"active"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Comment is a message in the discussion thread of a reimbursement.
+// Attachments are URLs returned by the attachment upload endpoint; no other
+// link is accepted.
+type Comment struct {
+	ID              int       `json:"id" db:"id"`
+	ReimbursementID int       `json:"reimbursement_id" db:"reimbursement_id"`
+	AuthorID        int       `json:"author_id" db:"author_id"`
+	AuthorName      string    `json:"author_name" db:"author_name"`
+	AuthorRole      UserRole  `json:"author_role" db:"author_role"`
+	Body            string    `json:"body" db:"body"`
+	Attachments     []string  `json:"attachments" db:"attachments"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateCommentRequest struct {
+	Body        string   `json:"body" binding:"required,max=5000"`
+	Attachments []string `json:"attachments" binding:"omitempty,max=5,dive,required,max=500"`
+}
+
+// CommentUnreadCount is the number of comments on a reimbursement posted by
+// others since the user last read its thread.
+type CommentUnreadCount struct {
+	ReimbursementID int `json:"reimbursement_id"`
+	Unread          int `json:"unread"`
+}
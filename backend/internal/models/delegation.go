@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Delegation lets a delegate act on the delegator's approvals between
+// StartDate and EndDate (inclusive). It stops applying on its own once
+// EndDate has passed, or earlier if it is revoked.
+type Delegation struct {
+	ID          int        `json:"id" db:"id"`
+	DelegatorID int        `json:"delegator_id" db:"delegator_id"`
+	DelegateID  int        `json:"delegate_id" db:"delegate_id"`
+	StartDate   time.Time  `json:"start_date" db:"start_date"`
+	EndDate     time.Time  `json:"end_date" db:"end_date"`
+	Reason      *string    `json:"reason,omitempty" db:"reason"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateDelegationRequest struct {
+	DelegateID int     `json:"delegate_id" binding:"required"`
+	StartDate  string  `json:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate    string  `json:"end_date" binding:"required,datetime=2006-01-02"`
+	Reason     *string `json:"reason"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Separation-of-duties rules. Each rule can be switched off by finance, but
+// is on by default.
+const (
+	// RuleNoSelfApproval stops anyone from deciding a step of a claim they
+	// submitted, directly or as a delegate.
+	RuleNoSelfApproval = "no_self_approval"
+	// RuleDistinctApprovers stops one person from approving more than one
+	// step of the same claim, e.g. both the manager and the finance step.
+	RuleDistinctApprovers = "distinct_approvers"
+)
+
+// DutyRule is a separation-of-duties rule checked when approvers decide a
+// step.
+type DutyRule struct {
+	Code        string    `json:"code" db:"code"`
+	Description string    `json:"description" db:"description"`
+	Enabled     bool      `json:"enabled" db:"enabled"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DutyViolation is a decision already stored that breaks a rule, found by
+// checking the existing data.
+type DutyViolation struct {
+	Rule            string     `json:"rule"`
+	ReimbursementID int        `json:"reimbursement_id"`
+	UserID          int        `json:"user_id"`
+	Details         string     `json:"details"`
+	DecidedAt       *time.Time `json:"decided_at,omitempty"`
+}
+
+type UpdateDutyRuleRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// Reimbursement event actions.
+const (
+	EventCreated         = "created"
+	EventSubmitted       = "submitted"
+	EventUpdated         = "updated"
+	EventApproved        = "approved"
+	EventRejected        = "rejected"
+	EventRevisionAsked   = "revision_requested"
+	EventResubmitted     = "resubmitted"
+	EventCancelled       = "cancelled"
+	EventPurged          = "purged"
+	EventPaid            = "paid"
+	EventPaymentReversed = "payment_reversed"
+	EventSettled         = "settled"
+	EventEscalated       = "escalated"
+	EventOverdue         = "overdue"
+)
+
+// Actor is who makes a change, and from where. The zero Actor is the system
+// itself, for example the SLA worker.
+type Actor struct {
+	UserID    int
+	Role      UserRole
+	IPAddress string
+	UserAgent string
+}
+
+// ReimbursementEvent is one entry of the append-only history of a
+// reimbursement. Events are written in the same transaction as the change
+// they describe.
+type ReimbursementEvent struct {
+	ID              int                  `json:"id" db:"id"`
+	ReimbursementID int                  `json:"reimbursement_id" db:"reimbursement_id"`
+	Action          string               `json:"action" db:"action"`
+	ActorID         *int                 `json:"actor_id,omitempty" db:"actor_id"`
+	ActorRole       *UserRole            `json:"actor_role,omitempty" db:"actor_role"`
+	FromStatus      *ReimbursementStatus `json:"from_status,omitempty" db:"from_status"`
+	ToStatus        *ReimbursementStatus `json:"to_status,omitempty" db:"to_status"`
+	Notes           *string              `json:"notes,omitempty" db:"notes"`
+	IPAddress       *string              `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent       *string              `json:"user_agent,omitempty" db:"user_agent"`
+	CreatedAt       time.Time            `json:"created_at" db:"created_at"`
+}
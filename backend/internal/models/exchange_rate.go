@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// ExchangeRate is the value of one unit of Currency in the base currency,
+// valid from RateDate until the next rate of the same currency.
+type ExchangeRate struct {
+	ID        int       `json:"id" db:"id"`
+	Currency  string    `json:"currency" db:"currency"`
+	RateDate  time.Time `json:"rate_date" db:"rate_date"`
+	Rate      float64   `json:"rate" db:"rate"`
+	CreatedBy *int      `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SaveExchangeRateRequest creates the rate of a currency on a date, or
+// replaces it if one already exists.
+type SaveExchangeRateRequest struct {
+	Currency string  `json:"currency" binding:"required,len=3,alpha,uppercase"`
+	RateDate string  `json:"rate_date" binding:"required,datetime=2006-01-02"`
+	Rate     float64 `json:"rate" binding:"required,gt=0"`
+}
+
+type ImportExchangeRatesResponse struct {
+	Imported int `json:"imported"`
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"reflect"
+	"time"
+)
+
+type LineStatus string
+
+const (
+	LinePending  LineStatus = "pending"
+	LineAccepted LineStatus = "accepted"
+	LineRejected LineStatus = "rejected"
+)
+
+// ReimbursementLine is one expense of a reimbursement. A reimbursement is an
+// expense report of one or more lines, approved as a unit; approvers may
+// reject single lines, which are then not paid. Claims submitted without
+// lines have a single line made from their own fields.
+//
+// OriginalAmount is what was spent, in Currency. Amount is that amount
+// converted to the base currency with ExchangeRate, and is what is approved
+// and paid.
+type ReimbursementLine struct {
+	ID              int                   `json:"id" db:"id"`
+	ReimbursementID int                   `json:"reimbursement_id" db:"reimbursement_id"`
+	LineNo          int                   `json:"line_no" db:"line_no"`
+	Category        ReimbursementCategory `json:"category" db:"category"`
+	Description     string                `json:"description" db:"description"`
+	Amount          Money                 `json:"amount" db:"amount"`
+	Currency        string                `json:"currency" db:"currency"`
+	OriginalAmount  Money                 `json:"original_amount" db:"original_amount"`
+	ExchangeRate    float64               `json:"exchange_rate" db:"exchange_rate"`
+	ExpenseDate     time.Time             `json:"expense_date" db:"expense_date"`
+	ReceiptURL      string                `json:"receipt_url" db:"receipt_url"`
+	Status          LineStatus            `json:"status" db:"status"`
+	RejectionReason *string               `json:"rejection_reason,omitempty" db:"rejection_reason"`
+	DecidedBy       *int                  `json:"decided_by,omitempty" db:"decided_by"`
+	DecidedAt       *time.Time            `json:"decided_at,omitempty" db:"decided_at"`
+	MileageRate     *Money                `json:"mileage_rate,omitempty" db:"mileage_rate"`
+	PerDiem         *PerDiemBreakdown     `json:"per_diem,omitempty" db:"per_diem_breakdown"`
+	TaxDetails
+	MileageDetails
+	PerDiemDetails
+}
+
+// CreateLineRequest is one expense of a report. Amount is in Currency, the
+// base currency if omitted, and so is its optional tax breakdown. Amount and
+// ReceiptURL are required unless the category is computed: a mileage or
+// per-diem line has a trip instead, from which its amount is computed.
+type CreateLineRequest struct {
+	Category    ReimbursementCategory `json:"category" binding:"required"`
+	Description string                `json:"description" binding:"required"`
+	Amount      Money                 `json:"amount" binding:"omitempty,gt=0"`
+	Currency    string                `json:"currency" binding:"omitempty,len=3,alpha,uppercase"`
+	ExpenseDate string                `json:"expense_date" binding:"required_unless=Category per_diem,omitempty,datetime=2006-01-02"`
+	ReceiptURL  string                `json:"receipt_url"`
+	TaxDetails
+	MileageDetails
+	PerDiemDetails
+}
+
+// LineDecision accepts or rejects one line while approving a report. A
+// rejection needs a Reason.
+type LineDecision struct {
+	ID     int     `json:"id" binding:"required,gt=0"`
+	Action string  `json:"action" binding:"required,oneof=accept reject"`
+	Reason *string `json:"reason"`
+}
+
+// SameLines reports whether two sets of lines describe the same expenses,
+// ignoring their decisions.
+func SameLines(a, b []ReimbursementLine) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Category != b[i].Category || a[i].Description != b[i].Description || a[i].Currency != b[i].Currency ||
+			a[i].OriginalAmount != b[i].OriginalAmount || !a[i].ExpenseDate.Equal(b[i].ExpenseDate) || a[i].ReceiptURL != b[i].ReceiptURL ||
+			!reflect.DeepEqual(a[i].TaxDetails, b[i].TaxDetails) || !reflect.DeepEqual(a[i].MileageDetails, b[i].MileageDetails) ||
+			!reflect.DeepEqual(a[i].PerDiemDetails, b[i].PerDiemDetails) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyLines sets the report-level fields derived from its converted lines:
+// the total amount, the category (the lines' category if they share one,
+// other otherwise), the expense date of the earliest line and the receipt of
+// the first line. If every line is in the
+// same currency the report also shows that currency, the total spent in it
+// and, if all lines used the same rate, that rate.
+func (r *Reimbursement) ApplyLines(lines []ReimbursementLine) {
+	r.Lines = lines
+	r.Amount = SumLines(lines)
+	r.Category = lines[0].Category
+	r.ReceiptURL = lines[0].ReceiptURL
+	expenseDate := lines[0].ExpenseDate
+	currency, rate := lines[0].Currency, lines[0].ExchangeRate
+	var original Money
+	for _, l := range lines {
+		if l.Category != r.Category {
+			r.Category = CategoryOther
+		}
+		if l.ExpenseDate.Before(expenseDate) {
+			expenseDate = l.ExpenseDate
+		}
+		if l.Currency != currency {
+			currency = ""
+		}
+		if l.ExchangeRate != rate {
+			rate = 0
+		}
+		original += l.OriginalAmount
+	}
+
+	r.ExpenseDate = &expenseDate
+	r.Currency, r.OriginalAmount, r.ExchangeRate = nil, nil, nil
+	if currency != "" {
+		r.Currency, r.OriginalAmount = &currency, &original
+		if rate != 0 {
+			r.ExchangeRate = &rate
+		}
+	}
+}
+
+// SumLines returns the total amount of the lines that are not rejected.
+func SumLines(lines []ReimbursementLine) Money {
+	var total Money
+	for _, l := range lines {
+		if l.Status != LineRejected {
+			total += l.Amount
+		}
+	}
+	return total
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+type VehicleType string
+
+const (
+	VehicleCar        VehicleType = "car"
+	VehicleMotorcycle VehicleType = "motorcycle"
+)
+
+// MileageDetails is the trip of a mileage line, driven in the employee's own
+// vehicle. The amount of a mileage line is not entered but computed from
+// DistanceKm at the rate of VehicleType in effect on the expense date.
+type MileageDetails struct {
+	Origin      *string      `json:"origin,omitempty" db:"origin" binding:"omitempty,max=200"`
+	Destination *string      `json:"destination,omitempty" db:"destination" binding:"omitempty,max=200"`
+	DistanceKm  *float64     `json:"distance_km,omitempty" db:"distance_km" binding:"omitempty,gt=0,lte=10000"`
+	VehicleType *VehicleType `json:"vehicle_type,omitempty" db:"vehicle_type" binding:"omitempty,oneof=car motorcycle"`
+}
+
+// IsEmpty reports whether no mileage field is set.
+func (m MileageDetails) IsEmpty() bool {
+	return m.Origin == nil && m.Destination == nil && m.DistanceKm == nil && m.VehicleType == nil
+}
+
+// IsComplete reports whether every mileage field is set.
+func (m MileageDetails) IsComplete() bool {
+	return m.Origin != nil && *m.Origin != "" && m.Destination != nil && *m.Destination != "" &&
+		m.DistanceKm != nil && m.VehicleType != nil
+}
+
+// MileageRate is the amount paid per kilometre driven in a type of vehicle,
+// in the base currency, from EffectiveFrom until the next rate of the same
+// vehicle type. Mileage lines keep the rate they were priced at, so a new
+// rate does not change claims already made.
+type MileageRate struct {
+	ID            int         `json:"id" db:"id"`
+	VehicleType   VehicleType `json:"vehicle_type" db:"vehicle_type"`
+	RatePerKm     Money       `json:"rate_per_km" db:"rate_per_km"`
+	EffectiveFrom time.Time   `json:"effective_from" db:"effective_from"`
+	CreatedBy     *int        `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt     time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// SaveMileageRateRequest creates the rate of a vehicle type effective from a
+// date, or replaces it if one already exists.
+type SaveMileageRateRequest struct {
+	VehicleType   VehicleType `json:"vehicle_type" binding:"required,oneof=car motorcycle"`
+	RatePerKm     Money       `json:"rate_per_km" binding:"required,gt=0"`
+	EffectiveFrom string      `json:"effective_from" binding:"required,datetime=2006-01-02"`
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount in cents (hundredths of the currency unit), the
+// precision of the DECIMAL(12, 2) columns it is stored in. It is encoded in
+// JSON as a decimal string such as "1250000.50" so clients never see a
+// rounded float; a JSON number is still accepted on input and read exactly
+// from its digits.
+type Money int64
+
+// ParseMoney reads a decimal amount with at most two decimal places.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" || len(frac) > 2 || !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("invalid amount %q: expected a decimal with at most 2 decimal places", s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > (1<<63-1)/100-1 {
+		return 0, fmt.Errorf("invalid amount %q: out of range", s)
+	}
+	cents, _ := strconv.ParseInt(frac, 10, 64)
+	m := Money(units*100 + cents)
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats m with two decimal places, e.g. "1250000.50".
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// Convert returns m multiplied by an exchange rate, rounded half away from
+// zero to the cent. The rate is taken at its shortest decimal form, which is
+// the value stored in the database.
+func (m Money) Convert(rate float64) Money {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	return roundCents(new(big.Rat).Mul(new(big.Rat).SetInt64(int64(m)), r))
+}
+
+// Share returns the part of m that part is of whole, rounded half away from
+// zero to the cent. The ratio is kept exact, unlike a rate passed to Convert.
+func (m Money) Share(part, whole Money) Money {
+	product := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(part))), big.NewInt(int64(whole)))
+	return roundCents(product)
+}
+
+// roundCents rounds an amount in cents half away from zero.
+func roundCents(product *big.Rat) Money {
+	num, den := product.Num(), product.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// Round half away from zero: compare twice the remainder to the divisor
+	if new(big.Int).Abs(new(big.Int).Lsh(rem, 1)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return Money(quo.Int64())
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan reads a DECIMAL column, which the driver returns as text.
+func (m *Money) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value stores m as an exact decimal string.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
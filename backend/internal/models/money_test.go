@@ -0,0 +1,226 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "1", want: 100},
+		{in: "1.5", want: 150},
+		{in: "1.05", want: 105},
+		{in: "1250000.50", want: 125000050},
+		{in: " 12.30 ", want: 1230},
+		{in: "0.01", want: 1},
+		{in: "-12.34", want: -1234},
+		{in: "1.234", wantErr: true},
+		{in: "0.001", wantErr: true},
+		{in: "", wantErr: true},
+		{in: ".5", wantErr: true},
+		{in: "1.", want: 100},
+		{in: "1e3", wantErr: true},
+		{in: "1,50", wantErr: true},
+		{in: "+1", wantErr: true},
+		{in: "--1", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "92233720368547758.07", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMoney(%q) = %d, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q) returned %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{150, "1.50"},
+		{125000050, "1250000.50"},
+		{-5, "-0.05"},
+		{-1234, "-12.34"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Money
+		out     string
+		wantErr bool
+	}{
+		{name: "string", in: `"1250000.50"`, want: 125000050, out: `"1250000.50"`},
+		{name: "number", in: `1250000.5`, want: 125000050, out: `"1250000.50"`},
+		{name: "integer", in: `42`, want: 4200, out: `"42.00"`},
+		{name: "exact beyond float precision", in: `"9007199254740993.01"`, want: 900719925474099301, out: `"9007199254740993.01"`},
+		{name: "negative", in: `"-3.20"`, want: -320, out: `"-3.20"`},
+		{name: "too many decimals", in: `"10.005"`, wantErr: true},
+		{name: "too many decimals as number", in: `0.125`, wantErr: true},
+		{name: "exponent", in: `1e2`, wantErr: true},
+		{name: "not a number", in: `"ten"`, wantErr: true},
+		{name: "boolean", in: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Money
+			err := json.Unmarshal([]byte(tt.in), &m)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unmarshalling %s gave %d, want an error", tt.in, m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unmarshalling %s returned %v", tt.in, err)
+			}
+			if m != tt.want {
+				t.Errorf("unmarshalling %s gave %d, want %d", tt.in, m, tt.want)
+			}
+			out, err := json.Marshal(m)
+			if err != nil {
+				t.Fatalf("marshalling %d returned %v", m, err)
+			}
+			if string(out) != tt.out {
+				t.Errorf("marshalling %d gave %s, want %s", m, out, tt.out)
+			}
+		})
+	}
+}
+
+func TestMoneyJSONNull(t *testing.T) {
+	var v struct {
+		Amount *Money `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount": null}`), &v); err != nil {
+		t.Fatalf("unmarshalling null returned %v", err)
+	}
+	if v.Amount != nil {
+		t.Errorf("unmarshalling null gave %d, want nil", *v.Amount)
+	}
+}
+
+// Amounts in requests are validated with gt=0, so a negative or zero amount
+// never reaches a handler.
+func TestMoneyRequestRejectsNonPositive(t *testing.T) {
+	tests := []struct {
+		body    string
+		wantErr bool
+	}{
+		{body: `{"purpose": "Trip", "amount": "100.00"}`},
+		{body: `{"purpose": "Trip", "amount": "0.01"}`},
+		{body: `{"purpose": "Trip", "amount": "0.00"}`, wantErr: true},
+		{body: `{"purpose": "Trip", "amount": "-100.00"}`, wantErr: true},
+		{body: `{"purpose": "Trip", "amount": -1}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			var req CreateAdvanceRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("unmarshalling returned %v", err)
+			}
+			err := binding.Validator.ValidateStruct(&req)
+			if tt.wantErr && err == nil {
+				t.Errorf("amount %s passed validation", req.Amount)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("amount %s failed validation: %v", req.Amount, err)
+			}
+		})
+	}
+}
+
+func TestMoneyConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Money
+		rate float64
+		want Money
+	}{
+		{"identity", 12345, 1, 12345},
+		{"exact", 10000, 15500, 155000000},
+		{"rounds down below half", 100, 0.123, 12},
+		{"rounds half up", 100, 0.125, 13},
+		{"rounds half away from zero when negative", -100, 0.125, -13},
+		{"rounds down when negative below half", -100, 0.123, -12},
+		{"tenth of a cent", 1, 0.5, 1},
+		{"rate with many decimals", 2500, 16234.56789012, 40586420},
+		{"decimal rate is exact", 1000, 0.07, 70},
+		{"zero", 0, 16000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.in.Convert(tt.rate); got != tt.want {
+				t.Errorf("Money(%d).Convert(%v) = %d, want %d", int64(tt.in), tt.rate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    Money
+		wantErr bool
+	}{
+		{name: "bytes", src: []byte("1250000.50"), want: 125000050},
+		{name: "string", src: "12.30", want: 1230},
+		{name: "integer", src: int64(7), want: 700},
+		{name: "float", src: 1.5, wantErr: true},
+		{name: "garbage", src: []byte("x"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Money
+			err := m.Scan(tt.src)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan(%v) gave %d, want an error", tt.src, m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan(%v) returned %v", tt.src, err)
+			}
+			if m != tt.want {
+				t.Errorf("Scan(%v) = %d, want %d", tt.src, m, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+type NotificationKind string
+
+const (
+	NotificationReminder   NotificationKind = "reminder"
+	NotificationEscalation NotificationKind = "escalation"
+	NotificationOverdue    NotificationKind = "overdue"
+)
+
+// Notification is a message for an approver about a reimbursement. It goes
+// either to one user or, when RecipientID is nil, to everyone with
+// RecipientRole (for steps open to a whole role, such as finance).
+//
+// StepOrder and StepEnteredAt identify the wait it is about, so the same
+// notification is never stored twice for one wait on one step.
+type Notification struct {
+	ID              int              `json:"id" db:"id"`
+	ReimbursementID int              `json:"reimbursement_id" db:"reimbursement_id"`
+	RecipientID     *int             `json:"recipient_id,omitempty" db:"recipient_id"`
+	RecipientRole   *UserRole        `json:"recipient_role,omitempty" db:"recipient_role"`
+	Kind            NotificationKind `json:"kind" db:"kind"`
+	Message         string           `json:"message" db:"message"`
+	StepOrder       int              `json:"step_order" db:"step_order"`
+	StepEnteredAt   time.Time        `json:"step_entered_at" db:"step_entered_at"`
+	CreatedAt       time.Time        `json:"created_at" db:"created_at"`
+}
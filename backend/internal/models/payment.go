@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+type PaymentMethod string
+
+const (
+	PaymentBankTransfer PaymentMethod = "bank_transfer"
+	PaymentCash         PaymentMethod = "cash"
+	PaymentPayroll      PaymentMethod = "payroll"
+)
+
+type Payment struct {
+	ID              int           `json:"id" db:"id"`
+	ReimbursementID int           `json:"reimbursement_id" db:"reimbursement_id"`
+	Amount          Money         `json:"amount" db:"amount"`
+	Method          PaymentMethod `json:"method" db:"method"`
+	Reference       string        `json:"reference" db:"reference"`
+	PaymentDate     time.Time     `json:"payment_date" db:"payment_date"`
+	PaidBy          *int          `json:"paid_by,omitempty" db:"paid_by"`
+	ReversedAt      *time.Time    `json:"reversed_at,omitempty" db:"reversed_at"`
+	ReversedBy      *int          `json:"reversed_by,omitempty" db:"reversed_by"`
+	ReversalReason  *string       `json:"reversal_reason,omitempty" db:"reversal_reason"`
+	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
+}
+
+type MarkPaidRequest struct {
+	PaymentDate string        `json:"payment_date" binding:"required,datetime=2006-01-02"`
+	Method      PaymentMethod `json:"method" binding:"required,oneof=bank_transfer cash payroll"`
+	Reference   string        `json:"reference" binding:"required"`
+}
+
+type ReversePaymentRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
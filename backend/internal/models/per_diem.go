@@ -0,0 +1,169 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MaxPerDiemDays is the longest trip a per-diem line may cover.
+const MaxPerDiemDays = 90
+
+// PerDiemDetails is the business trip of a per-diem line: from TripStart to
+// TripEnd (YYYY-MM-DD, both included) in Location, a city or country with a
+// per-diem rate. ProvidedMeals lists the days on which meals were provided
+// and so are not paid. The amount of a per-diem line is not entered but
+// computed from these.
+type PerDiemDetails struct {
+	TripStart     *string  `json:"trip_start,omitempty" db:"trip_start" binding:"omitempty,datetime=2006-01-02"`
+	TripEnd       *string  `json:"trip_end,omitempty" db:"trip_end" binding:"omitempty,datetime=2006-01-02"`
+	Location      *string  `json:"location,omitempty" db:"location" binding:"omitempty,max=100"`
+	ProvidedMeals MealPlan `json:"provided_meals,omitempty" db:"provided_meals" binding:"omitempty,max=90,dive"`
+}
+
+// IsEmpty reports whether no per-diem field is set.
+func (p PerDiemDetails) IsEmpty() bool {
+	return p.TripStart == nil && p.TripEnd == nil && p.Location == nil && len(p.ProvidedMeals) == 0
+}
+
+// ProvidedMeals flags the meals provided on one day of a trip.
+type ProvidedMeals struct {
+	Date      string `json:"date" binding:"required,datetime=2006-01-02"`
+	Breakfast bool   `json:"breakfast"`
+	Lunch     bool   `json:"lunch"`
+	Dinner    bool   `json:"dinner"`
+}
+
+// MealPlan is the provided meals of a trip, stored as JSONB.
+type MealPlan []ProvidedMeals
+
+func (m *MealPlan) Scan(src interface{}) error {
+	return scanJSON(src, m)
+}
+
+func (m MealPlan) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
+
+// PerDiemRate is the daily allowance for trips to Location, in the base
+// currency, from EffectiveFrom until the next rate of the same location. A
+// provided meal reduces a day's allowance by its percentage of the rate.
+type PerDiemRate struct {
+	ID               int       `json:"id" db:"id"`
+	Location         string    `json:"location" db:"location"`
+	DailyRate        Money     `json:"daily_rate" db:"daily_rate"`
+	BreakfastPercent float64   `json:"breakfast_percent" db:"breakfast_percent"`
+	LunchPercent     float64   `json:"lunch_percent" db:"lunch_percent"`
+	DinnerPercent    float64   `json:"dinner_percent" db:"dinner_percent"`
+	EffectiveFrom    time.Time `json:"effective_from" db:"effective_from"`
+	CreatedBy        *int      `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SavePerDiemRateRequest creates the rate of a location effective from a
+// date, or replaces it if one already exists.
+type SavePerDiemRateRequest struct {
+	Location         string  `json:"location" binding:"required,max=100"`
+	DailyRate        Money   `json:"daily_rate" binding:"required,gt=0"`
+	BreakfastPercent float64 `json:"breakfast_percent" binding:"gte=0,lte=100"`
+	LunchPercent     float64 `json:"lunch_percent" binding:"gte=0,lte=100"`
+	DinnerPercent    float64 `json:"dinner_percent" binding:"gte=0,lte=100"`
+	EffectiveFrom    string  `json:"effective_from" binding:"required,datetime=2006-01-02"`
+}
+
+// PerDiemBreakdown is how the amount of a per-diem line was computed, kept
+// with the line so finance can audit it.
+type PerDiemBreakdown struct {
+	RateID           int          `json:"rate_id"`
+	Location         string       `json:"location"`
+	DailyRate        Money        `json:"daily_rate"`
+	BreakfastPercent float64      `json:"breakfast_percent"`
+	LunchPercent     float64      `json:"lunch_percent"`
+	DinnerPercent    float64      `json:"dinner_percent"`
+	Days             []PerDiemDay `json:"days"`
+	Total            Money        `json:"total"`
+}
+
+// PerDiemDay is the allowance of one day of a trip.
+type PerDiemDay struct {
+	Date      string `json:"date"`
+	Breakfast bool   `json:"breakfast"`
+	Lunch     bool   `json:"lunch"`
+	Dinner    bool   `json:"dinner"`
+	Reduction Money  `json:"reduction"`
+	Amount    Money  `json:"amount"`
+}
+
+func (b *PerDiemBreakdown) Scan(src interface{}) error {
+	return scanJSON(src, b)
+}
+
+func (b PerDiemBreakdown) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
+
+// NewPerDiemBreakdown computes the allowance of each day from start to end at
+// rate, less the provided meals.
+func NewPerDiemBreakdown(rate *PerDiemRate, start, end time.Time, meals MealPlan) *PerDiemBreakdown {
+	provided := make(map[string]ProvidedMeals, len(meals))
+	for _, m := range meals {
+		provided[m.Date] = m
+	}
+
+	b := &PerDiemBreakdown{
+		RateID:           rate.ID,
+		Location:         rate.Location,
+		DailyRate:        rate.DailyRate,
+		BreakfastPercent: rate.BreakfastPercent,
+		LunchPercent:     rate.LunchPercent,
+		DinnerPercent:    rate.DinnerPercent,
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		m := provided[date]
+		var percent float64
+		if m.Breakfast {
+			percent += rate.BreakfastPercent
+		}
+		if m.Lunch {
+			percent += rate.LunchPercent
+		}
+		if m.Dinner {
+			percent += rate.DinnerPercent
+		}
+		reduction := rate.DailyRate.Convert(percent / 100)
+		if reduction > rate.DailyRate {
+			reduction = rate.DailyRate
+		}
+		amount := rate.DailyRate - reduction
+		b.Days = append(b.Days, PerDiemDay{
+			Date:      date,
+			Breakfast: m.Breakfast,
+			Lunch:     m.Lunch,
+			Dinner:    m.Dinner,
+			Reduction: reduction,
+			Amount:    amount,
+		})
+		b.Total += amount
+	}
+	return b
+}
+
+// scanJSON reads a JSONB column into dest, leaving it unchanged if NULL.
+func scanJSON(src interface{}, dest interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", src, dest)
+	}
+}
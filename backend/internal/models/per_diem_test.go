@@ -0,0 +1,124 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewPerDiemBreakdown(t *testing.T) {
+	rate := &PerDiemRate{
+		ID:               7,
+		Location:         "Jakarta",
+		DailyRate:        50000000,
+		BreakfastPercent: 15,
+		LunchPercent:     30,
+		DinnerPercent:    30,
+	}
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name    string
+		rate    *PerDiemRate
+		start   string
+		end     string
+		meals   MealPlan
+		amounts []Money
+		total   Money
+	}{
+		{
+			name:    "single day",
+			rate:    rate,
+			start:   "2024-03-04",
+			end:     "2024-03-04",
+			amounts: []Money{50000000},
+			total:   50000000,
+		},
+		{
+			name:    "three days, no meals",
+			rate:    rate,
+			start:   "2024-03-04",
+			end:     "2024-03-06",
+			amounts: []Money{50000000, 50000000, 50000000},
+			total:   150000000,
+		},
+		{
+			name:  "provided meals reduce their day",
+			rate:  rate,
+			start: "2024-03-04",
+			end:   "2024-03-06",
+			meals: MealPlan{
+				{Date: "2024-03-04", Dinner: true},
+				{Date: "2024-03-05", Breakfast: true, Lunch: true},
+			},
+			amounts: []Money{35000000, 27500000, 50000000},
+			total:   112500000,
+		},
+		{
+			name:    "meals outside the trip are ignored",
+			rate:    rate,
+			start:   "2024-03-04",
+			end:     "2024-03-04",
+			meals:   MealPlan{{Date: "2024-03-10", Breakfast: true, Lunch: true, Dinner: true}},
+			amounts: []Money{50000000},
+			total:   50000000,
+		},
+		{
+			name:    "crosses a month end",
+			rate:    rate,
+			start:   "2024-02-28",
+			end:     "2024-03-01",
+			meals:   MealPlan{{Date: "2024-02-29", Lunch: true}},
+			amounts: []Money{50000000, 35000000, 50000000},
+			total:   135000000,
+		},
+		{
+			name:    "reduction rounds to the cent",
+			rate:    &PerDiemRate{Location: "Bandung", DailyRate: 33333, BreakfastPercent: 15},
+			start:   "2024-03-04",
+			end:     "2024-03-04",
+			meals:   MealPlan{{Date: "2024-03-04", Breakfast: true}},
+			amounts: []Money{28333},
+			total:   28333,
+		},
+		{
+			name:    "reduction never exceeds the rate",
+			rate:    &PerDiemRate{Location: "Surabaya", DailyRate: 40000000, BreakfastPercent: 40, LunchPercent: 40, DinnerPercent: 40},
+			start:   "2024-03-04",
+			end:     "2024-03-05",
+			meals:   MealPlan{{Date: "2024-03-04", Breakfast: true, Lunch: true, Dinner: true}},
+			amounts: []Money{0, 40000000},
+			total:   40000000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewPerDiemBreakdown(tt.rate, date(tt.start), date(tt.end), tt.meals)
+			if b.RateID != tt.rate.ID || b.Location != tt.rate.Location || b.DailyRate != tt.rate.DailyRate {
+				t.Errorf("breakdown records rate %d %s %s, want %d %s %s",
+					b.RateID, b.Location, b.DailyRate, tt.rate.ID, tt.rate.Location, tt.rate.DailyRate)
+			}
+			if len(b.Days) != len(tt.amounts) {
+				t.Fatalf("breakdown has %d days, want %d", len(b.Days), len(tt.amounts))
+			}
+			for i, day := range b.Days {
+				if day.Amount != tt.amounts[i] {
+					t.Errorf("day %s amount = %s, want %s", day.Date, day.Amount, tt.amounts[i])
+				}
+				if day.Reduction+day.Amount != tt.rate.DailyRate {
+					t.Errorf("day %s reduction %s plus amount %s is not the daily rate %s",
+						day.Date, day.Reduction, day.Amount, tt.rate.DailyRate)
+				}
+			}
+			if b.Total != tt.total {
+				t.Errorf("total = %s, want %s", b.Total, tt.total)
+			}
+		})
+	}
+}
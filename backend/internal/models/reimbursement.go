@@ -7,73 +7,200 @@ import (
 type ReimbursementStatus string
 
 const (
-	StatusPending          ReimbursementStatus = "pending"
-	StatusApprovedManager  ReimbursementStatus = "approved_manager"
-	StatusRejectedManager  ReimbursementStatus = "rejected_manager"
-	StatusApprovedFinance  ReimbursementStatus = "approved_finance"
-	StatusRejectedFinance  ReimbursementStatus = "rejected_finance"
-	StatusCompleted        ReimbursementStatus = "completed"
+	StatusDraft           ReimbursementStatus = "draft"
+	StatusPending         ReimbursementStatus = "pending"
+	StatusApprovedManager ReimbursementStatus = "approved_manager"
+	StatusRejectedManager ReimbursementStatus = "rejected_manager"
+	StatusApprovedFinance ReimbursementStatus = "approved_finance"
+	StatusRejectedFinance ReimbursementStatus = "rejected_finance"
+	StatusCompleted       ReimbursementStatus = "completed"
+	StatusNeedsRevision   ReimbursementStatus = "needs_revision"
+	StatusCancelled       ReimbursementStatus = "cancelled"
 )
 
+// ReimbursementCategory is the code of a Category. Categories are managed in
+// the categories table; these are the ones the code treats specially.
 type ReimbursementCategory string
 
 const (
-	CategoryTransport     ReimbursementCategory = "transport"
-	CategoryAccommodation ReimbursementCategory = "accommodation"
-	CategoryMeals         ReimbursementCategory = "meals"
-	CategoryOfficeSupply  ReimbursementCategory = "office_supply"
-	CategoryOther         ReimbursementCategory = "other"
+	CategoryMileage ReimbursementCategory = "mileage"
+	CategoryPerDiem ReimbursementCategory = "per_diem"
+	CategoryOther   ReimbursementCategory = "other"
 )
 
+// IsComputed reports whether the amount of an expense of category c is
+// computed by the server rather than entered, and needs no receipt.
+func (c ReimbursementCategory) IsComputed() bool {
+	return c == CategoryMileage || c == CategoryPerDiem
+}
+
 type Reimbursement struct {
-	ID              int                   `json:"id" db:"id"`
-	EmployeeID      int                   `json:"employee_id" db:"employee_id"`
-	EmployeeName    string                `json:"employee_name" db:"employee_name"`
-	Name            string                `json:"name" db:"name"`
-	Title           string                `json:"title" db:"title"`
-	Description     string                `json:"description" db:"description"`
-	Category        ReimbursementCategory `json:"category" db:"category"`
-	Amount          float64               `json:"amount" db:"amount"`
-	ReceiptURL      string                `json:"receipt_url" db:"receipt_url"`
-	Status          ReimbursementStatus   `json:"status" db:"status"`
-	SubmittedDate   time.Time             `json:"submitted_date" db:"submitted_date"`
-	ManagerID       *int                  `json:"manager_id,omitempty" db:"manager_id"`
-	ManagerNotes    *string               `json:"manager_notes,omitempty" db:"manager_notes"`
-	ManagerApproved *time.Time            `json:"manager_approved,omitempty" db:"manager_approved"`
-	FinanceID       *int                  `json:"finance_id,omitempty" db:"finance_id"`
-	FinanceNotes    *string               `json:"finance_notes,omitempty" db:"finance_notes"`
-	FinanceApproved *time.Time            `json:"finance_approved,omitempty" db:"finance_approved"`
-	CreatedAt       time.Time             `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time             `json:"updated_at" db:"updated_at"`
+	ID                     int                   `json:"id" db:"id"`
+	EmployeeID             int                   `json:"employee_id" db:"employee_id"`
+	EmployeeName           string                `json:"employee_name" db:"employee_name"`
+	Name                   string                `json:"name" db:"name"`
+	Title                  string                `json:"title" db:"title"`
+	Description            string                `json:"description" db:"description"`
+	Category               ReimbursementCategory `json:"category" db:"category"`
+	Amount                 Money                 `json:"amount" db:"amount"`
+	Currency               *string               `json:"currency,omitempty" db:"currency"`
+	OriginalAmount         *Money                `json:"original_amount,omitempty" db:"original_amount"`
+	ExchangeRate           *float64              `json:"exchange_rate,omitempty" db:"exchange_rate"`
+	ApprovedAmount         *Money                `json:"approved_amount,omitempty" db:"approved_amount"`
+	AmountAdjustmentReason *string               `json:"amount_adjustment_reason,omitempty" db:"amount_adjustment_reason"`
+	ReceiptURL             string                `json:"receipt_url" db:"receipt_url"`
+	Status                 ReimbursementStatus   `json:"status" db:"status"`
+	ExpenseDate            *time.Time            `json:"expense_date,omitempty" db:"expense_date"`
+	SubmittedDate          time.Time             `json:"submitted_date" db:"submitted_date"`
+	Late                   bool                  `json:"late" db:"late"`
+	LateReason             *string               `json:"late_reason,omitempty" db:"late_reason"`
+	AdvanceID              *int                  `json:"advance_id,omitempty" db:"advance_id"`
+	ApprovalChainID        *int                  `json:"approval_chain_id,omitempty" db:"approval_chain_id"`
+	CurrentStep            int                   `json:"current_step" db:"current_step"`
+	CurrentApproverID      *int                  `json:"current_approver_id,omitempty" db:"current_approver_id"`
+	RevisionCount          int                   `json:"revision_count" db:"revision_count"`
+	Version                int                   `json:"version" db:"version"`
+	StepEnteredAt          time.Time             `json:"step_entered_at" db:"step_entered_at"`
+	Overdue                bool                  `json:"overdue" db:"overdue"`
+	CancelReason           *string               `json:"cancel_reason,omitempty" db:"cancel_reason"`
+	CancelledAt            *time.Time            `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	ManagerID              *int                  `json:"manager_id,omitempty" db:"manager_id"`
+	ManagerOnBehalfOfID    *int                  `json:"manager_on_behalf_of_id,omitempty" db:"manager_on_behalf_of_id"`
+	ManagerNotes           *string               `json:"manager_notes,omitempty" db:"manager_notes"`
+	ManagerApproved        *time.Time            `json:"manager_approved,omitempty" db:"manager_approved"`
+	FinanceID              *int                  `json:"finance_id,omitempty" db:"finance_id"`
+	FinanceOnBehalfOfID    *int                  `json:"finance_on_behalf_of_id,omitempty" db:"finance_on_behalf_of_id"`
+	FinanceNotes           *string               `json:"finance_notes,omitempty" db:"finance_notes"`
+	FinanceApproved        *time.Time            `json:"finance_approved,omitempty" db:"finance_approved"`
+	CreatedAt              time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt              time.Time             `json:"updated_at" db:"updated_at"`
+	Approvals              []Approval            `json:"approvals,omitempty"`
+	Lines                  []ReimbursementLine   `json:"lines,omitempty"`
+	Allocations            []Allocation          `json:"allocations,omitempty"`
+
+	// Draft is the claim as saved while it is a draft, see StatusDraft.
+	Draft *CreateReimbursementRequest `json:"draft,omitempty" db:"draft"`
+}
+
+// PayableAmount is the amount finance pays out: the approved amount if an
+// approver lowered it, the claimed amount otherwise.
+func (r *Reimbursement) PayableAmount() Money {
+	if r.ApprovedAmount != nil {
+		return *r.ApprovedAmount
+	}
+	return r.Amount
 }
 
+// CreateReimbursementRequest submits an expense report. With Lines, the
+// category, amount and receipt are derived from the lines; without, the
+// claim becomes a report of one line made from them, with Amount and the
+// tax breakdown in Currency and dated ExpenseDate. A mileage or per-diem
+// claim has a trip instead of an amount and receipt, and a per-diem claim is
+// dated by its trip. Allocations, if any, charge the claim to cost
+// centers and projects and must add up to its amount or to 100%. AdvanceID
+// files the claim against an open cash advance of the submitter, which it is
+// then settled against instead of being paid out.
 type CreateReimbursementRequest struct {
 	Name        string                `json:"name" binding:"required"`
 	Title       string                `json:"title" binding:"required"`
 	Description string                `json:"description" binding:"required"`
-	Category    ReimbursementCategory `json:"category" binding:"required"`
-	Amount      float64               `json:"amount" binding:"required,gt=0"`
-	ReceiptURL  string                `json:"receipt_url" binding:"required"`
+	Category    ReimbursementCategory `json:"category" binding:"required_without=Lines"`
+	Amount      Money                 `json:"amount" binding:"omitempty,gt=0"`
+	Currency    string                `json:"currency" binding:"omitempty,len=3,alpha,uppercase"`
+	ExpenseDate string                `json:"expense_date" binding:"omitempty,datetime=2006-01-02"`
+	ReceiptURL  string                `json:"receipt_url"`
+	Lines       []CreateLineRequest   `json:"lines" binding:"omitempty,min=1,max=100,dive"`
+	Allocations []AllocationRequest   `json:"allocations" binding:"omitempty,max=20,dive"`
+	AdvanceID   *int                  `json:"advance_id" binding:"omitempty,gt=0"`
+	TaxDetails
+	MileageDetails
+	PerDiemDetails
 }
 
+// UpdateReimbursementRequest edits a reimbursement. Lines, when given,
+// replace all lines of the report. ExpenseDate and the tax, mileage and
+// per-diem fields given edit the line of a one-line report. Allocations,
+// when given, replace the claim's allocations; an empty list removes them.
 type UpdateReimbursementRequest struct {
 	Name        string                `json:"name"`
 	Title       string                `json:"title"`
 	Description string                `json:"description"`
 	Category    ReimbursementCategory `json:"category"`
-	Amount      float64               `json:"amount" binding:"omitempty,gt=0"`
+	Amount      Money                 `json:"amount" binding:"omitempty,gt=0"`
+	Currency    string                `json:"currency" binding:"omitempty,len=3,alpha,uppercase"`
+	ExpenseDate string                `json:"expense_date" binding:"omitempty,datetime=2006-01-02"`
 	ReceiptURL  string                `json:"receipt_url"`
+	Lines       []CreateLineRequest   `json:"lines" binding:"omitempty,min=1,max=100,dive"`
+	Allocations []AllocationRequest   `json:"allocations" binding:"omitempty,max=20,dive"`
+	TaxDetails
+	MileageDetails
+	PerDiemDetails
+}
+
+// ExpensePeriod limits a list of reimbursements to those whose expense date
+// is on or after From and before To, when set.
+type ExpensePeriod struct {
+	From *time.Time
+	To   *time.Time
 }
 
+type CancelReimbursementRequest struct {
+	Reason *string `json:"reason"`
+}
+
+// ApprovalRequest decides the current step of a reimbursement. An approval
+// may lower the amount with ApprovedAmount, which then needs a
+// Justification. An approval of a multi-line report may instead accept or
+// reject single lines with Lines. Version is the version the approver
+// reviewed and is required; the decision is refused if the claim has been
+// edited since.
 type ApprovalRequest struct {
-	Action string  `json:"action" binding:"required,oneof=approve reject"`
-	Notes  *string `json:"notes"`
+	Action         string         `json:"action" binding:"required,oneof=approve reject revise"`
+	Notes          *string        `json:"notes"`
+	ApprovedAmount *Money         `json:"approved_amount" binding:"omitempty,gt=0"`
+	Justification  *string        `json:"justification"`
+	Lines          []LineDecision `json:"lines" binding:"omitempty,max=100,dive"`
+	Version        int            `json:"version" binding:"required,gt=0"`
+}
+
+// BulkApprovalRequest applies one action to several reimbursements. Notes
+// apply to every claim unless ItemNotes has notes for its ID. Versions maps
+// each ID to the version reviewed, as in ApprovalRequest, and must cover
+// every ID.
+type BulkApprovalRequest struct {
+	IDs       []int          `json:"ids" binding:"required,min=1,max=100,dive,gt=0"`
+	Action    string         `json:"action" binding:"required,oneof=approve reject revise"`
+	Notes     *string        `json:"notes"`
+	ItemNotes map[int]string `json:"item_notes"`
+	Versions  map[int]int    `json:"versions" binding:"required"`
+}
+
+// BulkApprovalResult is the outcome for one reimbursement of a bulk
+// decision. Status is the HTTP status the single approve endpoint would have
+// returned.
+type BulkApprovalResult struct {
+	ID            int            `json:"id"`
+	Success       bool           `json:"success"`
+	Status        int            `json:"status"`
+	Error         string         `json:"error,omitempty"`
+	Reimbursement *Reimbursement `json:"reimbursement,omitempty"`
+}
+
+type BulkApprovalResponse struct {
+	Results   []BulkApprovalResult `json:"results"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
 }
 
 type ReimbursementStats struct {
-	TotalSubmitted int     `json:"total_submitted"`
-	TotalApproved  int     `json:"total_approved"`
-	TotalRejected  int     `json:"total_rejected"`
-	TotalPending   int     `json:"total_pending"`
-	TotalAmount    float64 `json:"total_amount"`
+	TotalSubmitted       int    `json:"total_submitted"`
+	TotalApproved        int    `json:"total_approved"`
+	TotalRejected        int    `json:"total_rejected"`
+	TotalPending         int    `json:"total_pending"`
+	TotalNeedsRevision   int    `json:"total_needs_revision"`
+	TotalAwaitingPayment int    `json:"total_awaiting_payment"`
+	TotalPaid            int    `json:"total_paid"`
+	TotalAmount          Money  `json:"total_amount"`
+	TotalClaimedAmount   Money  `json:"total_claimed_amount"`
+	Currency             string `json:"currency"`
 }
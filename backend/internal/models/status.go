@@ -0,0 +1,101 @@
+package models
+
+// statusTransitions is the reimbursement lifecycle: for every current status
+// it lists the statuses a claim may move to and the roles allowed to make
+// that move. Any change not listed here is rejected.
+//
+// While a claim is being approved, the approval chain decides who acts on the
+// current step; the roles below only bound what each role can ever do.
+// approved_manager means at least one step has approved and more remain.
+var statusTransitions = map[ReimbursementStatus]map[ReimbursementStatus][]UserRole{
+	StatusDraft: {
+		// A draft is still being prepared by its submitter and is not seen
+		// by approvers until it is submitted.
+		StatusPending: {RoleEmployee},
+	},
+	StatusPending: {
+		StatusApprovedManager: {RoleManager, RoleFinance},
+		StatusApprovedFinance: {RoleManager, RoleFinance},
+		StatusRejectedManager: {RoleManager},
+		StatusRejectedFinance: {RoleFinance},
+		StatusNeedsRevision:   {RoleManager, RoleFinance},
+		StatusCancelled:       {RoleEmployee},
+	},
+	StatusApprovedManager: {
+		StatusApprovedManager: {RoleManager, RoleFinance},
+		StatusApprovedFinance: {RoleManager, RoleFinance},
+		StatusRejectedManager: {RoleManager},
+		StatusRejectedFinance: {RoleFinance},
+		StatusNeedsRevision:   {RoleManager, RoleFinance},
+	},
+	StatusNeedsRevision: {
+		// Resubmission goes back to the step that returned the claim.
+		StatusPending:         {RoleEmployee},
+		StatusApprovedManager: {RoleEmployee},
+		StatusCancelled:       {RoleEmployee},
+	},
+	StatusApprovedFinance: {
+		StatusCompleted: {RoleFinance},
+	},
+	StatusCompleted: {
+		// Payment reversal, e.g. a bounced transfer.
+		StatusApprovedFinance: {RoleFinance},
+	},
+}
+
+// CanTransition reports whether a user with the given role may move a
+// reimbursement from one status to another.
+func CanTransition(from, to ReimbursementStatus, role UserRole) bool {
+	for _, allowed := range statusTransitions[from][to] {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEditable reports whether the submitter may still change or remove a
+// reimbursement in this status.
+func (s ReimbursementStatus) IsEditable() bool {
+	return s == StatusPending || s == StatusNeedsRevision
+}
+
+// IsAwaitingApproval reports whether a reimbursement in this status is
+// somewhere in its approval chain.
+func (s ReimbursementStatus) IsAwaitingApproval() bool {
+	return s == StatusPending || s == StatusApprovedManager
+}
+
+// IsUndecided reports whether a reimbursement in this status is still waiting
+// for an approval decision, either from an approver or, after being returned
+// for revision, from its submitter.
+func (s ReimbursementStatus) IsUndecided() bool {
+	return s.IsAwaitingApproval() || s == StatusNeedsRevision
+}
+
+// StepOutcome returns the status a reimbursement moves to when an approver
+// with the given role decides a step of its approval chain. Approving the
+// last step completes approval; approving an earlier one leaves the claim in
+// approved_manager for the next step.
+func StepOutcome(role UserRole, last, approve bool) ReimbursementStatus {
+	if approve {
+		if last {
+			return StatusApprovedFinance
+		}
+		return StatusApprovedManager
+	}
+	if role == RoleFinance {
+		return StatusRejectedFinance
+	}
+	return StatusRejectedManager
+}
+
+// ResubmitStatus returns the status a reimbursement returned for revision
+// moves to when it is resubmitted at the given step: pending if nothing has
+// been approved yet, approved_manager otherwise.
+func ResubmitStatus(step int) ReimbursementStatus {
+	if step <= 1 {
+		return StatusPending
+	}
+	return StatusApprovedManager
+}
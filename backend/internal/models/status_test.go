@@ -0,0 +1,43 @@
+package models
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		name string
+		from ReimbursementStatus
+		to   ReimbursementStatus
+		role UserRole
+		want bool
+	}{
+		{"manager approves pending", StatusPending, StatusApprovedManager, RoleManager, true},
+		{"finance approves pending", StatusPending, StatusApprovedFinance, RoleFinance, true},
+		{"employee cannot approve", StatusPending, StatusApprovedManager, RoleEmployee, false},
+		{"manager rejects pending", StatusPending, StatusRejectedManager, RoleManager, true},
+		{"manager cannot reject as finance", StatusPending, StatusRejectedFinance, RoleManager, false},
+		{"finance cannot reject as manager", StatusPending, StatusRejectedManager, RoleFinance, false},
+		{"employee cancels pending", StatusPending, StatusCancelled, RoleEmployee, true},
+		{"manager cannot cancel", StatusPending, StatusCancelled, RoleManager, false},
+		{"approver returns for revision", StatusApprovedManager, StatusNeedsRevision, RoleFinance, true},
+		{"employee resubmits", StatusNeedsRevision, StatusPending, RoleEmployee, true},
+		{"employee resubmits to later step", StatusNeedsRevision, StatusApprovedManager, RoleEmployee, true},
+		{"employee submits draft", StatusDraft, StatusPending, RoleEmployee, true},
+		{"manager cannot submit draft", StatusDraft, StatusPending, RoleManager, false},
+		{"draft skips approval", StatusDraft, StatusApprovedFinance, RoleFinance, false},
+		{"finance pays", StatusApprovedFinance, StatusCompleted, RoleFinance, true},
+		{"manager cannot pay", StatusApprovedFinance, StatusCompleted, RoleManager, false},
+		{"finance reverses payment", StatusCompleted, StatusApprovedFinance, RoleFinance, true},
+		{"rejected is final", StatusRejectedManager, StatusPending, RoleEmployee, false},
+		{"cancelled is final", StatusCancelled, StatusPending, RoleEmployee, false},
+		{"pending cannot be paid", StatusPending, StatusCompleted, RoleFinance, false},
+		{"unknown status", ReimbursementStatus("bogus"), StatusPending, RoleEmployee, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanTransition(tt.from, tt.to, tt.role); got != tt.want {
+				t.Errorf("CanTransition(%s, %s, %s) = %v, want %v", tt.from, tt.to, tt.role, got, tt.want)
+			}
+		})
+	}
+}
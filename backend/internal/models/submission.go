@@ -0,0 +1,36 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// SubmissionWindow is how late an expense may be claimed: at most
+// MaxAgeDays days after it was spent, and before its month closes on day
+// MonthCloseDay of the following month, or on its last day if the month is
+// shorter. 0 disables either limit. Late
+// expenses are refused if BlockLate, and flagged otherwise.
+type SubmissionWindow struct {
+	MaxAgeDays    int
+	MonthCloseDay int
+	BlockLate     bool
+}
+
+// LateReason returns why an expense spent on the date expense is late when
+// claimed on the date submitted, or "" if it is not.
+func (w SubmissionWindow) LateReason(expense, submitted time.Time) string {
+	if w.MaxAgeDays > 0 {
+		if days := int(submitted.Sub(expense).Hours() / 24); days > w.MaxAgeDays {
+			return fmt.Sprintf("claimed %d days after the expense, more than the %d allowed", days, w.MaxAgeDays)
+		}
+	}
+	if w.MonthCloseDay > 0 {
+		// Day 0 of the month after next is the last day of the following month
+		lastDay := time.Date(expense.Year(), expense.Month()+2, 0, 0, 0, 0, 0, expense.Location()).Day()
+		closed := time.Date(expense.Year(), expense.Month()+1, min(w.MonthCloseDay, lastDay), 0, 0, 0, 0, expense.Location())
+		if submitted.After(closed) {
+			return fmt.Sprintf("claimed after %s was closed on %s", expense.Format("January 2006"), closed.Format("2006-01-02"))
+		}
+	}
+	return ""
+}
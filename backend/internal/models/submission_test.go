@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLateReason(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name      string
+		window    SubmissionWindow
+		expense   string
+		submitted string
+		late      bool
+	}{
+		{name: "no limits", window: SubmissionWindow{}, expense: "2023-01-01", submitted: "2024-06-01"},
+		{name: "same day", window: SubmissionWindow{MaxAgeDays: 90}, expense: "2024-03-04", submitted: "2024-03-04"},
+		{name: "on the last allowed day", window: SubmissionWindow{MaxAgeDays: 30}, expense: "2024-03-01", submitted: "2024-03-31"},
+		{name: "a day too old", window: SubmissionWindow{MaxAgeDays: 30}, expense: "2024-03-01", submitted: "2024-04-01", late: true},
+		{name: "before month close", window: SubmissionWindow{MonthCloseDay: 5}, expense: "2024-03-20", submitted: "2024-04-04"},
+		{name: "on month close", window: SubmissionWindow{MonthCloseDay: 5}, expense: "2024-03-20", submitted: "2024-04-05"},
+		{name: "after month close", window: SubmissionWindow{MonthCloseDay: 5}, expense: "2024-03-20", submitted: "2024-04-06", late: true},
+		{name: "month close across a year end", window: SubmissionWindow{MonthCloseDay: 10}, expense: "2023-12-31", submitted: "2024-01-11", late: true},
+		{name: "close day past February closes on its last day", window: SubmissionWindow{MonthCloseDay: 31}, expense: "2024-01-15", submitted: "2024-02-29"},
+		{name: "close day past February does not roll into March", window: SubmissionWindow{MonthCloseDay: 31}, expense: "2024-01-15", submitted: "2024-03-01", late: true},
+		{name: "close day past a 30-day month", window: SubmissionWindow{MonthCloseDay: 31}, expense: "2024-03-10", submitted: "2024-05-01", late: true},
+		{name: "close day 30 in a non-leap February", window: SubmissionWindow{MonthCloseDay: 30}, expense: "2023-01-31", submitted: "2023-03-01", late: true},
+		{name: "close day 29 in a leap February", window: SubmissionWindow{MonthCloseDay: 29}, expense: "2024-01-31", submitted: "2024-02-29"},
+		{name: "both limits, only month close", window: SubmissionWindow{MaxAgeDays: 90, MonthCloseDay: 5}, expense: "2024-03-01", submitted: "2024-04-10", late: true},
+		{name: "both limits, on time", window: SubmissionWindow{MaxAgeDays: 90, MonthCloseDay: 5}, expense: "2024-03-01", submitted: "2024-04-02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := tt.window.LateReason(date(tt.expense), date(tt.submitted))
+			if tt.late && reason == "" {
+				t.Errorf("expense of %s claimed on %s is not late, want late", tt.expense, tt.submitted)
+			}
+			if !tt.late && reason != "" {
+				t.Errorf("expense of %s claimed on %s is late (%s), want on time", tt.expense, tt.submitted, reason)
+			}
+		})
+	}
+}
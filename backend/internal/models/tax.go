@@ -0,0 +1,94 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// wholeUnitCurrencies are the currencies invoices are rounded to whole units
+// in, such as the rupiah, whose cents are not in circulation.
+var wholeUnitCurrencies = map[string]bool{
+	"IDR": true,
+	"JPY": true,
+	"KRW": true,
+	"VND": true,
+}
+
+// TaxTolerance is how far the net amount plus tax may be from the gross
+// amount, and the tax from the net amount at the tax rate, in currency: the
+// rounding of a tax invoice. That is one unit for currencies invoiced in
+// whole units and one cent for the others.
+func TaxTolerance(currency string) Money {
+	if wholeUnitCurrencies[currency] {
+		return 100
+	}
+	return 1
+}
+
+// TaxDetails is the VAT (PPN) breakdown of an expense, in the currency it was
+// spent in. It is optional; when given, NetAmount plus TaxAmount must match
+// the gross amount of the expense. Only tax on an invoice with a SellerTaxID
+// and TaxInvoiceNumber can be reclaimed.
+type TaxDetails struct {
+	NetAmount        *Money   `json:"net_amount,omitempty" db:"net_amount" binding:"omitempty,gt=0"`
+	TaxRate          *float64 `json:"tax_rate,omitempty" db:"tax_rate" binding:"omitempty,gte=0,lte=100"`
+	TaxAmount        *Money   `json:"tax_amount,omitempty" db:"tax_amount" binding:"omitempty,gte=0"`
+	SellerTaxID      *string  `json:"seller_tax_id,omitempty" db:"seller_tax_id" binding:"omitempty,max=30"`
+	TaxInvoiceNumber *string  `json:"tax_invoice_number,omitempty" db:"tax_invoice_number" binding:"omitempty,max=50"`
+}
+
+// IsEmpty reports whether no tax field is set.
+func (t TaxDetails) IsEmpty() bool {
+	return t.NetAmount == nil && t.TaxRate == nil && t.TaxAmount == nil && t.SellerTaxID == nil && t.TaxInvoiceNumber == nil
+}
+
+// Validate checks that the breakdown adds up to gross, an amount in currency.
+// The rate is a percentage, e.g. 11 for 11%.
+func (t TaxDetails) Validate(gross Money, currency string) error {
+	if t.IsEmpty() {
+		return nil
+	}
+	if t.NetAmount == nil || t.TaxAmount == nil {
+		return errors.New("net_amount and tax_amount are required with any tax detail")
+	}
+	net, tax := *t.NetAmount, *t.TaxAmount
+	if net <= 0 || tax < 0 {
+		return errors.New("net_amount must be positive and tax_amount must not be negative")
+	}
+	tolerance := TaxTolerance(currency)
+	if diff := net + tax - gross; diff > tolerance || diff < -tolerance {
+		return fmt.Errorf("net_amount %s plus tax_amount %s does not add up to the amount %s", net, tax, gross)
+	}
+	if t.TaxRate != nil {
+		rate := *t.TaxRate
+		if rate < 0 || rate > 100 {
+			return errors.New("tax_rate must be a percentage between 0 and 100")
+		}
+		if diff := net.Convert(rate/100) - tax; diff > tolerance || diff < -tolerance {
+			return fmt.Errorf("tax_amount %s is not %g%% of net_amount %s", tax, rate, net)
+		}
+	}
+	return nil
+}
+
+// TaxReportRow is the reclaimable tax of one month and category, in the base
+// currency.
+type TaxReportRow struct {
+	Month     string                `json:"month"`
+	Category  ReimbursementCategory `json:"category"`
+	Lines     int                   `json:"lines"`
+	NetAmount Money                 `json:"net_amount"`
+	TaxAmount Money                 `json:"tax_amount"`
+}
+
+// TaxReport aggregates the reclaimable tax of finance-approved claims by the
+// month of the expense and category. From and To are the months covered,
+// YYYY-MM, if limited.
+type TaxReport struct {
+	From           string         `json:"from,omitempty"`
+	To             string         `json:"to,omitempty"`
+	Currency       string         `json:"currency"`
+	Rows           []TaxReportRow `json:"rows"`
+	TotalNetAmount Money          `json:"total_net_amount"`
+	TotalTaxAmount Money          `json:"total_tax_amount"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "testing"
+
+func TestTaxDetailsValidate(t *testing.T) {
+	money := func(m Money) *Money { return &m }
+	rate := func(r float64) *float64 { return &r }
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		name     string
+		tax      TaxDetails
+		gross    Money
+		currency string
+		wantErr  bool
+	}{
+		{name: "no tax details", gross: 111000},
+		{name: "net plus tax is gross", tax: TaxDetails{NetAmount: money(100000), TaxAmount: money(11000)}, gross: 111000},
+		{name: "within one unit of rounding", tax: TaxDetails{NetAmount: money(100000), TaxAmount: money(11000)}, gross: 111100},
+		{name: "beyond one unit of rounding", tax: TaxDetails{NetAmount: money(100000), TaxAmount: money(11000)}, gross: 111101, wantErr: true},
+		{name: "under gross", tax: TaxDetails{NetAmount: money(90000), TaxAmount: money(11000)}, gross: 111000, wantErr: true},
+		{name: "zero tax", tax: TaxDetails{NetAmount: money(50000), TaxAmount: money(0)}, gross: 50000},
+		{name: "rate matches", tax: TaxDetails{NetAmount: money(100000), TaxAmount: money(11000), TaxRate: rate(11)}, gross: 111000},
+		{name: "rate matches after rounding", tax: TaxDetails{NetAmount: money(123456), TaxAmount: money(13580), TaxRate: rate(11)}, gross: 137036},
+		{name: "rate does not match", tax: TaxDetails{NetAmount: money(100000), TaxAmount: money(12000), TaxRate: rate(11)}, gross: 112000, wantErr: true},
+		{name: "rate out of range", tax: TaxDetails{NetAmount: money(100000), TaxAmount: money(11000), TaxRate: rate(111)}, gross: 111000, wantErr: true},
+		{name: "negative rate", tax: TaxDetails{NetAmount: money(100000), TaxAmount: money(11000), TaxRate: rate(-11)}, gross: 111000, wantErr: true},
+		{name: "only invoice number", tax: TaxDetails{TaxInvoiceNumber: text("010.000-24.00000001")}, gross: 111000, wantErr: true},
+		{name: "net without tax", tax: TaxDetails{NetAmount: money(100000)}, gross: 100000, wantErr: true},
+		{name: "tax without net", tax: TaxDetails{TaxAmount: money(11000)}, gross: 11000, wantErr: true},
+		{name: "zero net", tax: TaxDetails{NetAmount: money(0), TaxAmount: money(0)}, gross: 0, wantErr: true},
+		{name: "negative tax", tax: TaxDetails{NetAmount: money(112000), TaxAmount: money(-1000)}, gross: 111000, wantErr: true},
+		{name: "cents: net plus tax is gross", tax: TaxDetails{NetAmount: money(10000), TaxAmount: money(1100)}, gross: 11100, currency: "USD"},
+		{name: "cents: within one cent of rounding", tax: TaxDetails{NetAmount: money(10000), TaxAmount: money(1100)}, gross: 11101, currency: "USD"},
+		{name: "cents: beyond one cent of rounding", tax: TaxDetails{NetAmount: money(10000), TaxAmount: money(1100)}, gross: 11102, currency: "USD", wantErr: true},
+		{name: "cents: one unit off is refused", tax: TaxDetails{NetAmount: money(10000), TaxAmount: money(1100)}, gross: 11200, currency: "SGD", wantErr: true},
+		{name: "cents: rate matches after rounding", tax: TaxDetails{NetAmount: money(12345), TaxAmount: money(1358), TaxRate: rate(11)}, gross: 13703, currency: "USD"},
+		{name: "cents: rate within one cent", tax: TaxDetails{NetAmount: money(12345), TaxAmount: money(1359), TaxRate: rate(11)}, gross: 13704, currency: "USD"},
+		{name: "cents: rate beyond one cent", tax: TaxDetails{NetAmount: money(12345), TaxAmount: money(1360), TaxRate: rate(11)}, gross: 13705, currency: "EUR", wantErr: true},
+		{name: "whole units: yen within one unit", tax: TaxDetails{NetAmount: money(100000), TaxAmount: money(10000)}, gross: 110100, currency: "JPY"},
+		{
+			name: "reclaimable invoice",
+			tax: TaxDetails{
+				NetAmount:        money(100000),
+				TaxAmount:        money(11000),
+				TaxRate:          rate(11),
+				SellerTaxID:      text("01.234.567.8-901.000"),
+				TaxInvoiceNumber: text("010.000-24.00000001"),
+			},
+			gross: 111000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currency := tt.currency
+			if currency == "" {
+				currency = "IDR"
+			}
+			err := tt.tax.Validate(tt.gross, currency)
+			if tt.wantErr && err == nil {
+				t.Errorf("Validate(%s, %s) passed, want an error", tt.gross, currency)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate(%s, %s) returned %v", tt.gross, currency, err)
+			}
+		})
+	}
+}
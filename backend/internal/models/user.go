@@ -19,6 +19,7 @@ type User struct {
 	FullName  string    `json:"full_name" db:"full_name"`
 	Email     string    `json:"email" db:"email"`
 	Role      UserRole  `json:"role" db:"role"`
+	ManagerID *int      `json:"manager_id,omitempty" db:"manager_id"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -32,3 +33,7 @@ type LoginResponse struct {
 	Token string `json:"token"`
 	User  User   `json:"user"`
 }
+
+type SetManagerRequest struct {
+	ManagerID *int `json:"manager_id"`
+}
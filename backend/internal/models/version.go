@@ -0,0 +1,86 @@
+package models
+
+import (
+	"reflect"
+	"time"
+)
+
+// ReimbursementVersion is a snapshot of the submitter's fields of a
+// reimbursement. Version 1 is the claim as submitted; every edit adds the
+// next version.
+type ReimbursementVersion struct {
+	ID              int                   `json:"id" db:"id"`
+	ReimbursementID int                   `json:"reimbursement_id" db:"reimbursement_id"`
+	Version         int                   `json:"version" db:"version"`
+	Name            string                `json:"name" db:"name"`
+	Title           string                `json:"title" db:"title"`
+	Description     string                `json:"description" db:"description"`
+	Category        ReimbursementCategory `json:"category" db:"category"`
+	Amount          Money                 `json:"amount" db:"amount"`
+	ReceiptURL      string                `json:"receipt_url" db:"receipt_url"`
+	Lines           []VersionLine         `json:"lines" db:"lines"`
+	Allocations     []VersionAllocation   `json:"allocations" db:"allocations"`
+	EditedBy        *int                  `json:"edited_by,omitempty" db:"edited_by"`
+	CreatedAt       time.Time             `json:"created_at" db:"created_at"`
+}
+
+// VersionLine is a line of a report as it was in a version.
+type VersionLine struct {
+	LineNo      int                   `json:"line_no"`
+	Category    ReimbursementCategory `json:"category"`
+	Description string                `json:"description"`
+	Amount      Money                 `json:"amount"`
+	Currency    string                `json:"currency"`
+	Original    Money                 `json:"original_amount"`
+	ExpenseDate string                `json:"expense_date"`
+	ReceiptURL  string                `json:"receipt_url"`
+	TaxDetails
+	MileageDetails
+	PerDiemDetails
+}
+
+// VersionAllocation is an allocation of a claim as it was in a version.
+type VersionAllocation struct {
+	CostCenterID int      `json:"cost_center_id"`
+	ProjectID    *int     `json:"project_id,omitempty"`
+	Percent      *float64 `json:"percent,omitempty"`
+	Amount       Money    `json:"amount"`
+}
+
+// FieldChange is one field that differs between two versions.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// VersionDiff lists the fields changed between version From and version To.
+type VersionDiff struct {
+	ReimbursementID int           `json:"reimbursement_id"`
+	From            int           `json:"from"`
+	To              int           `json:"to"`
+	Changes         []FieldChange `json:"changes"`
+}
+
+// Diff returns the fields that differ between v and other, in a fixed order.
+func (v *ReimbursementVersion) Diff(other *ReimbursementVersion) []FieldChange {
+	changes := []FieldChange{}
+	add := func(field string, from, to interface{}) {
+		if from != to {
+			changes = append(changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+	add("name", v.Name, other.Name)
+	add("title", v.Title, other.Title)
+	add("description", v.Description, other.Description)
+	add("category", v.Category, other.Category)
+	add("amount", v.Amount, other.Amount)
+	add("receipt_url", v.ReceiptURL, other.ReceiptURL)
+	if !reflect.DeepEqual(v.Lines, other.Lines) {
+		changes = append(changes, FieldChange{Field: "lines", From: v.Lines, To: other.Lines})
+	}
+	if !reflect.DeepEqual(v.Allocations, other.Allocations) {
+		changes = append(changes, FieldChange{Field: "allocations", From: v.Allocations, To: other.Allocations})
+	}
+	return changes
+}
@@ -0,0 +1,448 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"reimbursement-backend/internal/models"
+)
+
+// ErrAdvanceClaimsOpen is returned when a cash advance is settled while
+// claims filed for it are still being approved.
+var ErrAdvanceClaimsOpen = errors.New("claims filed for the cash advance are still being approved")
+
+// ErrAdvanceNotOpen is returned when a claim is filed against a cash advance
+// that is not the employee's or is not disbursed, for example because it was
+// settled in the meantime.
+var ErrAdvanceNotOpen = errors.New("cash advance is not open for claims")
+
+// advanceColumns is the column list scanned by scanAdvance. Queries using it
+// must alias the cash_advances table as a. Until an advance is settled its
+// claimed amount is summed from its finance-approved claims.
+const advanceColumns = `
+	a.id, a.employee_id, a.employee_name, a.purpose, a.amount, a.status, a.approver_id,
+	a.decided_by, a.decided_at, a.decision_notes,
+	a.disbursed_date, a.disbursed_by, a.disbursement_method, a.disbursement_reference,
+	a.settled_date, a.settled_by, a.settlement_method, a.settlement_reference,
+	a.cancelled_at, a.created_at, a.updated_at,
+	COALESCE(a.claimed_amount, (
+		SELECT COALESCE(SUM(COALESCE(r.approved_amount, r.amount)), 0)
+		FROM reimbursements r
+		WHERE r.advance_id = a.id AND r.status IN ('approved_finance', 'completed')
+	))
+`
+
+// advanceManagerScope limits a query on cash_advances a to the advances
+// manager $1 may see: those of anyone in their reporting subtree and those
+// they are, or stand in through a delegation for, the approver of.
+const advanceManagerScope = `(
+	a.employee_id IN (
+		WITH RECURSIVE team AS (
+			SELECT id FROM users WHERE manager_id = $1
+			UNION
+			SELECT u.id FROM users u JOIN team t ON u.manager_id = t.id
+		)
+		SELECT id FROM team
+	)
+	OR a.approver_id = $1
+	OR a.approver_id IN (SELECT delegator_id FROM delegations WHERE delegate_id = $1 AND ` + activeDelegation + `)
+)`
+
+type AdvanceRepository struct {
+	db *sql.DB
+}
+
+func NewAdvanceRepository(db *sql.DB) *AdvanceRepository {
+	return &AdvanceRepository{db: db}
+}
+
+// Create stores a new cash advance with its created event.
+func (r *AdvanceRepository) Create(a *models.CashAdvance, actor models.Actor) error {
+	query := `
+		INSERT INTO cash_advances (employee_id, employee_name, purpose, amount, status, approver_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	return withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, a.EmployeeID, a.EmployeeName, a.Purpose, a.Amount, a.Status, a.ApproverID).
+			Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		a.Reconcile()
+		return insertAdvanceEvent(tx, a.ID, models.EventCreated, actor, "", a.Status, nil)
+	})
+}
+
+// GetByID returns a cash advance with the claims filed for it.
+func (r *AdvanceRepository) GetByID(id int) (*models.CashAdvance, error) {
+	query := `SELECT ` + advanceColumns + ` FROM cash_advances a WHERE a.id = $1`
+	a, err := scanAdvance(r.db.QueryRow(query, id))
+	if err != nil {
+		return nil, err
+	}
+	a.Claims, err = r.getClaims(id)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// GetAll returns every cash advance, newest first, optionally only those in
+// status.
+func (r *AdvanceRepository) GetAll(status models.AdvanceStatus) ([]models.CashAdvance, error) {
+	query := `SELECT ` + advanceColumns + ` FROM cash_advances a
+		WHERE ($1 = '' OR a.status = $1)
+		ORDER BY a.created_at DESC`
+	return r.queryAdvances(query, status)
+}
+
+func (r *AdvanceRepository) GetByEmployeeID(employeeID int, status models.AdvanceStatus) ([]models.CashAdvance, error) {
+	query := `SELECT ` + advanceColumns + ` FROM cash_advances a
+		WHERE a.employee_id = $1 AND ($2 = '' OR a.status = $2)
+		ORDER BY a.created_at DESC`
+	return r.queryAdvances(query, employeeID, status)
+}
+
+// GetVisibleToManager returns the cash advances a manager may see, newest
+// first, optionally only those in status.
+func (r *AdvanceRepository) GetVisibleToManager(managerID int, status models.AdvanceStatus) ([]models.CashAdvance, error) {
+	query := `SELECT ` + advanceColumns + ` FROM cash_advances a
+		WHERE ` + advanceManagerScope + ` AND ($2 = '' OR a.status = $2)
+		ORDER BY a.created_at DESC`
+	return r.queryAdvances(query, managerID, status)
+}
+
+func (r *AdvanceRepository) IsVisibleToManager(id, managerID int) (bool, error) {
+	var visible bool
+	query := `SELECT EXISTS (SELECT 1 FROM cash_advances a WHERE ` + advanceManagerScope + ` AND a.id = $2)`
+	err := r.db.QueryRow(query, managerID, id).Scan(&visible)
+	return visible, err
+}
+
+// Decide approves or rejects an advance in status from, with its event. It
+// fails with ErrStatusConflict if the advance has moved on in the meantime.
+func (r *AdvanceRepository) Decide(a *models.CashAdvance, from models.AdvanceStatus, actor models.Actor) error {
+	query := `
+		UPDATE cash_advances
+		SET status = $1, decided_by = $2, decided_at = $3, decision_notes = $4
+		WHERE id = $5 AND status = $6
+	`
+	now := time.Now()
+	action := models.EventApproved
+	if a.Status == models.AdvanceRejected {
+		action = models.EventRejected
+	}
+	err := withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, a.Status, actor.UserID, now, a.DecisionNotes, a.ID, from)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		return insertAdvanceEvent(tx, a.ID, action, actor, from, a.Status, a.DecisionNotes)
+	})
+	if err != nil {
+		return err
+	}
+	a.DecidedBy = &actor.UserID
+	a.DecidedAt = &now
+	return nil
+}
+
+// Disburse records that an approved advance was paid out to the employee,
+// with its event.
+func (r *AdvanceRepository) Disburse(a *models.CashAdvance, actor models.Actor) error {
+	query := `
+		UPDATE cash_advances
+		SET status = $1, disbursed_date = $2, disbursed_by = $3, disbursement_method = $4, disbursement_reference = $5
+		WHERE id = $6 AND status = $7
+	`
+	err := withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, models.AdvanceDisbursed, a.DisbursedDate, a.DisbursedBy, a.DisbursementMethod,
+			a.DisbursementReference, a.ID, models.AdvanceApproved)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		notes := fmt.Sprintf("%s %s", *a.DisbursementMethod, *a.DisbursementReference)
+		return insertAdvanceEvent(tx, a.ID, models.EventDisbursed, actor, models.AdvanceApproved, models.AdvanceDisbursed, &notes)
+	})
+	if err != nil {
+		return err
+	}
+	a.Status = models.AdvanceDisbursed
+	return nil
+}
+
+// Cancel withdraws an advance that has not been paid out yet, with its
+// event.
+func (r *AdvanceRepository) Cancel(id int, from models.AdvanceStatus, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE cash_advances SET status = $1, cancelled_at = $2 WHERE id = $3 AND status = $4
+		`, models.AdvanceCancelled, time.Now(), id, from)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		return insertAdvanceEvent(tx, id, models.EventCancelled, actor, from, models.AdvanceCancelled, nil)
+	})
+}
+
+// Settle closes a disbursed advance in one transaction. Its
+// finance-approved claims are completed, as they are paid by the advance
+// rather than one by one, and their payable amount is recorded as the
+// claimed amount of the advance. It fails with ErrAdvanceClaimsOpen if a
+// claim filed for the advance is still being approved, and with
+// ErrStatusConflict if the advance is no longer disbursed.
+func (r *AdvanceRepository) Settle(a *models.CashAdvance, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		// The lock waits for claims being filed against the advance, which
+		// hold it with lockOpenAdvance, so they are all seen below
+		var id int
+		err := tx.QueryRow(`
+			SELECT id FROM cash_advances WHERE id = $1 AND status = $2 FOR UPDATE
+		`, a.ID, models.AdvanceDisbursed).Scan(&id)
+		if err == sql.ErrNoRows {
+			return ErrStatusConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		var open bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM reimbursements
+				WHERE advance_id = $1 AND status IN ($2, $3, $4)
+			)
+		`, a.ID, models.StatusPending, models.StatusApprovedManager, models.StatusNeedsRevision).Scan(&open)
+		if err != nil {
+			return err
+		}
+		if open {
+			return ErrAdvanceClaimsOpen
+		}
+
+		rows, err := tx.Query(`
+			UPDATE reimbursements
+			SET status = $1
+			WHERE advance_id = $2 AND status = $3
+			RETURNING id, COALESCE(approved_amount, amount)
+		`, models.StatusCompleted, a.ID, models.StatusApprovedFinance)
+		if err != nil {
+			return err
+		}
+		var ids []int
+		var claimed models.Money
+		for rows.Next() {
+			var id int
+			var amount models.Money
+			if err := rows.Scan(&id, &amount); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+			claimed += amount
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		a.ClaimedAmount = claimed
+
+		notes := fmt.Sprintf("Settled against cash advance #%d", a.ID)
+		for _, id := range ids {
+			if err := insertEvent(tx, id, models.EventSettled, actor, models.StatusApprovedFinance, models.StatusCompleted, &notes); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`
+			UPDATE cash_advances
+			SET status = $1, claimed_amount = $2, settled_date = $3, settled_by = $4,
+			    settlement_method = $5, settlement_reference = $6
+			WHERE id = $7
+		`, models.AdvanceSettled, a.ClaimedAmount, a.SettledDate, actor.UserID, a.SettlementMethod, a.SettlementReference, a.ID)
+		if err != nil {
+			return err
+		}
+		a.Status = models.AdvanceSettled
+		a.SettledBy = &actor.UserID
+		a.Reconcile()
+
+		notes = fmt.Sprintf("Claimed %s of %s", a.ClaimedAmount, a.Amount)
+		if a.ReturnAmount > 0 {
+			notes += fmt.Sprintf(", %s returned", a.ReturnAmount)
+		} else if a.TopUpAmount > 0 {
+			notes += fmt.Sprintf(", %s topped up", a.TopUpAmount)
+		}
+		if a.SettlementMethod != nil && a.SettlementReference != nil {
+			notes += fmt.Sprintf(" by %s %s", *a.SettlementMethod, *a.SettlementReference)
+		}
+		return insertAdvanceEvent(tx, a.ID, models.EventSettled, actor, models.AdvanceDisbursed, models.AdvanceSettled, &notes)
+	})
+}
+
+// GetEvents returns the history of a cash advance, oldest first.
+func (r *AdvanceRepository) GetEvents(advanceID int) ([]models.AdvanceEvent, error) {
+	rows, err := r.db.Query(`
+		SELECT id, advance_id, action, actor_id, actor_role, from_status, to_status, notes,
+		       ip_address, user_agent, created_at
+		FROM cash_advance_events
+		WHERE advance_id = $1
+		ORDER BY created_at, id
+	`, advanceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AdvanceEvent{}
+	for rows.Next() {
+		var e models.AdvanceEvent
+		err := rows.Scan(
+			&e.ID,
+			&e.AdvanceID,
+			&e.Action,
+			&e.ActorID,
+			&e.ActorRole,
+			&e.FromStatus,
+			&e.ToStatus,
+			&e.Notes,
+			&e.IPAddress,
+			&e.UserAgent,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// insertAdvanceEvent appends an event to the history of a cash advance as
+// part of the transaction making the change, as insertEvent does for
+// reimbursements.
+func insertAdvanceEvent(tx *sql.Tx, advanceID int, action string, actor models.Actor, from, to models.AdvanceStatus, notes *string) error {
+	_, err := tx.Exec(`
+		INSERT INTO cash_advance_events (advance_id, action, actor_id, actor_role, from_status, to_status, notes, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		advanceID,
+		action,
+		nullIfZero(actor.UserID),
+		nullIfEmpty(string(actor.Role)),
+		nullIfEmpty(string(from)),
+		nullIfEmpty(string(to)),
+		notes,
+		nullIfEmpty(actor.IPAddress),
+		nullIfEmpty(actor.UserAgent),
+	)
+	return err
+}
+
+// lockOpenAdvance checks, in the transaction filing a claim against the
+// advance advanceID, that it is a disbursed advance of employeeID, and holds
+// it until the claim is stored so it cannot be settled in the meantime. It
+// fails with ErrAdvanceNotOpen otherwise. A nil advanceID is not checked.
+func lockOpenAdvance(tx *sql.Tx, advanceID *int, employeeID int) error {
+	if advanceID == nil {
+		return nil
+	}
+	var id int
+	err := tx.QueryRow(`
+		SELECT id FROM cash_advances WHERE id = $1 AND employee_id = $2 AND status = $3 FOR SHARE
+	`, *advanceID, employeeID, models.AdvanceDisbursed).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrAdvanceNotOpen
+	}
+	return err
+}
+
+// getClaims returns the claims filed for an advance, oldest first. A draft is
+// only filed against its advance once it is submitted.
+func (r *AdvanceRepository) getClaims(advanceID int) ([]models.AdvanceClaim, error) {
+	rows, err := r.db.Query(`
+		SELECT id, title, status, expense_date, COALESCE(approved_amount, amount)
+		FROM reimbursements
+		WHERE advance_id = $1
+		ORDER BY created_at, id
+	`, advanceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claims []models.AdvanceClaim
+	for rows.Next() {
+		var claim models.AdvanceClaim
+		if err := rows.Scan(&claim.ID, &claim.Title, &claim.Status, &claim.ExpenseDate, &claim.PayableAmount); err != nil {
+			return nil, err
+		}
+		claims = append(claims, claim)
+	}
+	return claims, rows.Err()
+}
+
+func (r *AdvanceRepository) queryAdvances(query string, args ...interface{}) ([]models.CashAdvance, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var advances []models.CashAdvance
+	for rows.Next() {
+		a, err := scanAdvance(rows)
+		if err != nil {
+			return nil, err
+		}
+		advances = append(advances, *a)
+	}
+	return advances, nil
+}
+
+// scanAdvance scans one row selected with advanceColumns.
+func scanAdvance(row rowScanner) (*models.CashAdvance, error) {
+	a := &models.CashAdvance{}
+	err := row.Scan(
+		&a.ID,
+		&a.EmployeeID,
+		&a.EmployeeName,
+		&a.Purpose,
+		&a.Amount,
+		&a.Status,
+		&a.ApproverID,
+		&a.DecidedBy,
+		&a.DecidedAt,
+		&a.DecisionNotes,
+		&a.DisbursedDate,
+		&a.DisbursedBy,
+		&a.DisbursementMethod,
+		&a.DisbursementReference,
+		&a.SettledDate,
+		&a.SettledBy,
+		&a.SettlementMethod,
+		&a.SettlementReference,
+		&a.CancelledAt,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+		&a.ClaimedAmount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	a.Reconcile()
+	return a, nil
+}
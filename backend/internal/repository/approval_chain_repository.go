@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"reimbursement-backend/internal/models"
+)
+
+type ApprovalChainRepository struct {
+	db *sql.DB
+}
+
+func NewApprovalChainRepository(db *sql.DB) *ApprovalChainRepository {
+	return &ApprovalChainRepository{db: db}
+}
+
+// Resolve picks the active chain for a claim of the given category and
+// amount. Category-specific chains win over generic ones, then the highest
+// priority, then the narrowest (highest) minimum amount.
+func (r *ApprovalChainRepository) Resolve(category models.ReimbursementCategory, amount models.Money) (*models.ApprovalChain, error) {
+	var id int
+	query := `
+		SELECT id
+		FROM approval_chains
+		WHERE active
+		  AND (category IS NULL OR category = $1)
+		  AND min_amount <= $2
+		  AND (max_amount IS NULL OR $2 < max_amount)
+		ORDER BY (category IS NOT NULL) DESC, priority DESC, min_amount DESC
+		LIMIT 1
+	`
+	err := r.db.QueryRow(query, category, amount).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no approval chain matches this reimbursement")
+		}
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+func (r *ApprovalChainRepository) GetByID(id int) (*models.ApprovalChain, error) {
+	chain := &models.ApprovalChain{}
+	query := `
+		SELECT id, name, category, min_amount, max_amount, priority, active, created_at
+		FROM approval_chains
+		WHERE id = $1
+	`
+	err := r.db.QueryRow(query, id).Scan(
+		&chain.ID,
+		&chain.Name,
+		&chain.Category,
+		&chain.MinAmount,
+		&chain.MaxAmount,
+		&chain.Priority,
+		&chain.Active,
+		&chain.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("approval chain not found")
+		}
+		return nil, err
+	}
+
+	chain.Steps, err = r.getSteps(id)
+	if err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+func (r *ApprovalChainRepository) GetAll() ([]models.ApprovalChain, error) {
+	query := `
+		SELECT id, name, category, min_amount, max_amount, priority, active, created_at
+		FROM approval_chains
+		ORDER BY active DESC, priority DESC, min_amount
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chains []models.ApprovalChain
+	for rows.Next() {
+		var chain models.ApprovalChain
+		err := rows.Scan(
+			&chain.ID,
+			&chain.Name,
+			&chain.Category,
+			&chain.MinAmount,
+			&chain.MaxAmount,
+			&chain.Priority,
+			&chain.Active,
+			&chain.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		chains = append(chains, chain)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range chains {
+		chains[i].Steps, err = r.getSteps(chains[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return chains, nil
+}
+
+// Create inserts a chain and its steps in one transaction. Steps are
+// numbered in the order given, starting at 1.
+func (r *ApprovalChainRepository) Create(chain *models.ApprovalChain) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(`
+			INSERT INTO approval_chains (name, category, min_amount, max_amount, priority)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, active, created_at
+		`,
+			chain.Name,
+			chain.Category,
+			chain.MinAmount,
+			chain.MaxAmount,
+			chain.Priority,
+		).Scan(&chain.ID, &chain.Active, &chain.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		for i := range chain.Steps {
+			step := &chain.Steps[i]
+			step.ChainID = chain.ID
+			step.StepOrder = i + 1
+			err := tx.QueryRow(`
+				INSERT INTO approval_chain_steps (chain_id, step_order, name, required_role, required_user_id, cost_center_owner)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING id
+			`, step.ChainID, step.StepOrder, step.Name, step.RequiredRole, step.RequiredUserID, step.CostCenterOwner).Scan(&step.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetActive enables or disables a chain for new claims. Claims already
+// routed through it keep using it.
+func (r *ApprovalChainRepository) SetActive(id int, active bool) error {
+	result, err := r.db.Exec(`UPDATE approval_chains SET active = $1 WHERE id = $2`, active, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("approval chain not found")
+	}
+	return nil
+}
+
+func (r *ApprovalChainRepository) getSteps(chainID int) ([]models.ApprovalStep, error) {
+	query := `
+		SELECT id, chain_id, step_order, name, required_role, required_user_id, cost_center_owner
+		FROM approval_chain_steps
+		WHERE chain_id = $1
+		ORDER BY step_order
+	`
+	rows, err := r.db.Query(query, chainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var steps []models.ApprovalStep
+	for rows.Next() {
+		var step models.ApprovalStep
+		err := rows.Scan(
+			&step.ID,
+			&step.ChainID,
+			&step.StepOrder,
+			&step.Name,
+			&step.RequiredRole,
+			&step.RequiredUserID,
+			&step.CostCenterOwner,
+		)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"reimbursement-backend/internal/models"
+)
+
+const categoryColumns = `
+	code, display_name, local_display_name, gl_account, receipt_required, active, created_at, updated_at
+`
+
+// ErrCategoryExists is returned when creating a category whose code is
+// taken.
+var ErrCategoryExists = errors.New("category already exists")
+
+type CategoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// GetAll returns the active categories, or every category if
+// includeInactive, by display name.
+func (r *CategoryRepository) GetAll(includeInactive bool) ([]models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE active OR $1 ORDER BY active DESC, display_name`
+	rows, err := r.db.Query(query, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, *category)
+	}
+	return categories, nil
+}
+
+func (r *CategoryRepository) Get(code models.ReimbursementCategory) (*models.Category, error) {
+	category, err := scanCategory(r.db.QueryRow(`SELECT `+categoryColumns+` FROM categories WHERE code = $1`, code))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("category not found")
+	}
+	return category, err
+}
+
+// Create adds a category, or returns ErrCategoryExists if the code is taken.
+func (r *CategoryRepository) Create(category *models.Category) error {
+	err := r.db.QueryRow(`
+		INSERT INTO categories (code, display_name, local_display_name, gl_account, receipt_required, active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING created_at, updated_at
+	`, category.Code, category.DisplayName, category.LocalDisplayName, category.GLAccount, category.ReceiptRequired,
+		category.Active).Scan(&category.CreatedAt, &category.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrCategoryExists
+	}
+	return err
+}
+
+func (r *CategoryRepository) Update(category *models.Category) error {
+	return r.db.QueryRow(`
+		UPDATE categories
+		SET display_name = $1, local_display_name = $2, gl_account = $3, receipt_required = $4, active = $5,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE code = $6
+		RETURNING updated_at
+	`, category.DisplayName, category.LocalDisplayName, category.GLAccount, category.ReceiptRequired, category.Active,
+		category.Code).Scan(&category.UpdatedAt)
+}
+
+func scanCategory(row rowScanner) (*models.Category, error) {
+	var category models.Category
+	err := row.Scan(
+		&category.Code,
+		&category.DisplayName,
+		&category.LocalDisplayName,
+		&category.GLAccount,
+		&category.ReceiptRequired,
+		&category.Active,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
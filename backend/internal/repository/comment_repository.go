@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"reimbursement-backend/internal/models"
+)
+
+type CommentRepository struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// Create stores a comment and fills in its ID, timestamp and author details.
+func (r *CommentRepository) Create(comment *models.Comment) error {
+	if comment.Attachments == nil {
+		comment.Attachments = []string{}
+	}
+	query := `
+		WITH inserted AS (
+			INSERT INTO reimbursement_comments (reimbursement_id, author_id, body, attachments)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, author_id, created_at
+		)
+		SELECT i.id, i.created_at, u.full_name, u.role
+		FROM inserted i
+		JOIN users u ON u.id = i.author_id
+	`
+	return r.db.QueryRow(
+		query,
+		comment.ReimbursementID,
+		comment.AuthorID,
+		comment.Body,
+		pq.Array(comment.Attachments),
+	).Scan(&comment.ID, &comment.CreatedAt, &comment.AuthorName, &comment.AuthorRole)
+}
+
+// GetByReimbursementID returns the thread of a reimbursement, oldest first.
+func (r *CommentRepository) GetByReimbursementID(reimbursementID int) ([]models.Comment, error) {
+	query := `
+		SELECT c.id, c.reimbursement_id, c.author_id, u.full_name, u.role, c.body, c.attachments, c.created_at
+		FROM reimbursement_comments c
+		JOIN users u ON u.id = c.author_id
+		WHERE c.reimbursement_id = $1
+		ORDER BY c.created_at, c.id
+	`
+	rows, err := r.db.Query(query, reimbursementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var comment models.Comment
+		err := rows.Scan(
+			&comment.ID,
+			&comment.ReimbursementID,
+			&comment.AuthorID,
+			&comment.AuthorName,
+			&comment.AuthorRole,
+			&comment.Body,
+			pq.Array(&comment.Attachments),
+			&comment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
+
+// MarkRead records that a user has read the thread of a reimbursement up to
+// its latest comment.
+func (r *CommentRepository) MarkRead(reimbursementID, userID int) error {
+	query := `
+		INSERT INTO reimbursement_comment_reads (reimbursement_id, user_id, last_read_comment_id)
+		SELECT $1, $2, COALESCE(MAX(id), 0) FROM reimbursement_comments WHERE reimbursement_id = $1
+		ON CONFLICT (reimbursement_id, user_id) DO UPDATE
+		SET last_read_comment_id = GREATEST(reimbursement_comment_reads.last_read_comment_id, EXCLUDED.last_read_comment_id)
+	`
+	_, err := r.db.Exec(query, reimbursementID, userID)
+	return err
+}
+
+// CountUnread returns how many comments others have posted on a
+// reimbursement since the user last read its thread.
+func (r *CommentRepository) CountUnread(reimbursementID, userID int) (int, error) {
+	var unread int
+	query := `
+		SELECT COUNT(*)
+		FROM reimbursement_comments c
+		WHERE c.reimbursement_id = $1 AND c.author_id <> $2
+		  AND c.id > COALESCE((
+			SELECT last_read_comment_id FROM reimbursement_comment_reads
+			WHERE reimbursement_id = $1 AND user_id = $2
+		  ), 0)
+	`
+	err := r.db.QueryRow(query, reimbursementID, userID).Scan(&unread)
+	return unread, err
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"reimbursement-backend/internal/models"
+)
+
+const costCenterColumns = `
+	id, code, name, owner_id, active, created_at, updated_at
+`
+
+// ErrCostCenterExists is returned when creating a cost center whose code is
+// taken.
+var ErrCostCenterExists = errors.New("cost center already exists")
+
+type CostCenterRepository struct {
+	db *sql.DB
+}
+
+func NewCostCenterRepository(db *sql.DB) *CostCenterRepository {
+	return &CostCenterRepository{db: db}
+}
+
+// GetAll returns the active cost centers, or every cost center if
+// includeInactive, by code.
+func (r *CostCenterRepository) GetAll(includeInactive bool) ([]models.CostCenter, error) {
+	query := `SELECT ` + costCenterColumns + ` FROM cost_centers WHERE active OR $1 ORDER BY active DESC, code`
+	rows, err := r.db.Query(query, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	costCenters := []models.CostCenter{}
+	for rows.Next() {
+		costCenter, err := scanCostCenter(rows)
+		if err != nil {
+			return nil, err
+		}
+		costCenters = append(costCenters, *costCenter)
+	}
+	return costCenters, nil
+}
+
+func (r *CostCenterRepository) GetByID(id int) (*models.CostCenter, error) {
+	costCenter, err := scanCostCenter(r.db.QueryRow(`SELECT `+costCenterColumns+` FROM cost_centers WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("cost center not found")
+	}
+	return costCenter, err
+}
+
+// Create adds a cost center, or returns ErrCostCenterExists if the code is
+// taken.
+func (r *CostCenterRepository) Create(costCenter *models.CostCenter) error {
+	err := r.db.QueryRow(`
+		INSERT INTO cost_centers (code, name, owner_id, active)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING id, created_at, updated_at
+	`, costCenter.Code, costCenter.Name, costCenter.OwnerID, costCenter.Active).Scan(&costCenter.ID, &costCenter.CreatedAt, &costCenter.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrCostCenterExists
+	}
+	return err
+}
+
+func (r *CostCenterRepository) Update(costCenter *models.CostCenter) error {
+	return r.db.QueryRow(`
+		UPDATE cost_centers
+		SET name = $1, owner_id = $2, active = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING updated_at
+	`, costCenter.Name, costCenter.OwnerID, costCenter.Active, costCenter.ID).Scan(&costCenter.UpdatedAt)
+}
+
+func scanCostCenter(row rowScanner) (*models.CostCenter, error) {
+	var costCenter models.CostCenter
+	err := row.Scan(
+		&costCenter.ID,
+		&costCenter.Code,
+		&costCenter.Name,
+		&costCenter.OwnerID,
+		&costCenter.Active,
+		&costCenter.CreatedAt,
+		&costCenter.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &costCenter, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"reimbursement-backend/internal/models"
+)
+
+// activeDelegation is the condition for a delegation row to be in effect
+// today. Delegations expire on their own once end_date has passed.
+const activeDelegation = `revoked_at IS NULL AND CURRENT_DATE BETWEEN start_date AND end_date`
+
+type DelegationRepository struct {
+	db *sql.DB
+}
+
+func NewDelegationRepository(db *sql.DB) *DelegationRepository {
+	return &DelegationRepository{db: db}
+}
+
+func (r *DelegationRepository) Create(d *models.Delegation) error {
+	query := `
+		INSERT INTO delegations (delegator_id, delegate_id, start_date, end_date, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(
+		query,
+		d.DelegatorID,
+		d.DelegateID,
+		d.StartDate,
+		d.EndDate,
+		d.Reason,
+	).Scan(&d.ID, &d.CreatedAt)
+}
+
+// GetByUser returns the delegations a user has given or received, newest
+// first.
+func (r *DelegationRepository) GetByUser(userID int) ([]models.Delegation, error) {
+	query := `
+		SELECT id, delegator_id, delegate_id, start_date, end_date, reason, revoked_at, created_at
+		FROM delegations
+		WHERE delegator_id = $1 OR delegate_id = $1
+		ORDER BY start_date DESC, id DESC
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var delegations []models.Delegation
+	for rows.Next() {
+		var d models.Delegation
+		err := rows.Scan(
+			&d.ID,
+			&d.DelegatorID,
+			&d.DelegateID,
+			&d.StartDate,
+			&d.EndDate,
+			&d.Reason,
+			&d.RevokedAt,
+			&d.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, d)
+	}
+	return delegations, nil
+}
+
+// Revoke ends a delegation early. Only the delegator can revoke it.
+func (r *DelegationRepository) Revoke(id, delegatorID int) error {
+	query := `
+		UPDATE delegations
+		SET revoked_at = $1
+		WHERE id = $2 AND delegator_id = $3 AND revoked_at IS NULL
+	`
+	result, err := r.db.Exec(query, time.Now(), id, delegatorID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("delegation not found")
+	}
+	return nil
+}
+
+// IsActive reports whether delegateID may act for delegatorID today.
+func (r *DelegationRepository) IsActive(delegatorID, delegateID int) (bool, error) {
+	var active bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM delegations
+			WHERE delegator_id = $1 AND delegate_id = $2 AND ` + activeDelegation + `
+		)
+	`
+	err := r.db.QueryRow(query, delegatorID, delegateID).Scan(&active)
+	return active, err
+}
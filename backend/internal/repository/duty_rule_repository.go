@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"reimbursement-backend/internal/models"
+)
+
+type DutyRuleRepository struct {
+	db *sql.DB
+}
+
+func NewDutyRuleRepository(db *sql.DB) *DutyRuleRepository {
+	return &DutyRuleRepository{db: db}
+}
+
+func (r *DutyRuleRepository) GetAll() ([]models.DutyRule, error) {
+	query := `
+		SELECT code, description, enabled, updated_at
+		FROM duty_rules
+		ORDER BY code
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.DutyRule
+	for rows.Next() {
+		var rule models.DutyRule
+		if err := rows.Scan(&rule.Code, &rule.Description, &rule.Enabled, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// IsEnabled reports whether a rule is switched on. Unknown rules count as on.
+func (r *DutyRuleRepository) IsEnabled(code string) (bool, error) {
+	var enabled bool
+	err := r.db.QueryRow(`SELECT enabled FROM duty_rules WHERE code = $1`, code).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	return enabled, err
+}
+
+func (r *DutyRuleRepository) SetEnabled(code string, enabled bool) error {
+	result, err := r.db.Exec(`UPDATE duty_rules SET enabled = $1, updated_at = CURRENT_TIMESTAMP WHERE code = $2`, enabled, code)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("rule not found")
+	}
+	return nil
+}
+
+// GetViolations checks the stored decisions against every rule, whether it
+// is currently switched on or not. Both the approval history and the legacy
+// manager_id/finance_id columns are checked, so claims decided before the
+// history was kept are covered too. An approval recorded on behalf of someone
+// counts for both the delegate and the person they stood in for, as it does
+// when the decision is made.
+func (r *DutyRuleRepository) GetViolations() ([]models.DutyViolation, error) {
+	query := `
+		SELECT $1 AS rule, a.reimbursement_id, r.employee_id,
+		       'Submitter decided step ' || a.step_order || ' (' || a.action || ')' AS details, a.created_at
+		FROM reimbursement_approvals a
+		JOIN reimbursements r ON r.id = a.reimbursement_id
+		WHERE a.approver_id = r.employee_id OR a.on_behalf_of_id = r.employee_id
+
+		UNION ALL
+
+		SELECT $1, r.id, r.employee_id, 'Submitter is recorded as manager or finance approver', NULL
+		FROM reimbursements r
+		WHERE (r.manager_id = r.employee_id OR r.finance_id = r.employee_id)
+		  AND NOT EXISTS (SELECT 1 FROM reimbursement_approvals a WHERE a.reimbursement_id = r.id)
+
+		UNION ALL
+
+		SELECT $2, a.reimbursement_id, p.person,
+		       'Approved ' || COUNT(DISTINCT a.step_order) || ' steps', MAX(a.created_at)
+		FROM reimbursement_approvals a
+		CROSS JOIN LATERAL UNNEST(ARRAY[a.approver_id, a.on_behalf_of_id]) AS p(person)
+		WHERE a.action = 'approve' AND p.person IS NOT NULL
+		GROUP BY a.reimbursement_id, p.person
+		HAVING COUNT(DISTINCT a.step_order) > 1
+
+		UNION ALL
+
+		SELECT $2, r.id, r.manager_id, 'Recorded as both manager and finance approver', r.finance_approved
+		FROM reimbursements r
+		WHERE r.manager_id = r.finance_id
+		  AND NOT EXISTS (SELECT 1 FROM reimbursement_approvals a WHERE a.reimbursement_id = r.id)
+
+		ORDER BY 2 DESC, 1
+	`
+	rows, err := r.db.Query(query, models.RuleNoSelfApproval, models.RuleDistinctApprovers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	violations := []models.DutyViolation{}
+	for rows.Next() {
+		var v models.DutyViolation
+		if err := rows.Scan(&v.Rule, &v.ReimbursementID, &v.UserID, &v.Details, &v.DecidedAt); err != nil {
+			return nil, err
+		}
+		violations = append(violations, v)
+	}
+	return violations, nil
+}
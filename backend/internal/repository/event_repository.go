@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"database/sql"
+
+	"reimbursement-backend/internal/models"
+)
+
+type EventRepository struct {
+	db *sql.DB
+}
+
+func NewEventRepository(db *sql.DB) *EventRepository {
+	return &EventRepository{db: db}
+}
+
+// GetByReimbursementID returns the history of a reimbursement, oldest first.
+func (r *EventRepository) GetByReimbursementID(reimbursementID int) ([]models.ReimbursementEvent, error) {
+	query := `
+		SELECT id, reimbursement_id, action, actor_id, actor_role, from_status, to_status, notes,
+		       ip_address, user_agent, created_at
+		FROM reimbursement_events
+		WHERE reimbursement_id = $1
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(query, reimbursementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.ReimbursementEvent{}
+	for rows.Next() {
+		var e models.ReimbursementEvent
+		err := rows.Scan(
+			&e.ID,
+			&e.ReimbursementID,
+			&e.Action,
+			&e.ActorID,
+			&e.ActorRole,
+			&e.FromStatus,
+			&e.ToStatus,
+			&e.Notes,
+			&e.IPAddress,
+			&e.UserAgent,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// insertEvent appends an event to the history of a reimbursement as part of
+// the transaction making the change. Empty statuses and notes are stored as
+// NULL.
+func insertEvent(tx *sql.Tx, reimbursementID int, action string, actor models.Actor, from, to models.ReimbursementStatus, notes *string) error {
+	_, err := tx.Exec(`
+		INSERT INTO reimbursement_events (reimbursement_id, action, actor_id, actor_role, from_status, to_status, notes, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		reimbursementID,
+		action,
+		nullIfZero(actor.UserID),
+		nullIfEmpty(string(actor.Role)),
+		nullIfEmpty(string(from)),
+		nullIfEmpty(string(to)),
+		notes,
+		nullIfEmpty(actor.IPAddress),
+		nullIfEmpty(actor.UserAgent),
+	)
+	return err
+}
+
+func nullIfZero(v int) *int {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"reimbursement-backend/internal/models"
+)
+
+// ErrNoExchangeRate is returned when a currency has no rate on or before the
+// requested date.
+var ErrNoExchangeRate = errors.New("no exchange rate")
+
+type ExchangeRateRepository struct {
+	db *sql.DB
+}
+
+func NewExchangeRateRepository(db *sql.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db}
+}
+
+// GetAll returns the rates of currency, or of every currency if it is empty,
+// newest first.
+func (r *ExchangeRateRepository) GetAll(currency string) ([]models.ExchangeRate, error) {
+	query := `
+		SELECT id, currency, rate_date, rate, created_by, created_at, updated_at
+		FROM exchange_rates
+		WHERE $1 = '' OR currency = $1
+		ORDER BY currency, rate_date DESC
+	`
+	rows, err := r.db.Query(query, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		var rate models.ExchangeRate
+		if err := rows.Scan(&rate.ID, &rate.Currency, &rate.RateDate, &rate.Rate, &rate.CreatedBy, &rate.CreatedAt, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// RateOn returns the rate of currency in effect on date: the latest rate
+// dated on or before it.
+func (r *ExchangeRateRepository) RateOn(currency string, date time.Time) (*models.ExchangeRate, error) {
+	query := `
+		SELECT id, currency, rate_date, rate, created_by, created_at, updated_at
+		FROM exchange_rates
+		WHERE currency = $1 AND rate_date <= $2
+		ORDER BY rate_date DESC
+		LIMIT 1
+	`
+	var rate models.ExchangeRate
+	err := r.db.QueryRow(query, currency, date).Scan(
+		&rate.ID, &rate.Currency, &rate.RateDate, &rate.Rate, &rate.CreatedBy, &rate.CreatedAt, &rate.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoExchangeRate
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// Save creates or replaces the rate of a currency on a date.
+func (r *ExchangeRateRepository) Save(rate *models.ExchangeRate) error {
+	return saveExchangeRate(r.db, rate)
+}
+
+// Import saves a batch of rates in one transaction, so a failing row leaves
+// the table unchanged.
+func (r *ExchangeRateRepository) Import(rates []models.ExchangeRate) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		for i := range rates {
+			if err := saveExchangeRate(tx, &rates[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *ExchangeRateRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM exchange_rates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("exchange rate not found")
+	}
+	return nil
+}
+
+func saveExchangeRate(q queryRower, rate *models.ExchangeRate) error {
+	return q.QueryRow(`
+		INSERT INTO exchange_rates (currency, rate_date, rate, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (currency, rate_date) DO UPDATE
+		SET rate = EXCLUDED.rate, created_by = EXCLUDED.created_by, updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at
+	`, rate.Currency, rate.RateDate, rate.Rate, rate.CreatedBy).Scan(&rate.ID, &rate.CreatedAt, &rate.UpdatedAt)
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"reimbursement-backend/internal/models"
+)
+
+// ErrNoMileageRate is returned when a vehicle type has no rate in effect on
+// the requested date.
+var ErrNoMileageRate = errors.New("no mileage rate")
+
+type MileageRateRepository struct {
+	db *sql.DB
+}
+
+func NewMileageRateRepository(db *sql.DB) *MileageRateRepository {
+	return &MileageRateRepository{db: db}
+}
+
+// GetAll returns every mileage rate, newest first per vehicle type.
+func (r *MileageRateRepository) GetAll() ([]models.MileageRate, error) {
+	query := `
+		SELECT id, vehicle_type, rate_per_km, effective_from, created_by, created_at, updated_at
+		FROM mileage_rates
+		ORDER BY vehicle_type, effective_from DESC
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []models.MileageRate{}
+	for rows.Next() {
+		var rate models.MileageRate
+		if err := rows.Scan(&rate.ID, &rate.VehicleType, &rate.RatePerKm, &rate.EffectiveFrom, &rate.CreatedBy, &rate.CreatedAt, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// RateOn returns the rate of a vehicle type in effect on date: the latest
+// rate effective on or before it.
+func (r *MileageRateRepository) RateOn(vehicleType models.VehicleType, date time.Time) (*models.MileageRate, error) {
+	query := `
+		SELECT id, vehicle_type, rate_per_km, effective_from, created_by, created_at, updated_at
+		FROM mileage_rates
+		WHERE vehicle_type = $1 AND effective_from <= $2
+		ORDER BY effective_from DESC
+		LIMIT 1
+	`
+	var rate models.MileageRate
+	err := r.db.QueryRow(query, vehicleType, date).Scan(
+		&rate.ID, &rate.VehicleType, &rate.RatePerKm, &rate.EffectiveFrom, &rate.CreatedBy, &rate.CreatedAt, &rate.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoMileageRate
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// Save creates or replaces the rate of a vehicle type effective from a date.
+func (r *MileageRateRepository) Save(rate *models.MileageRate) error {
+	return r.db.QueryRow(`
+		INSERT INTO mileage_rates (vehicle_type, rate_per_km, effective_from, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (vehicle_type, effective_from) DO UPDATE
+		SET rate_per_km = EXCLUDED.rate_per_km, created_by = EXCLUDED.created_by, updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at
+	`, rate.VehicleType, rate.RatePerKm, rate.EffectiveFrom, rate.CreatedBy).Scan(&rate.ID, &rate.CreatedAt, &rate.UpdatedAt)
+}
+
+func (r *MileageRateRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM mileage_rates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("mileage rate not found")
+	}
+	return nil
+}
+
+// DistanceInMonth returns the kilometres an employee has claimed for trips
+// in the month starting at month, leaving out reimbursement excludeID,
+// cancelled and rejected claims and rejected lines.
+func (r *MileageRateRepository) DistanceInMonth(employeeID int, month time.Time, excludeID int) (float64, error) {
+	var distance float64
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(l.distance_km), 0)
+		FROM reimbursement_lines l
+		JOIN reimbursements r ON r.id = l.reimbursement_id
+		WHERE r.employee_id = $1 AND r.id <> $2
+		AND r.status NOT IN ('cancelled', 'rejected_manager', 'rejected_finance')
+		AND l.category = 'mileage' AND l.status <> 'rejected'
+		AND l.expense_date >= $3 AND l.expense_date < $4
+	`, employeeID, excludeID, month, month.AddDate(0, 1, 0)).Scan(&distance)
+	return distance, err
+}
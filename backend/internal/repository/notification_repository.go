@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"database/sql"
+
+	"reimbursement-backend/internal/models"
+)
+
+// queryRower is implemented by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Create stores a notification unless the same one was already stored for
+// this wait on the step. It reports whether the notification was new.
+func (r *NotificationRepository) Create(n *models.Notification) (bool, error) {
+	return insertNotification(r.db, n)
+}
+
+// GetForUser returns the notifications addressed to a user, or to everyone
+// with their role, newest first.
+func (r *NotificationRepository) GetForUser(userID int, role models.UserRole) ([]models.Notification, error) {
+	query := `
+		SELECT id, reimbursement_id, recipient_id, recipient_role, kind, message, step_order, step_entered_at, created_at
+		FROM reimbursement_notifications
+		WHERE recipient_id = $1 OR (recipient_id IS NULL AND recipient_role = $2)
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.Query(query, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		err := rows.Scan(
+			&n.ID,
+			&n.ReimbursementID,
+			&n.RecipientID,
+			&n.RecipientRole,
+			&n.Kind,
+			&n.Message,
+			&n.StepOrder,
+			&n.StepEnteredAt,
+			&n.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
+func insertNotification(q queryRower, n *models.Notification) (bool, error) {
+	query := `
+		INSERT INTO reimbursement_notifications (reimbursement_id, recipient_id, recipient_role, kind, message, step_order, step_entered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (reimbursement_id, kind, step_order, step_entered_at) DO NOTHING
+		RETURNING id, created_at
+	`
+	err := q.QueryRow(
+		query,
+		n.ReimbursementID,
+		n.RecipientID,
+		n.RecipientRole,
+		n.Kind,
+		n.Message,
+		n.StepOrder,
+		n.StepEnteredAt,
+	).Scan(&n.ID, &n.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"reimbursement-backend/internal/models"
+)
+
+type PaymentRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRepository(db *sql.DB) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+// MarkPaid records a payment and moves the reimbursement to completed in one
+// transaction. The paid amount is taken from the reimbursement itself: the
+// approved amount if an approver lowered it, the claimed amount otherwise.
+// Claims filed against a cash advance are completed by settling the advance
+// instead, and fail here with ErrStatusConflict.
+func (r *PaymentRepository) MarkPaid(payment *models.Payment, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(`
+			UPDATE reimbursements
+			SET status = $1
+			WHERE id = $2 AND status = $3 AND advance_id IS NULL
+			RETURNING COALESCE(approved_amount, amount)
+		`, models.StatusCompleted, payment.ReimbursementID, models.StatusApprovedFinance).Scan(&payment.Amount)
+		if err == sql.ErrNoRows {
+			return ErrStatusConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(`
+			INSERT INTO reimbursement_payments (reimbursement_id, amount, method, reference, payment_date, paid_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, created_at
+		`,
+			payment.ReimbursementID,
+			payment.Amount,
+			payment.Method,
+			payment.Reference,
+			payment.PaymentDate,
+			payment.PaidBy,
+		).Scan(&payment.ID, &payment.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		notes := fmt.Sprintf("%s %s", payment.Method, payment.Reference)
+		return insertEvent(tx, payment.ReimbursementID, models.EventPaid, actor, models.StatusApprovedFinance, models.StatusCompleted, &notes)
+	})
+}
+
+// Reverse marks the active payment of a completed reimbursement as reversed
+// (for example a bounced transfer) and moves the claim back to
+// approved_finance so it can be paid again.
+func (r *PaymentRepository) Reverse(reimbursementID int, reason string, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE reimbursements SET status = $1 WHERE id = $2 AND status = $3
+		`, models.StatusApprovedFinance, reimbursementID, models.StatusCompleted)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+
+		result, err = tx.Exec(`
+			UPDATE reimbursement_payments
+			SET reversed_at = $1, reversed_by = $2, reversal_reason = $3
+			WHERE reimbursement_id = $4 AND reversed_at IS NULL
+		`, time.Now(), actor.UserID, reason, reimbursementID)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		return insertEvent(tx, reimbursementID, models.EventPaymentReversed, actor, models.StatusCompleted, models.StatusApprovedFinance, &reason)
+	})
+}
+
+// GetByReimbursementID returns every payment made for a reimbursement,
+// including reversed ones, newest first.
+func (r *PaymentRepository) GetByReimbursementID(reimbursementID int) ([]models.Payment, error) {
+	query := `
+		SELECT id, reimbursement_id, amount, method, reference, payment_date, paid_by,
+		       reversed_at, reversed_by, reversal_reason, created_at
+		FROM reimbursement_payments
+		WHERE reimbursement_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(query, reimbursementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []models.Payment
+	for rows.Next() {
+		var p models.Payment
+		err := rows.Scan(
+			&p.ID,
+			&p.ReimbursementID,
+			&p.Amount,
+			&p.Method,
+			&p.Reference,
+			&p.PaymentDate,
+			&p.PaidBy,
+			&p.ReversedAt,
+			&p.ReversedBy,
+			&p.ReversalReason,
+			&p.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"reimbursement-backend/internal/models"
+)
+
+// ErrNoPerDiemRate is returned when a location has no rate in effect on the
+// requested date.
+var ErrNoPerDiemRate = errors.New("no per-diem rate")
+
+const perDiemRateColumns = `
+	id, location, daily_rate, breakfast_percent, lunch_percent, dinner_percent, effective_from, created_by, created_at, updated_at
+`
+
+type PerDiemRateRepository struct {
+	db *sql.DB
+}
+
+func NewPerDiemRateRepository(db *sql.DB) *PerDiemRateRepository {
+	return &PerDiemRateRepository{db: db}
+}
+
+// GetAll returns every per-diem rate, newest first per location.
+func (r *PerDiemRateRepository) GetAll() ([]models.PerDiemRate, error) {
+	rows, err := r.db.Query(`SELECT ` + perDiemRateColumns + ` FROM per_diem_rates ORDER BY LOWER(location), effective_from DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []models.PerDiemRate{}
+	for rows.Next() {
+		rate, err := scanPerDiemRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, *rate)
+	}
+	return rates, nil
+}
+
+// RateOn returns the rate of a location, matched ignoring case, in effect on
+// date: the latest rate effective on or before it.
+func (r *PerDiemRateRepository) RateOn(location string, date time.Time) (*models.PerDiemRate, error) {
+	query := `SELECT ` + perDiemRateColumns + ` FROM per_diem_rates
+		WHERE LOWER(location) = LOWER($1) AND effective_from <= $2
+		ORDER BY effective_from DESC
+		LIMIT 1`
+	rate, err := scanPerDiemRate(r.db.QueryRow(query, location, date))
+	if err == sql.ErrNoRows {
+		return nil, ErrNoPerDiemRate
+	}
+	return rate, err
+}
+
+// Save creates or replaces the rate of a location effective from a date.
+func (r *PerDiemRateRepository) Save(rate *models.PerDiemRate) error {
+	return r.db.QueryRow(`
+		INSERT INTO per_diem_rates (location, daily_rate, breakfast_percent, lunch_percent, dinner_percent, effective_from, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (LOWER(location), effective_from) DO UPDATE
+		SET location = EXCLUDED.location, daily_rate = EXCLUDED.daily_rate, breakfast_percent = EXCLUDED.breakfast_percent,
+		    lunch_percent = EXCLUDED.lunch_percent, dinner_percent = EXCLUDED.dinner_percent,
+		    created_by = EXCLUDED.created_by, updated_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at
+	`, rate.Location, rate.DailyRate, rate.BreakfastPercent, rate.LunchPercent, rate.DinnerPercent, rate.EffectiveFrom,
+		rate.CreatedBy).Scan(&rate.ID, &rate.CreatedAt, &rate.UpdatedAt)
+}
+
+func (r *PerDiemRateRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM per_diem_rates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("per-diem rate not found")
+	}
+	return nil
+}
+
+func scanPerDiemRate(row rowScanner) (*models.PerDiemRate, error) {
+	var rate models.PerDiemRate
+	err := row.Scan(
+		&rate.ID,
+		&rate.Location,
+		&rate.DailyRate,
+		&rate.BreakfastPercent,
+		&rate.LunchPercent,
+		&rate.DinnerPercent,
+		&rate.EffectiveFrom,
+		&rate.CreatedBy,
+		&rate.CreatedAt,
+		&rate.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
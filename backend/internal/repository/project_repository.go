@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"reimbursement-backend/internal/models"
+)
+
+const projectColumns = `
+	id, code, name, client, cost_center_id, active, created_at, updated_at
+`
+
+// ErrProjectExists is returned when creating a project whose code is taken.
+var ErrProjectExists = errors.New("project already exists")
+
+type ProjectRepository struct {
+	db *sql.DB
+}
+
+func NewProjectRepository(db *sql.DB) *ProjectRepository {
+	return &ProjectRepository{db: db}
+}
+
+// GetAll returns the active projects, or every project if includeInactive,
+// by code.
+func (r *ProjectRepository) GetAll(includeInactive bool) ([]models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE active OR $1 ORDER BY active DESC, code`
+	rows, err := r.db.Query(query, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, *project)
+	}
+	return projects, nil
+}
+
+func (r *ProjectRepository) GetByID(id int) (*models.Project, error) {
+	project, err := scanProject(r.db.QueryRow(`SELECT `+projectColumns+` FROM projects WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("project not found")
+	}
+	return project, err
+}
+
+// Create adds a project, or returns ErrProjectExists if the code is taken.
+func (r *ProjectRepository) Create(project *models.Project) error {
+	err := r.db.QueryRow(`
+		INSERT INTO projects (code, name, client, cost_center_id, active)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING id, created_at, updated_at
+	`, project.Code, project.Name, project.Client, project.CostCenterID, project.Active).Scan(&project.ID, &project.CreatedAt, &project.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrProjectExists
+	}
+	return err
+}
+
+func (r *ProjectRepository) Update(project *models.Project) error {
+	return r.db.QueryRow(`
+		UPDATE projects
+		SET name = $1, client = $2, cost_center_id = $3, active = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5
+		RETURNING updated_at
+	`, project.Name, project.Client, project.CostCenterID, project.Active, project.ID).Scan(&project.UpdatedAt)
+}
+
+func scanProject(row rowScanner) (*models.Project, error) {
+	var project models.Project
+	err := row.Scan(
+		&project.ID,
+		&project.Code,
+		&project.Name,
+		&project.Client,
+		&project.CostCenterID,
+		&project.Active,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
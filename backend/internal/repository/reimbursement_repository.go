@@ -2,12 +2,55 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"reimbursement-backend/internal/models"
 )
 
+// ErrStatusConflict is returned by guarded writes when the reimbursement is
+// no longer in the status the caller expected, usually because another user
+// acted on it first.
+var ErrStatusConflict = errors.New("reimbursement status has changed")
+
+// reimbursementColumns is the column list scanned by scanReimbursement. Queries
+// using it must alias the reimbursements table as r.
+const reimbursementColumns = `
+	r.id, r.employee_id, r.employee_name, r.name, r.title, r.description, COALESCE(r.category, ''), r.amount, r.currency, r.original_amount,
+	r.exchange_rate, r.approved_amount,
+	r.amount_adjustment_reason, r.receipt_url,
+	r.status, r.expense_date, r.submitted_date, r.late, r.late_reason, r.approval_chain_id, r.current_step, r.current_approver_id, r.revision_count, r.version,
+	r.step_entered_at, r.overdue, r.cancel_reason, r.cancelled_at,
+	r.manager_id, r.manager_on_behalf_of_id, r.manager_notes, r.manager_approved,
+	r.finance_id, r.finance_on_behalf_of_id, r.finance_notes, r.finance_approved, r.created_at, r.updated_at, r.draft,
+	r.advance_id
+`
+
+// managerScope limits a query on reimbursements r to the claims manager $1
+// may see: claims submitted by anyone in their reporting subtree, claims
+// assigned to or decided by them (including through an active delegation),
+// and unassigned claims waiting on a manager step. Drafts are never seen.
+const managerScope = `r.status <> 'draft' AND (
+	r.employee_id IN (
+		WITH RECURSIVE team AS (
+			SELECT id FROM users WHERE manager_id = $1
+			UNION
+			SELECT u.id FROM users u JOIN team t ON u.manager_id = t.id
+		)
+		SELECT id FROM team
+	)
+	OR r.current_approver_id = $1
+	OR r.current_approver_id IN (SELECT delegator_id FROM delegations WHERE delegate_id = $1 AND ` + activeDelegation + `)
+	OR EXISTS (SELECT 1 FROM reimbursement_approvals a WHERE a.reimbursement_id = r.id AND a.approver_id = $1)
+	OR (r.current_approver_id IS NULL AND r.status IN ('pending', 'approved_manager') AND EXISTS (
+		SELECT 1 FROM approval_chain_steps s
+		WHERE s.chain_id = r.approval_chain_id AND s.step_order = r.current_step AND s.required_role = 'manager'
+	))
+)`
+
 type ReimbursementRepository struct {
 	db *sql.DB
 }
@@ -16,56 +59,62 @@ func NewReimbursementRepository(db *sql.DB) *ReimbursementRepository {
 	return &ReimbursementRepository{db: db}
 }
 
-func (r *ReimbursementRepository) Create(reimb *models.Reimbursement) error {
+// Create stores a new reimbursement with its lines, allocations, first
+// version and created event. It fails with ErrAdvanceNotOpen if the claim is
+// filed against a cash advance that is not open for claims.
+func (r *ReimbursementRepository) Create(reimb *models.Reimbursement, actor models.Actor) error {
 	query := `
-		INSERT INTO reimbursements (employee_id, employee_name, name, title, description, category, amount, receipt_url, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id, submitted_date, created_at, updated_at
+		INSERT INTO reimbursements (employee_id, employee_name, name, title, description, category, amount, currency, original_amount,
+		                            exchange_rate, receipt_url, status, approval_chain_id, current_step, current_approver_id,
+		                            expense_date, late, late_reason, advance_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING id, version, submitted_date, created_at, updated_at
 	`
-	return r.db.QueryRow(
-		query,
-		reimb.EmployeeID,
-		reimb.EmployeeName,
-		reimb.Name,
-		reimb.Title,
-		reimb.Description,
-		reimb.Category,
-		reimb.Amount,
-		reimb.ReceiptURL,
-		reimb.Status,
-	).Scan(&reimb.ID, &reimb.SubmittedDate, &reimb.CreatedAt, &reimb.UpdatedAt)
+	return withTx(r.db, func(tx *sql.Tx) error {
+		if err := lockOpenAdvance(tx, reimb.AdvanceID, reimb.EmployeeID); err != nil {
+			return err
+		}
+		err := tx.QueryRow(
+			query,
+			reimb.EmployeeID,
+			reimb.EmployeeName,
+			reimb.Name,
+			reimb.Title,
+			reimb.Description,
+			reimb.Category,
+			reimb.Amount,
+			reimb.Currency,
+			reimb.OriginalAmount,
+			reimb.ExchangeRate,
+			reimb.ReceiptURL,
+			reimb.Status,
+			reimb.ApprovalChainID,
+			reimb.CurrentStep,
+			reimb.CurrentApproverID,
+			reimb.ExpenseDate,
+			reimb.Late,
+			reimb.LateReason,
+			reimb.AdvanceID,
+		).Scan(&reimb.ID, &reimb.Version, &reimb.SubmittedDate, &reimb.CreatedAt, &reimb.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := insertLines(tx, reimb); err != nil {
+			return err
+		}
+		if err := insertAllocations(tx, reimb); err != nil {
+			return err
+		}
+		if err := insertVersion(tx, reimb, actor); err != nil {
+			return err
+		}
+		return insertEvent(tx, reimb.ID, models.EventCreated, actor, "", reimb.Status, nil)
+	})
 }
 
 func (r *ReimbursementRepository) GetByID(id int) (*models.Reimbursement, error) {
-	reimb := &models.Reimbursement{}
-	query := `
-		SELECT id, employee_id, employee_name, name, title, description, category, amount, receipt_url, 
-		       status, submitted_date, manager_id, manager_notes, manager_approved, 
-		       finance_id, finance_notes, finance_approved, created_at, updated_at
-		FROM reimbursements
-		WHERE id = $1
-	`
-	err := r.db.QueryRow(query, id).Scan(
-		&reimb.ID,
-		&reimb.EmployeeID,
-		&reimb.EmployeeName,
-		&reimb.Name,
-		&reimb.Title,
-		&reimb.Description,
-		&reimb.Category,
-		&reimb.Amount,
-		&reimb.ReceiptURL,
-		&reimb.Status,
-		&reimb.SubmittedDate,
-		&reimb.ManagerID,
-		&reimb.ManagerNotes,
-		&reimb.ManagerApproved,
-		&reimb.FinanceID,
-		&reimb.FinanceNotes,
-		&reimb.FinanceApproved,
-		&reimb.CreatedAt,
-		&reimb.UpdatedAt,
-	)
+	query := `SELECT ` + reimbursementColumns + ` FROM reimbursements r WHERE r.id = $1`
+	reimb, err := scanReimbursement(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("reimbursement not found")
@@ -75,148 +124,764 @@ func (r *ReimbursementRepository) GetByID(id int) (*models.Reimbursement, error)
 	return reimb, nil
 }
 
-func (r *ReimbursementRepository) GetAll() ([]models.Reimbursement, error) {
+// GetAll returns every submitted reimbursement with an expense date in
+// period, leaving out drafts.
+func (r *ReimbursementRepository) GetAll(period models.ExpensePeriod) ([]models.Reimbursement, error) {
 	query := `
-		SELECT id, employee_id, employee_name, name, title, description, category, amount, receipt_url, 
-		       status, submitted_date, manager_id, manager_notes, manager_approved, 
-		       finance_id, finance_notes, finance_approved, created_at, updated_at
-		FROM reimbursements
-		ORDER BY submitted_date DESC
+		SELECT ` + reimbursementColumns + `
+		FROM reimbursements r
+		WHERE r.status <> 'draft'
+		  AND ($1::date IS NULL OR r.expense_date >= $1) AND ($2::date IS NULL OR r.expense_date < $2)
+		ORDER BY r.submitted_date DESC
 	`
-	return r.queryReimbursements(query)
+	return r.queryReimbursements(query, period.From, period.To)
 }
 
-func (r *ReimbursementRepository) GetByEmployeeID(employeeID int) ([]models.Reimbursement, error) {
+// GetVisibleToManager returns the reimbursements a manager may see, see
+// managerScope, with an expense date in period.
+func (r *ReimbursementRepository) GetVisibleToManager(managerID int, period models.ExpensePeriod) ([]models.Reimbursement, error) {
 	query := `
-		SELECT id, employee_id, employee_name, name, title, description, category, amount, receipt_url, 
-		       status, submitted_date, manager_id, manager_notes, manager_approved, 
-		       finance_id, finance_notes, finance_approved, created_at, updated_at
-		FROM reimbursements
-		WHERE employee_id = $1
-		ORDER BY submitted_date DESC
+		SELECT ` + reimbursementColumns + `
+		FROM reimbursements r
+		WHERE ` + managerScope + `
+		  AND ($2::date IS NULL OR r.expense_date >= $2) AND ($3::date IS NULL OR r.expense_date < $3)
+		ORDER BY r.submitted_date DESC
 	`
-	return r.queryReimbursements(query, employeeID)
+	return r.queryReimbursements(query, managerID, period.From, period.To)
+}
+
+// IsVisibleToManager reports whether a manager may see a reimbursement.
+func (r *ReimbursementRepository) IsVisibleToManager(id, managerID int) (bool, error) {
+	var visible bool
+	query := `SELECT EXISTS (SELECT 1 FROM reimbursements r WHERE ` + managerScope + ` AND r.id = $2)`
+	err := r.db.QueryRow(query, managerID, id).Scan(&visible)
+	return visible, err
+}
+
+// GetByEmployeeID returns the reimbursements of an employee with an expense
+// date in period.
+func (r *ReimbursementRepository) GetByEmployeeID(employeeID int, period models.ExpensePeriod) ([]models.Reimbursement, error) {
+	query := `
+		SELECT ` + reimbursementColumns + `
+		FROM reimbursements r
+		WHERE r.employee_id = $1
+		  AND ($2::date IS NULL OR r.expense_date >= $2) AND ($3::date IS NULL OR r.expense_date < $3)
+		ORDER BY r.submitted_date DESC
+	`
+	return r.queryReimbursements(query, employeeID, period.From, period.To)
 }
 
 func (r *ReimbursementRepository) GetByStatus(status models.ReimbursementStatus) ([]models.Reimbursement, error) {
 	query := `
-		SELECT id, employee_id, employee_name, name, title, description, category, amount, receipt_url, 
-		       status, submitted_date, manager_id, manager_notes, manager_approved, 
-		       finance_id, finance_notes, finance_approved, created_at, updated_at
-		FROM reimbursements
-		WHERE status = $1
-		ORDER BY submitted_date DESC
+		SELECT ` + reimbursementColumns + `
+		FROM reimbursements r
+		WHERE r.status = $1
+		ORDER BY r.submitted_date DESC
 	`
 	return r.queryReimbursements(query, status)
 }
 
-func (r *ReimbursementRepository) Update(reimb *models.Reimbursement) error {
+// GetPendingForApprover returns the reimbursements whose current approval
+// step is assigned to the given user or to someone who delegated to them
+// today, or is unassigned and open to anyone with their role. A non-nil
+// overdue keeps only the claims whose overdue flag matches it.
+func (r *ReimbursementRepository) GetPendingForApprover(userID int, role models.UserRole, overdue *bool) ([]models.Reimbursement, error) {
 	query := `
-		UPDATE reimbursements
-		SET name = $1, title = $2, description = $3, category = $4, amount = $5, receipt_url = $6
-		WHERE id = $7
-		RETURNING updated_at
+		SELECT ` + reimbursementColumns + `
+		FROM reimbursements r
+		JOIN approval_chain_steps s ON s.chain_id = r.approval_chain_id AND s.step_order = r.current_step
+		WHERE r.status IN ($1, $2)
+		  AND (
+			r.current_approver_id = $3
+			OR r.current_approver_id IN (SELECT delegator_id FROM delegations WHERE delegate_id = $3 AND ` + activeDelegation + `)
+			OR (r.current_approver_id IS NULL AND s.required_role = $4)
+		  )
+		  AND ($5::boolean IS NULL OR r.overdue = $5)
+		ORDER BY r.submitted_date DESC
 	`
-	return r.db.QueryRow(
-		query,
-		reimb.Name,
-		reimb.Title,
-		reimb.Description,
-		reimb.Category,
-		reimb.Amount,
-		reimb.ReceiptURL,
-		reimb.ID,
-	).Scan(&reimb.UpdatedAt)
+	return r.queryReimbursements(query, models.StatusPending, models.StatusApprovedManager, userID, role, overdue)
 }
 
-func (r *ReimbursementRepository) UpdateStatus(id int, status models.ReimbursementStatus) error {
-	query := `UPDATE reimbursements SET status = $1 WHERE id = $2`
-	_, err := r.db.Exec(query, status, id)
-	return err
+// GetWaitingLongerThan returns the reimbursements in status that entered
+// their current step at least the given number of hours ago and are not
+// already overdue. The database clock is used so every API instance agrees.
+func (r *ReimbursementRepository) GetWaitingLongerThan(status models.ReimbursementStatus, hours int) ([]models.Reimbursement, error) {
+	query := `
+		SELECT ` + reimbursementColumns + `
+		FROM reimbursements r
+		WHERE r.status = $1 AND NOT r.overdue
+		  AND r.step_entered_at <= LOCALTIMESTAMP - make_interval(hours => $2)
+		ORDER BY r.step_entered_at
+	`
+	return r.queryReimbursements(query, status, hours)
 }
 
-func (r *ReimbursementRepository) ApproveByManager(id, managerID int, notes *string) error {
+// Update saves the submitter's changes to a reimbursement as its next
+// version. It is guarded by reimb.Status and reimb.Version, the status and
+// version the claim had when it was read, so concurrent edits cannot
+// overwrite each other. If reimb.Lines is set it replaces all lines, which
+// start over as pending, and if reimb.Allocations is set it replaces the
+// allocations.
+func (r *ReimbursementRepository) Update(reimb *models.Reimbursement, actor models.Actor) error {
 	query := `
 		UPDATE reimbursements
-		SET status = $1, manager_id = $2, manager_notes = $3, manager_approved = $4
-		WHERE id = $5
+		SET name = $1, title = $2, description = $3, category = $4, amount = $5, receipt_url = $6,
+		    approval_chain_id = $7, current_approver_id = $8, approved_amount = $9, amount_adjustment_reason = $10,
+		    currency = $14, original_amount = $15, exchange_rate = $16, expense_date = $17, late = $18, late_reason = $19,
+		    version = version + 1
+		WHERE id = $11 AND status = $12 AND version = $13
+		RETURNING version, updated_at
 	`
-	_, err := r.db.Exec(query, models.StatusApprovedManager, managerID, notes, time.Now(), id)
-	return err
+	return withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(
+			query,
+			reimb.Name,
+			reimb.Title,
+			reimb.Description,
+			reimb.Category,
+			reimb.Amount,
+			reimb.ReceiptURL,
+			reimb.ApprovalChainID,
+			reimb.CurrentApproverID,
+			reimb.ApprovedAmount,
+			reimb.AmountAdjustmentReason,
+			reimb.ID,
+			reimb.Status,
+			reimb.Version,
+			reimb.Currency,
+			reimb.OriginalAmount,
+			reimb.ExchangeRate,
+			reimb.ExpenseDate,
+			reimb.Late,
+			reimb.LateReason,
+		).Scan(&reimb.Version, &reimb.UpdatedAt)
+		if err == sql.ErrNoRows {
+			return ErrStatusConflict
+		}
+		if err != nil {
+			return err
+		}
+		if reimb.Lines != nil {
+			if _, err := tx.Exec(`DELETE FROM reimbursement_lines WHERE reimbursement_id = $1`, reimb.ID); err != nil {
+				return err
+			}
+			if err := insertLines(tx, reimb); err != nil {
+				return err
+			}
+		}
+		if reimb.Allocations != nil {
+			if _, err := tx.Exec(`DELETE FROM reimbursement_allocations WHERE reimbursement_id = $1`, reimb.ID); err != nil {
+				return err
+			}
+			if err := insertAllocations(tx, reimb); err != nil {
+				return err
+			}
+		}
+		if err := insertVersion(tx, reimb, actor); err != nil {
+			return err
+		}
+		notes := fmt.Sprintf("Version %d", reimb.Version)
+		return insertEvent(tx, reimb.ID, models.EventUpdated, actor, reimb.Status, reimb.Status, &notes)
+	})
 }
 
-func (r *ReimbursementRepository) RejectByManager(id, managerID int, notes *string) error {
+// CreateDraft stores a new draft with its created event. Only the name,
+// title and description of the draft are kept in their columns; the rest
+// stays in reimb.Draft until it is submitted.
+func (r *ReimbursementRepository) CreateDraft(reimb *models.Reimbursement, actor models.Actor) error {
+	draft, err := json.Marshal(reimb.Draft)
+	if err != nil {
+		return err
+	}
 	query := `
-		UPDATE reimbursements
-		SET status = $1, manager_id = $2, manager_notes = $3, manager_approved = $4
-		WHERE id = $5
+		INSERT INTO reimbursements (employee_id, employee_name, name, title, description, amount, receipt_url, status, draft)
+		VALUES ($1, $2, $3, $4, $5, 0, '', $6, $7)
+		RETURNING id, version, submitted_date, created_at, updated_at
 	`
-	_, err := r.db.Exec(query, models.StatusRejectedManager, managerID, notes, time.Now(), id)
-	return err
+	return withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, reimb.EmployeeID, reimb.EmployeeName, reimb.Name, reimb.Title, reimb.Description, reimb.Status, draft).
+			Scan(&reimb.ID, &reimb.Version, &reimb.SubmittedDate, &reimb.CreatedAt, &reimb.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return insertEvent(tx, reimb.ID, models.EventCreated, actor, "", reimb.Status, nil)
+	})
 }
 
-func (r *ReimbursementRepository) ApproveByFinance(id, financeID int, notes *string) error {
+// UpdateDraft replaces a draft with reimb.Draft. It fails with
+// ErrStatusConflict if the reimbursement is no longer a draft.
+func (r *ReimbursementRepository) UpdateDraft(reimb *models.Reimbursement, actor models.Actor) error {
+	draft, err := json.Marshal(reimb.Draft)
+	if err != nil {
+		return err
+	}
 	query := `
 		UPDATE reimbursements
-		SET status = $1, finance_id = $2, finance_notes = $3, finance_approved = $4
-		WHERE id = $5
+		SET employee_name = $1, name = $2, title = $3, description = $4, draft = $5
+		WHERE id = $6 AND status = $7
+		RETURNING updated_at
 	`
-	_, err := r.db.Exec(query, models.StatusApprovedFinance, financeID, notes, time.Now(), id)
-	return err
+	return withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(query, reimb.EmployeeName, reimb.Name, reimb.Title, reimb.Description, draft, reimb.ID, models.StatusDraft).
+			Scan(&reimb.UpdatedAt)
+		if err == sql.ErrNoRows {
+			return ErrStatusConflict
+		}
+		if err != nil {
+			return err
+		}
+		return insertEvent(tx, reimb.ID, models.EventUpdated, actor, models.StatusDraft, models.StatusDraft, nil)
+	})
 }
 
-func (r *ReimbursementRepository) RejectByFinance(id, financeID int, notes *string) error {
+// Submit moves a draft into approval as the claim built from it, storing
+// its fields, lines, allocations and first version as Create does. The
+// draft is cleared and the claim counts as submitted now. It fails with
+// ErrStatusConflict if the reimbursement is no longer a draft, and with
+// ErrAdvanceNotOpen as Create does.
+func (r *ReimbursementRepository) Submit(reimb *models.Reimbursement, actor models.Actor) error {
 	query := `
 		UPDATE reimbursements
-		SET status = $1, finance_id = $2, finance_notes = $3, finance_approved = $4
-		WHERE id = $5
+		SET employee_name = $1, name = $2, title = $3, description = $4, category = $5, amount = $6, currency = $7,
+		    original_amount = $8, exchange_rate = $9, receipt_url = $10, status = $11, approval_chain_id = $12,
+		    current_step = $13, current_approver_id = $14, expense_date = $15, late = $16, late_reason = $17,
+		    advance_id = $20, draft = NULL, submitted_date = CURRENT_TIMESTAMP, step_entered_at = CURRENT_TIMESTAMP
+		WHERE id = $18 AND status = $19
+		RETURNING version, submitted_date, step_entered_at, updated_at
 	`
-	_, err := r.db.Exec(query, models.StatusRejectedFinance, financeID, notes, time.Now(), id)
-	return err
+	return withTx(r.db, func(tx *sql.Tx) error {
+		if err := lockOpenAdvance(tx, reimb.AdvanceID, reimb.EmployeeID); err != nil {
+			return err
+		}
+		err := tx.QueryRow(
+			query,
+			reimb.EmployeeName,
+			reimb.Name,
+			reimb.Title,
+			reimb.Description,
+			reimb.Category,
+			reimb.Amount,
+			reimb.Currency,
+			reimb.OriginalAmount,
+			reimb.ExchangeRate,
+			reimb.ReceiptURL,
+			reimb.Status,
+			reimb.ApprovalChainID,
+			reimb.CurrentStep,
+			reimb.CurrentApproverID,
+			reimb.ExpenseDate,
+			reimb.Late,
+			reimb.LateReason,
+			reimb.ID,
+			models.StatusDraft,
+			reimb.AdvanceID,
+		).Scan(&reimb.Version, &reimb.SubmittedDate, &reimb.StepEnteredAt, &reimb.UpdatedAt)
+		if err == sql.ErrNoRows {
+			return ErrStatusConflict
+		}
+		if err != nil {
+			return err
+		}
+		if err := insertLines(tx, reimb); err != nil {
+			return err
+		}
+		if err := insertAllocations(tx, reimb); err != nil {
+			return err
+		}
+		if err := insertVersion(tx, reimb, actor); err != nil {
+			return err
+		}
+		return insertEvent(tx, reimb.ID, models.EventSubmitted, actor, models.StatusDraft, reimb.Status, nil)
+	})
+}
+
+// DeleteDraft removes a draft its submitter has discarded. Its history is
+// kept and ends with a purged event.
+func (r *ReimbursementRepository) DeleteDraft(id int, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM reimbursements WHERE id = $1 AND status = $2`, id, models.StatusDraft)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		return insertEvent(tx, id, models.EventPurged, actor, models.StatusDraft, "", nil)
+	})
+}
+
+// DeleteStaleDrafts removes the drafts last saved more than days days ago,
+// each with a purged event, and returns how many it removed.
+func (r *ReimbursementRepository) DeleteStaleDrafts(days int) (int, error) {
+	var ids []int
+	err := withTx(r.db, func(tx *sql.Tx) error {
+		rows, err := tx.Query(`
+			DELETE FROM reimbursements
+			WHERE status = $1 AND updated_at < CURRENT_TIMESTAMP - make_interval(days => $2)
+			RETURNING id
+		`, models.StatusDraft, days)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		notes := fmt.Sprintf("Draft not submitted within %d days", days)
+		for _, id := range ids {
+			if err := insertEvent(tx, id, models.EventPurged, models.Actor{}, models.StatusDraft, "", &notes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(ids), err
+}
+
+// Resubmit moves a reimbursement returned for revision back into approval
+// and restarts its SLA timer. It fails with ErrStatusConflict if the
+// reimbursement is no longer in status from.
+func (r *ReimbursementRepository) Resubmit(id int, from, to models.ReimbursementStatus, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE reimbursements
+			SET status = $1, step_entered_at = LOCALTIMESTAMP, overdue = FALSE
+			WHERE id = $2 AND status = $3
+		`, to, id, from)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		return insertEvent(tx, id, models.EventResubmitted, actor, from, to, nil)
+	})
 }
 
-func (r *ReimbursementRepository) Delete(id int) error {
-	query := `DELETE FROM reimbursements WHERE id = $1`
-	_, err := r.db.Exec(query, id)
-	return err
+// DecideStep applies an approver's decision on the current step of a
+// reimbursement and records it, in one transaction. The update is guarded by
+// the expected status, step and version, so when two approvers act at once,
+// or the submitter edits the claim meanwhile, only the first write wins and
+// the other gets ErrStatusConflict.
+//
+// Decisions on manager and finance steps are also copied to the legacy
+// manager_* and finance_* columns. Returning a claim for revision is not a
+// decision on the step, so it only counts the revision. An approval for a
+// lower amount also stores the approved amount and its justification. Line
+// decisions are applied to the lines, and the final approval accepts every
+// line still pending.
+func (r *ReimbursementRepository) DecideStep(d *models.ApprovalDecision) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		now := time.Now()
+
+		args := []interface{}{d.ToStatus, d.NextStep, d.ReimbursementID, d.FromStatus, d.Step.StepOrder, d.NextApproverID, d.Version}
+		arg := func(v interface{}) string {
+			args = append(args, v)
+			return "$" + strconv.Itoa(len(args))
+		}
+
+		set := ``
+		if d.ToStatus == models.StatusNeedsRevision {
+			set = `, revision_count = revision_count + 1`
+		} else if d.Step.RequiredRole != nil {
+			switch *d.Step.RequiredRole {
+			case models.RoleManager:
+				set = `, manager_id = ` + arg(d.ApproverID) + `, manager_on_behalf_of_id = ` + arg(d.OnBehalfOfID) +
+					`, manager_notes = ` + arg(d.Notes) + `, manager_approved = ` + arg(now)
+			case models.RoleFinance:
+				set = `, finance_id = ` + arg(d.ApproverID) + `, finance_on_behalf_of_id = ` + arg(d.OnBehalfOfID) +
+					`, finance_notes = ` + arg(d.Notes) + `, finance_approved = ` + arg(now)
+			}
+		}
+		if d.ApprovedAmount != nil {
+			set += `, approved_amount = ` + arg(*d.ApprovedAmount) + `, amount_adjustment_reason = ` + arg(d.AdjustmentReason)
+		}
+
+		result, err := tx.Exec(`
+			UPDATE reimbursements
+			SET status = $1, current_step = $2, current_approver_id = $6,
+			    step_entered_at = LOCALTIMESTAMP, overdue = FALSE`+set+`
+			WHERE id = $3 AND status = $4 AND current_step = $5 AND version = $7
+		`, args...)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+
+		for _, ld := range d.LineDecisions {
+			status := models.LineAccepted
+			if ld.Action == "reject" {
+				status = models.LineRejected
+			}
+			result, err := tx.Exec(`
+				UPDATE reimbursement_lines
+				SET status = $1, rejection_reason = $2, decided_by = $3, decided_at = $4
+				WHERE id = $5 AND reimbursement_id = $6 AND status <> 'rejected'
+			`, status, ld.Reason, d.ApproverID, now, ld.ID, d.ReimbursementID)
+			if err != nil {
+				return err
+			}
+			if err := expectAffected(result); err != nil {
+				return err
+			}
+		}
+		if d.ToStatus == models.StatusApprovedFinance {
+			_, err := tx.Exec(`
+				UPDATE reimbursement_lines
+				SET status = 'accepted', decided_by = $1, decided_at = $2
+				WHERE reimbursement_id = $3 AND status = 'pending'
+			`, d.ApproverID, now, d.ReimbursementID)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO reimbursement_approvals (reimbursement_id, step_order, step_name, approver_id, on_behalf_of_id, action, notes,
+			                                     approved_amount, adjustment_reason, version, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`, d.ReimbursementID, d.Step.StepOrder, d.Step.Name, d.ApproverID, d.OnBehalfOfID, d.Action, d.Notes,
+			d.ApprovedAmount, d.AdjustmentReason, d.Version, now)
+		if err != nil {
+			return err
+		}
+
+		action := models.EventApproved
+		switch d.Action {
+		case "reject":
+			action = models.EventRejected
+		case "revise":
+			action = models.EventRevisionAsked
+		}
+		return insertEvent(tx, d.ReimbursementID, action, d.Actor, d.FromStatus, d.ToStatus, d.Notes)
+	})
+}
+
+// Escalate hands the current step of a reimbursement to another approver
+// and records the notification, in one transaction. The SLA timer restarts
+// for the new approver. The update is guarded by the status, step and
+// step_entered_at the claim was read with, so a claim decided or escalated
+// in the meantime fails with ErrStatusConflict.
+func (r *ReimbursementRepository) Escalate(reimb *models.Reimbursement, approverID int, n *models.Notification) error {
+	notes := n.Message
+	return withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE reimbursements
+			SET current_approver_id = $1, step_entered_at = LOCALTIMESTAMP
+			WHERE id = $2 AND status = $3 AND current_step = $4 AND step_entered_at = $5
+		`, approverID, reimb.ID, reimb.Status, reimb.CurrentStep, reimb.StepEnteredAt)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		if _, err := insertNotification(tx, n); err != nil {
+			return err
+		}
+		return insertEvent(tx, reimb.ID, models.EventEscalated, models.Actor{}, reimb.Status, reimb.Status, &notes)
+	})
 }
 
+// MarkOverdue flags a reimbursement whose escalation deadline passed with
+// nobody to escalate to, and records the notification, in one transaction.
+// It is guarded like Escalate.
+func (r *ReimbursementRepository) MarkOverdue(reimb *models.Reimbursement, n *models.Notification) error {
+	notes := n.Message
+	return withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE reimbursements
+			SET overdue = TRUE
+			WHERE id = $1 AND status = $2 AND current_step = $3 AND step_entered_at = $4 AND NOT overdue
+		`, reimb.ID, reimb.Status, reimb.CurrentStep, reimb.StepEnteredAt)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		if _, err := insertNotification(tx, n); err != nil {
+			return err
+		}
+		return insertEvent(tx, reimb.ID, models.EventOverdue, models.Actor{}, reimb.Status, reimb.Status, &notes)
+	})
+}
+
+// GetApprovals returns the decisions recorded for a reimbursement in the
+// order they were made.
+func (r *ReimbursementRepository) GetApprovals(reimbursementID int) ([]models.Approval, error) {
+	query := `
+		SELECT id, reimbursement_id, step_order, step_name, approver_id, on_behalf_of_id, action, notes,
+		       approved_amount, adjustment_reason, version, created_at
+		FROM reimbursement_approvals
+		WHERE reimbursement_id = $1
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(query, reimbursementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var approvals []models.Approval
+	for rows.Next() {
+		var a models.Approval
+		err := rows.Scan(
+			&a.ID,
+			&a.ReimbursementID,
+			&a.StepOrder,
+			&a.StepName,
+			&a.ApproverID,
+			&a.OnBehalfOfID,
+			&a.Action,
+			&a.Notes,
+			&a.ApprovedAmount,
+			&a.AdjustmentReason,
+			&a.Version,
+			&a.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, nil
+}
+
+// GetLines returns the lines of a reimbursement in order.
+func (r *ReimbursementRepository) GetLines(reimbursementID int) ([]models.ReimbursementLine, error) {
+	query := `
+		SELECT id, reimbursement_id, line_no, category, description, amount, currency, original_amount, exchange_rate,
+		       expense_date, receipt_url, status, rejection_reason, decided_by, decided_at,
+		       net_amount, tax_rate, tax_amount, seller_tax_id, tax_invoice_number,
+		       origin, destination, distance_km, vehicle_type, mileage_rate, to_char(trip_start, 'YYYY-MM-DD'),
+		       to_char(trip_end, 'YYYY-MM-DD'), location, provided_meals, per_diem_breakdown
+		FROM reimbursement_lines
+		WHERE reimbursement_id = $1
+		ORDER BY line_no
+	`
+	rows, err := r.db.Query(query, reimbursementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []models.ReimbursementLine
+	for rows.Next() {
+		var l models.ReimbursementLine
+		err := rows.Scan(
+			&l.ID,
+			&l.ReimbursementID,
+			&l.LineNo,
+			&l.Category,
+			&l.Description,
+			&l.Amount,
+			&l.Currency,
+			&l.OriginalAmount,
+			&l.ExchangeRate,
+			&l.ExpenseDate,
+			&l.ReceiptURL,
+			&l.Status,
+			&l.RejectionReason,
+			&l.DecidedBy,
+			&l.DecidedAt,
+			&l.NetAmount,
+			&l.TaxRate,
+			&l.TaxAmount,
+			&l.SellerTaxID,
+			&l.TaxInvoiceNumber,
+			&l.Origin,
+			&l.Destination,
+			&l.DistanceKm,
+			&l.VehicleType,
+			&l.MileageRate,
+			&l.TripStart,
+			&l.TripEnd,
+			&l.Location,
+			&l.ProvidedMeals,
+			&l.PerDiem,
+		)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// insertLines stores reimb.Lines as pending lines numbered from 1, filling in
+// their IDs.
+func insertLines(tx *sql.Tx, reimb *models.Reimbursement) error {
+	for i := range reimb.Lines {
+		l := &reimb.Lines[i]
+		l.ReimbursementID = reimb.ID
+		l.LineNo = i + 1
+		l.Status = models.LinePending
+		err := tx.QueryRow(`
+			INSERT INTO reimbursement_lines (reimbursement_id, line_no, category, description, amount, currency, original_amount,
+			                                 exchange_rate, expense_date, receipt_url, status, net_amount, tax_rate,
+			                                 tax_amount, seller_tax_id, tax_invoice_number, origin, destination, distance_km,
+			                                 vehicle_type, mileage_rate, trip_start, trip_end, location, provided_meals,
+			                                 per_diem_breakdown)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21,
+			        $22, $23, $24, $25, $26)
+			RETURNING id
+		`, l.ReimbursementID, l.LineNo, l.Category, l.Description, l.Amount, l.Currency, l.OriginalAmount,
+			l.ExchangeRate, l.ExpenseDate, l.ReceiptURL, l.Status, l.NetAmount, l.TaxRate,
+			l.TaxAmount, l.SellerTaxID, l.TaxInvoiceNumber, l.Origin, l.Destination, l.DistanceKm,
+			l.VehicleType, l.MileageRate, l.TripStart, l.TripEnd, l.Location, l.ProvidedMeals,
+			l.PerDiem).Scan(&l.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAllocations returns the allocations of a reimbursement in the order
+// they were given.
+func (r *ReimbursementRepository) GetAllocations(reimbursementID int) ([]models.Allocation, error) {
+	query := `
+		SELECT a.id, a.reimbursement_id, a.cost_center_id, cc.code, a.project_id, p.code, a.percent, a.amount
+		FROM reimbursement_allocations a
+		JOIN cost_centers cc ON cc.id = a.cost_center_id
+		LEFT JOIN projects p ON p.id = a.project_id
+		WHERE a.reimbursement_id = $1
+		ORDER BY a.id
+	`
+	rows, err := r.db.Query(query, reimbursementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var allocations []models.Allocation
+	for rows.Next() {
+		var a models.Allocation
+		err := rows.Scan(
+			&a.ID,
+			&a.ReimbursementID,
+			&a.CostCenterID,
+			&a.CostCenterCode,
+			&a.ProjectID,
+			&a.ProjectCode,
+			&a.Percent,
+			&a.Amount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, a)
+	}
+	return allocations, nil
+}
+
+// insertAllocations stores reimb.Allocations, filling in their IDs.
+func insertAllocations(tx *sql.Tx, reimb *models.Reimbursement) error {
+	for i := range reimb.Allocations {
+		a := &reimb.Allocations[i]
+		a.ReimbursementID = reimb.ID
+		err := tx.QueryRow(`
+			INSERT INTO reimbursement_allocations (reimbursement_id, cost_center_id, project_id, percent, amount)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, a.ReimbursementID, a.CostCenterID, a.ProjectID, a.Percent, a.Amount).Scan(&a.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cancel withdraws a reimbursement that is still in status from, keeping the
+// row with status cancelled.
+func (r *ReimbursementRepository) Cancel(id int, from models.ReimbursementStatus, reason *string, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE reimbursements
+			SET status = $1, cancel_reason = $2, cancelled_at = $3
+			WHERE id = $4 AND status = $5
+		`, models.StatusCancelled, reason, time.Now(), id, from)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		return insertEvent(tx, id, models.EventCancelled, actor, from, models.StatusCancelled, reason)
+	})
+}
+
+// Purge permanently removes a cancelled reimbursement together with its
+// approvals and notifications. Its history is kept and ends with a purged
+// event.
+func (r *ReimbursementRepository) Purge(id int, actor models.Actor) error {
+	return withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM reimbursements WHERE id = $1 AND status = $2`, id, models.StatusCancelled)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		return insertEvent(tx, id, models.EventPurged, actor, models.StatusCancelled, "", nil)
+	})
+}
+
+// GetStats returns statistics over all reimbursements, or those of one
+// employee. Drafts and cancelled claims are not counted.
 func (r *ReimbursementRepository) GetStats(employeeID *int) (*models.ReimbursementStats, error) {
-	stats := &models.ReimbursementStats{}
-	
-	var query string
-	var args []interface{}
-	
 	if employeeID != nil {
-		query = `
-			SELECT 
-				COUNT(*) as total_submitted,
-				COUNT(CASE WHEN status IN ('approved_manager', 'approved_finance', 'completed') THEN 1 END) as total_approved,
-				COUNT(CASE WHEN status IN ('rejected_manager', 'rejected_finance') THEN 1 END) as total_rejected,
-				COUNT(CASE WHEN status = 'pending' THEN 1 END) as total_pending,
-				COALESCE(SUM(amount), 0) as total_amount
-			FROM reimbursements
-			WHERE employee_id = $1
-		`
-		args = append(args, *employeeID)
-	} else {
-		query = `
-			SELECT 
-				COUNT(*) as total_submitted,
-				COUNT(CASE WHEN status IN ('approved_manager', 'approved_finance', 'completed') THEN 1 END) as total_approved,
-				COUNT(CASE WHEN status IN ('rejected_manager', 'rejected_finance') THEN 1 END) as total_rejected,
-				COUNT(CASE WHEN status = 'pending' THEN 1 END) as total_pending,
-				COALESCE(SUM(amount), 0) as total_amount
-			FROM reimbursements
-		`
+		return r.queryStats(`r.employee_id = $1`, *employeeID)
 	}
-	
+	return r.queryStats(`TRUE`)
+}
+
+// GetStatsForManager returns statistics over the reimbursements a manager
+// may see, see managerScope.
+func (r *ReimbursementRepository) GetStatsForManager(managerID int) (*models.ReimbursementStats, error) {
+	return r.queryStats(managerScope, managerID)
+}
+
+// queryStats counts the reimbursements r matching cond, leaving out drafts
+// and cancelled ones.
+func (r *ReimbursementRepository) queryStats(cond string, args ...interface{}) (*models.ReimbursementStats, error) {
+	stats := &models.ReimbursementStats{}
+	query := `
+		SELECT
+			COUNT(*) as total_submitted,
+			COUNT(CASE WHEN r.status IN ('approved_manager', 'approved_finance', 'completed') THEN 1 END) as total_approved,
+			COUNT(CASE WHEN r.status IN ('rejected_manager', 'rejected_finance') THEN 1 END) as total_rejected,
+			COUNT(CASE WHEN r.status = 'pending' THEN 1 END) as total_pending,
+			COUNT(CASE WHEN r.status = 'needs_revision' THEN 1 END) as total_needs_revision,
+			COUNT(CASE WHEN r.status = 'approved_finance' THEN 1 END) as total_awaiting_payment,
+			COUNT(CASE WHEN r.status = 'completed' THEN 1 END) as total_paid,
+			COALESCE(SUM(COALESCE(r.approved_amount, r.amount)), 0) as total_amount,
+			COALESCE(SUM(r.amount), 0) as total_claimed_amount
+		FROM reimbursements r
+		WHERE r.status NOT IN ('draft', 'cancelled') AND ` + cond
+
 	err := r.db.QueryRow(query, args...).Scan(
 		&stats.TotalSubmitted,
 		&stats.TotalApproved,
 		&stats.TotalRejected,
 		&stats.TotalPending,
+		&stats.TotalNeedsRevision,
+		&stats.TotalAwaitingPayment,
+		&stats.TotalPaid,
 		&stats.TotalAmount,
+		&stats.TotalClaimedAmount,
 	)
 	return stats, err
 }
@@ -230,32 +895,87 @@ func (r *ReimbursementRepository) queryReimbursements(query string, args ...inte
 
 	var reimbursements []models.Reimbursement
 	for rows.Next() {
-		var reimb models.Reimbursement
-		err := rows.Scan(
-			&reimb.ID,
-			&reimb.EmployeeID,
-			&reimb.EmployeeName,
-			&reimb.Name,
-			&reimb.Title,
-			&reimb.Description,
-			&reimb.Category,
-			&reimb.Amount,
-			&reimb.ReceiptURL,
-			&reimb.Status,
-			&reimb.SubmittedDate,
-			&reimb.ManagerID,
-			&reimb.ManagerNotes,
-			&reimb.ManagerApproved,
-			&reimb.FinanceID,
-			&reimb.FinanceNotes,
-			&reimb.FinanceApproved,
-			&reimb.CreatedAt,
-			&reimb.UpdatedAt,
-		)
+		reimb, err := scanReimbursement(rows)
 		if err != nil {
 			return nil, err
 		}
-		reimbursements = append(reimbursements, reimb)
+		reimbursements = append(reimbursements, *reimb)
 	}
 	return reimbursements, nil
 }
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanReimbursement scans one row selected with reimbursementColumns.
+func scanReimbursement(row rowScanner) (*models.Reimbursement, error) {
+	reimb := &models.Reimbursement{}
+	var draft []byte
+	err := row.Scan(
+		&reimb.ID,
+		&reimb.EmployeeID,
+		&reimb.EmployeeName,
+		&reimb.Name,
+		&reimb.Title,
+		&reimb.Description,
+		&reimb.Category,
+		&reimb.Amount,
+		&reimb.Currency,
+		&reimb.OriginalAmount,
+		&reimb.ExchangeRate,
+		&reimb.ApprovedAmount,
+		&reimb.AmountAdjustmentReason,
+		&reimb.ReceiptURL,
+		&reimb.Status,
+		&reimb.ExpenseDate,
+		&reimb.SubmittedDate,
+		&reimb.Late,
+		&reimb.LateReason,
+		&reimb.ApprovalChainID,
+		&reimb.CurrentStep,
+		&reimb.CurrentApproverID,
+		&reimb.RevisionCount,
+		&reimb.Version,
+		&reimb.StepEnteredAt,
+		&reimb.Overdue,
+		&reimb.CancelReason,
+		&reimb.CancelledAt,
+		&reimb.ManagerID,
+		&reimb.ManagerOnBehalfOfID,
+		&reimb.ManagerNotes,
+		&reimb.ManagerApproved,
+		&reimb.FinanceID,
+		&reimb.FinanceOnBehalfOfID,
+		&reimb.FinanceNotes,
+		&reimb.FinanceApproved,
+		&reimb.CreatedAt,
+		&reimb.UpdatedAt,
+		&draft,
+		&reimb.AdvanceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if draft != nil {
+		reimb.Draft = &models.CreateReimbursementRequest{}
+		if err := json.Unmarshal(draft, reimb.Draft); err != nil {
+			return nil, err
+		}
+	}
+	return reimb, nil
+}
+
+// expectAffected turns a guarded write that matched no rows into
+// ErrStatusConflict.
+func expectAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
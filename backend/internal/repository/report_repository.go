@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"reimbursement-backend/internal/models"
+)
+
+// ReportRepository aggregates reimbursements for finance reports.
+type ReportRepository struct {
+	db *sql.DB
+}
+
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// TaxByMonth sums the reclaimable tax of the lines of finance-approved claims
+// by month of the expense and category, converted to the base currency. Tax
+// is reclaimable on lines that are not rejected and have a tax invoice
+// number and seller tax ID. Lines dated before from or on or after to are
+// left out when those are set.
+func (r *ReportRepository) TaxByMonth(from, to *time.Time) ([]models.TaxReportRow, error) {
+	query := `
+		SELECT to_char(l.expense_date, 'YYYY-MM') AS month, l.category, COUNT(*),
+		       SUM(ROUND(l.net_amount * l.exchange_rate, 2)), SUM(ROUND(l.tax_amount * l.exchange_rate, 2))
+		FROM reimbursement_lines l
+		JOIN reimbursements r ON r.id = l.reimbursement_id
+		WHERE r.status IN ('approved_finance', 'completed')
+		AND l.status <> 'rejected'
+		AND l.tax_amount > 0
+		AND COALESCE(l.seller_tax_id, '') <> '' AND COALESCE(l.tax_invoice_number, '') <> ''
+		AND ($1::date IS NULL OR l.expense_date >= $1)
+		AND ($2::date IS NULL OR l.expense_date < $2)
+		GROUP BY month, l.category
+		ORDER BY month, l.category
+	`
+	rows, err := r.db.Query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reportRows := []models.TaxReportRow{}
+	for rows.Next() {
+		var row models.TaxReportRow
+		if err := rows.Scan(&row.Month, &row.Category, &row.Lines, &row.NetAmount, &row.TaxAmount); err != nil {
+			return nil, err
+		}
+		reportRows = append(reportRows, row)
+	}
+	return reportRows, rows.Err()
+}
+
+// AllocationsByMonth sums the payable amount of finance-approved claims by
+// month of the expense, cost center and project. A claim's payable amount is
+// shared between its allocations in proportion to their amounts (see
+// models.ShareAllocations), so an approver lowering it lowers every
+// allocation; claims without allocations are summed with no cost center.
+// Claims dated before from or on or after to are left out when those are set.
+func (r *ReportRepository) AllocationsByMonth(from, to *time.Time) ([]models.AllocationReportRow, error) {
+	query := `
+		SELECT to_char(r.expense_date, 'YYYY-MM'), r.id, r.approved_amount, r.amount, a.amount,
+		       cc.code, cc.name, p.code, p.name
+		FROM reimbursements r
+		LEFT JOIN reimbursement_allocations a ON a.reimbursement_id = r.id
+		LEFT JOIN cost_centers cc ON cc.id = a.cost_center_id
+		LEFT JOIN projects p ON p.id = a.project_id
+		WHERE r.status IN ('approved_finance', 'completed')
+		AND ($1::date IS NULL OR r.expense_date >= $1)
+		AND ($2::date IS NULL OR r.expense_date < $2)
+		ORDER BY 1, cc.code NULLS LAST, cc.name, p.code NULLS FIRST, p.name, r.id, a.id
+	`
+	rows, err := r.db.Query(query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Each row is one allocation, or a whole claim without allocations. The
+	// shares of a claim are worked out once all of its allocations are read.
+	type allocationLine struct {
+		row             models.AllocationReportRow
+		reimbursementID int
+	}
+	var lines []allocationLine
+	byClaim := map[int][]int{}
+	payable := map[int]models.Money{}
+	for rows.Next() {
+		var line allocationLine
+		var approved, allocated *models.Money
+		var amount models.Money
+		err := rows.Scan(&line.row.Month, &line.reimbursementID, &approved, &amount, &allocated,
+			&line.row.CostCenterCode, &line.row.CostCenterName, &line.row.ProjectCode, &line.row.ProjectName)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case allocated == nil && approved != nil:
+			line.row.Amount = *approved
+		case allocated == nil:
+			line.row.Amount = amount
+		default:
+			line.row.Amount = *allocated
+			if approved != nil {
+				byClaim[line.reimbursementID] = append(byClaim[line.reimbursementID], len(lines))
+				payable[line.reimbursementID] = *approved
+			}
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for id, indexes := range byClaim {
+		amounts := make([]models.Money, len(indexes))
+		for i, index := range indexes {
+			amounts[i] = lines[index].row.Amount
+		}
+		for i, share := range models.ShareAllocations(payable[id], amounts) {
+			lines[indexes[i]].row.Amount = share
+		}
+	}
+
+	// Lines come sorted by month, cost center and project, so each report
+	// row is a run of lines with the same ones.
+	reportRows := []models.AllocationReportRow{}
+	lastClaim := 0
+	for _, line := range lines {
+		n := len(reportRows)
+		if n > 0 && sameAllocationRow(reportRows[n-1], line.row) {
+			reportRows[n-1].Amount += line.row.Amount
+			if line.reimbursementID != lastClaim {
+				reportRows[n-1].Claims++
+			}
+		} else {
+			line.row.Claims = 1
+			reportRows = append(reportRows, line.row)
+		}
+		lastClaim = line.reimbursementID
+	}
+	return reportRows, nil
+}
+
+// sameAllocationRow reports whether two report rows are for the same month,
+// cost center and project.
+func sameAllocationRow(a, b models.AllocationReportRow) bool {
+	return a.Month == b.Month && sameString(a.CostCenterCode, b.CostCenterCode) && sameString(a.CostCenterName, b.CostCenterName) &&
+		sameString(a.ProjectCode, b.ProjectCode) && sameString(a.ProjectName, b.ProjectName)
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// OutstandingAdvances returns the cash advances paid out and not yet
+// settled, oldest first, with what has been claimed against each: the
+// payable amount of its finance-approved claims, and the claimed amount of
+// those still being approved.
+func (r *ReportRepository) OutstandingAdvances() ([]models.AdvanceReportRow, error) {
+	query := `
+		SELECT a.id, a.employee_id, a.employee_name, a.purpose, a.disbursed_date, CURRENT_DATE - a.disbursed_date, a.amount,
+		       COALESCE(SUM(COALESCE(r.approved_amount, r.amount)) FILTER (WHERE r.status IN ('approved_finance', 'completed')), 0),
+		       COALESCE(SUM(r.amount) FILTER (WHERE r.status IN ('pending', 'approved_manager', 'needs_revision')), 0)
+		FROM cash_advances a
+		LEFT JOIN reimbursements r ON r.advance_id = a.id
+		WHERE a.status = 'disbursed'
+		GROUP BY a.id
+		ORDER BY a.disbursed_date, a.id
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reportRows := []models.AdvanceReportRow{}
+	for rows.Next() {
+		var row models.AdvanceReportRow
+		err := rows.Scan(&row.ID, &row.EmployeeID, &row.EmployeeName, &row.Purpose, &row.DisbursedDate, &row.DaysOpen,
+			&row.Amount, &row.ClaimedAmount, &row.InApproval)
+		if err != nil {
+			return nil, err
+		}
+		row.Balance = row.Amount - row.ClaimedAmount
+		reportRows = append(reportRows, row)
+	}
+	return reportRows, rows.Err()
+}
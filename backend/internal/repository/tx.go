@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+// withTx runs fn inside a transaction, committing if it returns nil and
+// rolling back otherwise.
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -2,11 +2,20 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"reimbursement-backend/internal/models"
 )
 
+// ErrReportingCycle is returned when assigning a manager would make a user
+// report to themselves, directly or through their team.
+var ErrReportingCycle = errors.New("manager assignment would create a reporting cycle")
+
+// reportingLineLockKey is the Postgres advisory lock held while a manager is
+// assigned, so that cycle checks run one at a time.
+const reportingLineLockKey = 7310002
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -18,7 +27,7 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, username, password, full_name, email, role, created_at, updated_at
+		SELECT id, username, password, full_name, email, role, manager_id, created_at, updated_at
 		FROM users
 		WHERE username = $1
 	`
@@ -29,6 +38,7 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 		&user.FullName,
 		&user.Email,
 		&user.Role,
+		&user.ManagerID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -44,7 +54,7 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 func (r *UserRepository) GetByID(id int) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, username, password, full_name, email, role, created_at, updated_at
+		SELECT id, username, password, full_name, email, role, manager_id, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -55,6 +65,7 @@ func (r *UserRepository) GetByID(id int) (*models.User, error) {
 		&user.FullName,
 		&user.Email,
 		&user.Role,
+		&user.ManagerID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -69,8 +80,8 @@ func (r *UserRepository) GetByID(id int) (*models.User, error) {
 
 func (r *UserRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (username, password, full_name, email, role)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (username, password, full_name, email, role, manager_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at, updated_at
 	`
 	return r.db.QueryRow(
@@ -80,12 +91,13 @@ func (r *UserRepository) Create(user *models.User) error {
 		user.FullName,
 		user.Email,
 		user.Role,
+		user.ManagerID,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 }
 
 func (r *UserRepository) GetAll() ([]models.User, error) {
 	query := `
-		SELECT id, username, full_name, email, role, created_at, updated_at
+		SELECT id, username, full_name, email, role, manager_id, created_at, updated_at
 		FROM users
 		ORDER BY created_at DESC
 	`
@@ -104,6 +116,7 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 			&user.FullName,
 			&user.Email,
 			&user.Role,
+			&user.ManagerID,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -114,3 +127,84 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 	}
 	return users, nil
 }
+
+// SetManager changes who a user reports to. A nil managerID removes the
+// reporting line. Assignments that would create a cycle fail with
+// ErrReportingCycle. The cycle check and the update share a transaction
+// holding reportingLineLockKey, so two crossing assignments cannot both pass
+// the check.
+func (r *UserRepository) SetManager(userID int, managerID *int) error {
+	if managerID != nil && *managerID == userID {
+		return ErrReportingCycle
+	}
+
+	return withTx(r.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, reportingLineLockKey); err != nil {
+			return err
+		}
+
+		if managerID != nil {
+			var inTeam bool
+			query := `
+				WITH RECURSIVE team AS (
+					SELECT id FROM users WHERE manager_id = $1
+					UNION
+					SELECT u.id FROM users u JOIN team t ON u.manager_id = t.id
+				)
+				SELECT EXISTS (SELECT 1 FROM team WHERE id = $2)
+			`
+			if err := tx.QueryRow(query, userID, *managerID).Scan(&inTeam); err != nil {
+				return err
+			}
+			if inTeam {
+				return ErrReportingCycle
+			}
+		}
+
+		result, err := tx.Exec(`UPDATE users SET manager_id = $1 WHERE id = $2`, managerID, userID)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("user not found")
+		}
+		return nil
+	})
+}
+
+// FindManagerAbove walks up the reporting line of a user and returns the
+// nearest user holding the manager role. Levels without a manager (for
+// example a team lead with the employee role) are skipped, so a claim
+// escalates to the next level up. It returns nil if nobody above the user is
+// a manager.
+func (r *UserRepository) FindManagerAbove(userID int) (*int, error) {
+	var managerID int
+	query := `
+		WITH RECURSIVE line AS (
+			SELECT manager_id AS id, 1 AS depth FROM users WHERE id = $1
+			UNION ALL
+			SELECT u.manager_id, l.depth + 1
+			FROM line l
+			JOIN users u ON u.id = l.id
+			WHERE l.depth < 50
+		)
+		SELECT u.id
+		FROM line l
+		JOIN users u ON u.id = l.id
+		WHERE u.role = $2
+		ORDER BY l.depth
+		LIMIT 1
+	`
+	err := r.db.QueryRow(query, userID, models.RoleManager).Scan(&managerID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &managerID, nil
+}
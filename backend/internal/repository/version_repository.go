@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"reimbursement-backend/internal/models"
+)
+
+const versionColumns = `
+	id, reimbursement_id, version, name, title, description, category, amount, receipt_url, lines, allocations, edited_by, created_at
+`
+
+// versionLines builds the lines snapshot of reimbursement $1 from
+// reimbursement_lines.
+const versionLines = `(
+	SELECT COALESCE(jsonb_agg(jsonb_build_object(
+		'line_no', line_no, 'category', category, 'description', description, 'amount', amount,
+		'currency', currency, 'original_amount', original_amount, 'expense_date', to_char(expense_date, 'YYYY-MM-DD'), 'receipt_url', receipt_url,
+		'net_amount', net_amount, 'tax_rate', tax_rate, 'tax_amount', tax_amount, 'seller_tax_id', seller_tax_id,
+		'tax_invoice_number', tax_invoice_number, 'origin', origin, 'destination', destination,
+		'distance_km', distance_km, 'vehicle_type', vehicle_type, 'trip_start', to_char(trip_start, 'YYYY-MM-DD'),
+		'trip_end', to_char(trip_end, 'YYYY-MM-DD'), 'location', location, 'provided_meals', provided_meals
+	) ORDER BY line_no), '[]'::jsonb)
+	FROM reimbursement_lines WHERE reimbursement_id = $1
+)`
+
+// versionAllocations builds the allocations snapshot of reimbursement $1 from
+// reimbursement_allocations.
+const versionAllocations = `(
+	SELECT COALESCE(jsonb_agg(jsonb_build_object(
+		'cost_center_id', cost_center_id, 'project_id', project_id, 'percent', percent, 'amount', amount
+	) ORDER BY id), '[]'::jsonb)
+	FROM reimbursement_allocations WHERE reimbursement_id = $1
+)`
+
+type VersionRepository struct {
+	db *sql.DB
+}
+
+func NewVersionRepository(db *sql.DB) *VersionRepository {
+	return &VersionRepository{db: db}
+}
+
+// GetByReimbursementID returns every version of a reimbursement, oldest first.
+func (r *VersionRepository) GetByReimbursementID(reimbursementID int) ([]models.ReimbursementVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM reimbursement_versions WHERE reimbursement_id = $1 ORDER BY version`
+	rows, err := r.db.Query(query, reimbursementID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []models.ReimbursementVersion{}
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *v)
+	}
+	return versions, nil
+}
+
+// Get returns one version of a reimbursement.
+func (r *VersionRepository) Get(reimbursementID, version int) (*models.ReimbursementVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM reimbursement_versions WHERE reimbursement_id = $1 AND version = $2`
+	v, err := scanVersion(r.db.QueryRow(query, reimbursementID, version))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("version not found")
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+func scanVersion(row rowScanner) (*models.ReimbursementVersion, error) {
+	v := &models.ReimbursementVersion{}
+	var lines, allocations []byte
+	err := row.Scan(
+		&v.ID,
+		&v.ReimbursementID,
+		&v.Version,
+		&v.Name,
+		&v.Title,
+		&v.Description,
+		&v.Category,
+		&v.Amount,
+		&v.ReceiptURL,
+		&lines,
+		&allocations,
+		&v.EditedBy,
+		&v.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(lines, &v.Lines); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(allocations, &v.Allocations); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// insertVersion snapshots the submitter's fields of reimb and its stored
+// lines and allocations as reimb.Version, as part of the transaction that
+// made the change.
+func insertVersion(tx *sql.Tx, reimb *models.Reimbursement, actor models.Actor) error {
+	_, err := tx.Exec(`
+		INSERT INTO reimbursement_versions (reimbursement_id, version, name, title, description, category, amount, receipt_url, lines,
+		                                    allocations, edited_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, `+versionLines+`, `+versionAllocations+`, $9)
+	`,
+		reimb.ID,
+		reimb.Version,
+		reimb.Name,
+		reimb.Title,
+		reimb.Description,
+		reimb.Category,
+		reimb.Amount,
+		reimb.ReceiptURL,
+		nullIfZero(actor.UserID),
+	)
+	return err
+}
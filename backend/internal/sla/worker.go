@@ -0,0 +1,232 @@
+package sla
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"reimbursement-backend/config"
+	"reimbursement-backend/internal/models"
+	"reimbursement-backend/internal/repository"
+)
+
+// sweepLockKey is the Postgres advisory lock held while sweeping, so that
+// only one API instance sweeps at a time.
+const sweepLockKey = 7310001
+
+// Worker chases reimbursements that wait too long on an approval step.
+// Past the reminder threshold the step's approver is reminded once; past the
+// escalation threshold the step is handed to the nearest manager above the
+// approver, or the claim is marked overdue if there is nobody to hand it to.
+type Worker struct {
+	db               *sql.DB
+	cfg              config.SLAConfig
+	reimbRepo        *repository.ReimbursementRepository
+	userRepo         *repository.UserRepository
+	chainRepo        *repository.ApprovalChainRepository
+	notificationRepo *repository.NotificationRepository
+}
+
+func NewWorker(
+	db *sql.DB,
+	cfg config.SLAConfig,
+	reimbRepo *repository.ReimbursementRepository,
+	userRepo *repository.UserRepository,
+	chainRepo *repository.ApprovalChainRepository,
+	notificationRepo *repository.NotificationRepository,
+) *Worker {
+	return &Worker{
+		db:               db,
+		cfg:              cfg,
+		reimbRepo:        reimbRepo,
+		userRepo:         userRepo,
+		chainRepo:        chainRepo,
+		notificationRepo: notificationRepo,
+	}
+}
+
+// Run sweeps once and then every CheckIntervalMinutes until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	interval := time.Duration(w.cfg.CheckIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Sweep(ctx); err != nil {
+			log.Printf("SLA sweep failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep checks every approval stage once. If another instance is already
+// sweeping it returns without doing anything.
+func (w *Worker) Sweep(ctx context.Context) error {
+	// Advisory locks belong to a session, so take and release it on one
+	// connection.
+	conn, err := w.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, sweepLockKey).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, sweepLockKey)
+
+	stages := []struct {
+		status models.ReimbursementStatus
+		sla    config.StageSLA
+	}{
+		{models.StatusPending, w.cfg.Pending},
+		{models.StatusApprovedManager, w.cfg.ApprovedManager},
+	}
+	for _, stage := range stages {
+		if err := w.sweepStage(stage.status, stage.sla); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Worker) sweepStage(status models.ReimbursementStatus, sla config.StageSLA) error {
+	// Escalate first: escalated claims restart their timer and overdue ones
+	// are skipped, so they are not reminded again below.
+	if sla.EscalateAfterHours > 0 {
+		claims, err := w.reimbRepo.GetWaitingLongerThan(status, sla.EscalateAfterHours)
+		if err != nil {
+			return err
+		}
+		for i := range claims {
+			if err := w.escalate(&claims[i]); err != nil {
+				log.Printf("SLA: failed to escalate reimbursement %d: %v", claims[i].ID, err)
+			}
+		}
+	}
+
+	if sla.RemindAfterHours > 0 {
+		claims, err := w.reimbRepo.GetWaitingLongerThan(status, sla.RemindAfterHours)
+		if err != nil {
+			return err
+		}
+		for i := range claims {
+			if err := w.remind(&claims[i]); err != nil {
+				log.Printf("SLA: failed to remind approver of reimbursement %d: %v", claims[i].ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Worker) remind(reimb *models.Reimbursement) error {
+	n, err := w.notification(reimb, models.NotificationReminder, fmt.Sprintf(
+		"Reimbursement #%d %q has been waiting for your approval since %s",
+		reimb.ID, reimb.Title, reimb.StepEnteredAt.Format("2006-01-02 15:04"),
+	))
+	if err != nil {
+		return err
+	}
+	created, err := w.notificationRepo.Create(n)
+	if err != nil {
+		return err
+	}
+	if created {
+		log.Printf("SLA: reminded approver of reimbursement %d", reimb.ID)
+	}
+	return nil
+}
+
+func (w *Worker) escalate(reimb *models.Reimbursement) error {
+	var fallbackID *int
+	if reimb.CurrentApproverID != nil {
+		var err error
+		fallbackID, err = w.userRepo.FindManagerAbove(*reimb.CurrentApproverID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if fallbackID != nil && *fallbackID != reimb.EmployeeID {
+		n := &models.Notification{
+			ReimbursementID: reimb.ID,
+			RecipientID:     fallbackID,
+			Kind:            models.NotificationEscalation,
+			Message: fmt.Sprintf(
+				"Reimbursement #%d %q was escalated to you after waiting on its approver since %s",
+				reimb.ID, reimb.Title, reimb.StepEnteredAt.Format("2006-01-02 15:04"),
+			),
+			StepOrder:     reimb.CurrentStep,
+			StepEnteredAt: reimb.StepEnteredAt,
+		}
+		err := w.reimbRepo.Escalate(reimb, *fallbackID, n)
+		if errors.Is(err, repository.ErrStatusConflict) {
+			return nil
+		}
+		if err == nil {
+			log.Printf("SLA: escalated reimbursement %d to user %d", reimb.ID, *fallbackID)
+		}
+		return err
+	}
+
+	n, err := w.notification(reimb, models.NotificationOverdue, fmt.Sprintf(
+		"Reimbursement #%d %q is overdue: it has been waiting for your approval since %s",
+		reimb.ID, reimb.Title, reimb.StepEnteredAt.Format("2006-01-02 15:04"),
+	))
+	if err != nil {
+		return err
+	}
+	err = w.reimbRepo.MarkOverdue(reimb, n)
+	if errors.Is(err, repository.ErrStatusConflict) {
+		return nil
+	}
+	if err == nil {
+		log.Printf("SLA: marked reimbursement %d overdue", reimb.ID)
+	}
+	return err
+}
+
+// notification builds a notification for whoever is expected to decide the
+// current step: the assigned approver, or everyone with the step's role.
+func (w *Worker) notification(reimb *models.Reimbursement, kind models.NotificationKind, message string) (*models.Notification, error) {
+	n := &models.Notification{
+		ReimbursementID: reimb.ID,
+		RecipientID:     reimb.CurrentApproverID,
+		Kind:            kind,
+		Message:         message,
+		StepOrder:       reimb.CurrentStep,
+		StepEnteredAt:   reimb.StepEnteredAt,
+	}
+	if n.RecipientID != nil {
+		return n, nil
+	}
+
+	if reimb.ApprovalChainID == nil {
+		return nil, fmt.Errorf("reimbursement %d has no approval chain", reimb.ID)
+	}
+	chain, err := w.chainRepo.GetByID(*reimb.ApprovalChainID)
+	if err != nil {
+		return nil, err
+	}
+	if reimb.CurrentStep < 1 || reimb.CurrentStep > len(chain.Steps) {
+		return nil, fmt.Errorf("reimbursement %d is on unknown step %d", reimb.ID, reimb.CurrentStep)
+	}
+	step := chain.Steps[reimb.CurrentStep-1]
+	n.RecipientID = step.RequiredUserID
+	n.RecipientRole = step.RequiredRole
+	return n, nil
+}
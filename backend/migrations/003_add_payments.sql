@@ -0,0 +1,19 @@
+-- Payments made for finance-approved reimbursements
+CREATE TABLE IF NOT EXISTS reimbursement_payments (
+    id SERIAL PRIMARY KEY,
+    reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+    amount DECIMAL(12, 2) NOT NULL,
+    method VARCHAR(20) NOT NULL CHECK (method IN ('bank_transfer', 'cash', 'payroll')),
+    reference VARCHAR(100) NOT NULL,
+    payment_date DATE NOT NULL,
+    paid_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reversed_at TIMESTAMP,
+    reversed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reversal_reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reimbursement_payments_reimbursement_id ON reimbursement_payments(reimbursement_id);
+
+-- At most one payment per reimbursement may be active (not reversed)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reimbursement_payments_active ON reimbursement_payments(reimbursement_id) WHERE reversed_at IS NULL;
@@ -0,0 +1,59 @@
+-- Configurable approval chains: ordered steps selected by category and amount
+CREATE TABLE IF NOT EXISTS approval_chains (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    category VARCHAR(50),
+    min_amount DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    max_amount DECIMAL(12, 2),
+    priority INTEGER NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (max_amount IS NULL OR max_amount > min_amount)
+);
+
+-- Each step is decided by a specific user or by any user with a role
+CREATE TABLE IF NOT EXISTS approval_chain_steps (
+    id SERIAL PRIMARY KEY,
+    chain_id INTEGER NOT NULL REFERENCES approval_chains(id) ON DELETE CASCADE,
+    step_order INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    required_role VARCHAR(20) CHECK (required_role IN ('manager', 'finance')),
+    required_user_id INTEGER REFERENCES users(id) ON DELETE RESTRICT,
+    UNIQUE (chain_id, step_order),
+    CHECK ((required_role IS NULL) <> (required_user_id IS NULL))
+);
+
+-- Default chain matching the previous hard-wired flow: manager, then finance
+INSERT INTO approval_chains (name, min_amount, priority)
+SELECT 'Standard', 0, 0
+WHERE NOT EXISTS (SELECT 1 FROM approval_chains);
+
+INSERT INTO approval_chain_steps (chain_id, step_order, name, required_role)
+SELECT c.id, v.step_order, v.name, v.required_role
+FROM approval_chains c
+CROSS JOIN (VALUES (1, 'Manager approval', 'manager'), (2, 'Finance approval', 'finance')) AS v(step_order, name, required_role)
+WHERE c.name = 'Standard'
+  AND NOT EXISTS (SELECT 1 FROM approval_chain_steps s WHERE s.chain_id = c.id);
+
+-- Each claim stores its resolved chain and the step it is waiting on
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS approval_chain_id INTEGER REFERENCES approval_chains(id);
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS current_step INTEGER NOT NULL DEFAULT 1;
+
+UPDATE reimbursements
+SET approval_chain_id = (SELECT id FROM approval_chains WHERE name = 'Standard' ORDER BY id LIMIT 1),
+    current_step = CASE WHEN status IN ('pending', 'rejected_manager') THEN 1 ELSE 2 END
+WHERE approval_chain_id IS NULL AND status <> 'draft';
+
+-- Decisions made on each step
+CREATE TABLE IF NOT EXISTS reimbursement_approvals (
+    id SERIAL PRIMARY KEY,
+    reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+    step_order INTEGER NOT NULL,
+    step_name VARCHAR(100) NOT NULL,
+    approver_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(20) NOT NULL,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reimbursement_approvals_reimbursement_id ON reimbursement_approvals(reimbursement_id);
@@ -0,0 +1,17 @@
+-- Reporting line: who each user reports to
+ALTER TABLE users ADD COLUMN IF NOT EXISTS manager_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_users_manager_id ON users(manager_id);
+
+-- The user the current approval step of a claim is routed to.
+-- NULL means anyone with the step's role may decide it.
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS current_approver_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_reimbursements_current_approver_id ON reimbursements(current_approver_id);
+
+-- Claims waiting on a step assigned to a named user
+UPDATE reimbursements r
+SET current_approver_id = s.required_user_id
+FROM approval_chain_steps s
+WHERE s.chain_id = r.approval_chain_id AND s.step_order = r.current_step
+  AND s.required_user_id IS NOT NULL
+  AND r.current_approver_id IS NULL
+  AND r.status IN ('pending', 'approved_manager');
@@ -0,0 +1,23 @@
+-- Approval delegation for out-of-office approvers.
+-- A delegation is in effect while revoked_at IS NULL and CURRENT_DATE is
+-- between start_date and end_date, so it expires on its own.
+CREATE TABLE IF NOT EXISTS delegations (
+    id SERIAL PRIMARY KEY,
+    delegator_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    delegate_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason TEXT,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_date >= start_date),
+    CHECK (delegator_id <> delegate_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_delegations_delegate_id ON delegations(delegate_id);
+CREATE INDEX IF NOT EXISTS idx_delegations_delegator_id ON delegations(delegator_id);
+
+-- Who an approval was made on behalf of, when made by a delegate
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS manager_on_behalf_of_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS finance_on_behalf_of_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE reimbursement_approvals ADD COLUMN IF NOT EXISTS on_behalf_of_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,10 @@
+-- Return for revision: approvers can send a claim back to the submitter,
+-- who edits and resubmits it to the same step.
+ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_status_check;
+ALTER TABLE reimbursements ADD CONSTRAINT reimbursements_status_check CHECK (status IN (
+    'pending', 'approved_manager', 'rejected_manager', 'approved_finance', 'rejected_finance', 'completed',
+    'needs_revision'
+));
+
+-- How many times the claim has been returned for revision
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS revision_count INTEGER NOT NULL DEFAULT 0;
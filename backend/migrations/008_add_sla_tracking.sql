@@ -0,0 +1,28 @@
+-- SLA tracking for claims waiting on an approval step.
+-- step_entered_at is when the claim reached its current step (or its current
+-- approver, after an escalation); overdue is set when the escalation deadline
+-- passes and there is nobody to escalate to.
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS step_entered_at TIMESTAMP;
+UPDATE reimbursements SET step_entered_at = COALESCE(updated_at, submitted_date) WHERE step_entered_at IS NULL;
+ALTER TABLE reimbursements ALTER COLUMN step_entered_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE reimbursements ALTER COLUMN step_entered_at SET NOT NULL;
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS overdue BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_reimbursements_status_step_entered_at ON reimbursements(status, step_entered_at);
+
+-- Reminders, escalations and overdue notices sent to approvers. The unique key
+-- makes sure a notice is stored once per wait on a step, however many API
+-- instances run the SLA worker.
+CREATE TABLE IF NOT EXISTS reimbursement_notifications (
+    id SERIAL PRIMARY KEY,
+    reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+    recipient_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    recipient_role VARCHAR(50),
+    kind VARCHAR(50) NOT NULL CHECK (kind IN ('reminder', 'escalation', 'overdue')),
+    message TEXT NOT NULL,
+    step_order INTEGER NOT NULL,
+    step_entered_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (reimbursement_id, kind, step_order, step_entered_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reimbursement_notifications_recipient_id ON reimbursement_notifications(recipient_id);
@@ -0,0 +1,10 @@
+-- Employees cancel claims instead of deleting them; cancelled claims are kept
+-- for audit and only removed by an administrative purge.
+ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_status_check;
+ALTER TABLE reimbursements ADD CONSTRAINT reimbursements_status_check CHECK (status IN (
+    'pending', 'approved_manager', 'rejected_manager', 'approved_finance', 'rejected_finance', 'completed',
+    'needs_revision', 'cancelled'
+));
+
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS cancel_reason TEXT;
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
@@ -0,0 +1,8 @@
+-- Partial approval: an approver may approve less than the claimed amount,
+-- with a justification. Stats and payments use approved_amount when set.
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS approved_amount DECIMAL(12, 2) CHECK (approved_amount > 0);
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS amount_adjustment_reason TEXT;
+
+-- The amount and justification given with each decision
+ALTER TABLE reimbursement_approvals ADD COLUMN IF NOT EXISTS approved_amount DECIMAL(12, 2);
+ALTER TABLE reimbursement_approvals ADD COLUMN IF NOT EXISTS adjustment_reason TEXT;
@@ -0,0 +1,13 @@
+-- Separation-of-duties rules checked when approvers decide a step. Rules are
+-- on by default and can be switched off by finance.
+CREATE TABLE IF NOT EXISTS duty_rules (
+    code VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO duty_rules (code, description) VALUES
+    ('no_self_approval', 'The submitter cannot decide any step of their own reimbursement'),
+    ('distinct_approvers', 'The same person cannot approve more than one step of a reimbursement')
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,20 @@
+-- Discussion thread on each reimbursement. Attachments are URLs returned by
+-- the upload endpoint.
+CREATE TABLE IF NOT EXISTS reimbursement_comments (
+    id SERIAL PRIMARY KEY,
+    reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    attachments TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reimbursement_comments_reimbursement_id ON reimbursement_comments(reimbursement_id);
+
+-- How far each user has read each thread, for unread counts
+CREATE TABLE IF NOT EXISTS reimbursement_comment_reads (
+    reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_read_comment_id INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (reimbursement_id, user_id)
+);
@@ -0,0 +1,38 @@
+-- Append-only history of every change to a reimbursement, written in the
+-- same transaction as the change. There is deliberately no foreign key to
+-- reimbursements so the history of a purged claim is kept, nor to users: the
+-- append-only trigger would refuse the ON DELETE action, and the history
+-- should keep who acted anyway.
+CREATE TABLE IF NOT EXISTS reimbursement_events (
+    id SERIAL PRIMARY KEY,
+    reimbursement_id INTEGER NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    actor_id INTEGER,
+    actor_role VARCHAR(20),
+    from_status VARCHAR(50),
+    to_status VARCHAR(50),
+    notes TEXT,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reimbursement_events_reimbursement_id ON reimbursement_events(reimbursement_id);
+ALTER TABLE reimbursement_events DROP CONSTRAINT IF EXISTS reimbursement_events_actor_id_fkey;
+
+CREATE OR REPLACE FUNCTION reject_reimbursement_event_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'reimbursement_events is append-only';
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS reimbursement_events_append_only ON reimbursement_events;
+CREATE TRIGGER reimbursement_events_append_only BEFORE UPDATE OR DELETE ON reimbursement_events
+    FOR EACH ROW EXECUTE FUNCTION reject_reimbursement_event_change();
+
+-- Existing claims start their history with a created event
+INSERT INTO reimbursement_events (reimbursement_id, action, actor_id, actor_role, to_status, notes, created_at)
+SELECT r.id, 'created', r.employee_id, 'employee', 'pending', 'Recorded when the history was introduced', r.submitted_date
+FROM reimbursements r
+WHERE NOT EXISTS (SELECT 1 FROM reimbursement_events e WHERE e.reimbursement_id = r.id);
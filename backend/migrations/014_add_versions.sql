@@ -0,0 +1,27 @@
+-- Every edit of a reimbursement is kept as a numbered snapshot of the
+-- submitter's fields. Approval decisions record the version they were made
+-- against.
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS reimbursement_versions (
+    id SERIAL PRIMARY KEY,
+    reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    name VARCHAR(200),
+    title VARCHAR(200) NOT NULL,
+    description TEXT NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    receipt_url TEXT NOT NULL,
+    edited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (reimbursement_id, version)
+);
+
+-- Existing claims start with their current fields as version 1
+INSERT INTO reimbursement_versions (reimbursement_id, version, name, title, description, category, amount, receipt_url, edited_by, created_at)
+SELECT r.id, r.version, r.name, r.title, r.description, r.category, r.amount, r.receipt_url, r.employee_id, r.updated_at
+FROM reimbursements r
+WHERE NOT EXISTS (SELECT 1 FROM reimbursement_versions v WHERE v.reimbursement_id = r.id);
+
+ALTER TABLE reimbursement_approvals ADD COLUMN IF NOT EXISTS version INTEGER;
@@ -0,0 +1,35 @@
+-- A reimbursement is an expense report of one or more lines, each with its
+-- own category, amount, date and receipt. Approvers may reject single lines.
+CREATE TABLE IF NOT EXISTS reimbursement_lines (
+    id SERIAL PRIMARY KEY,
+    reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+    line_no INTEGER NOT NULL,
+    category VARCHAR(50) NOT NULL CHECK (category IN ('transport', 'accommodation', 'meals', 'office_supply', 'other')),
+    description TEXT NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    expense_date DATE NOT NULL,
+    receipt_url TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'rejected')),
+    rejection_reason TEXT,
+    decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP,
+    UNIQUE (reimbursement_id, line_no)
+);
+
+-- Existing claims become one-line reports
+INSERT INTO reimbursement_lines (reimbursement_id, line_no, category, description, amount, expense_date, receipt_url, status)
+SELECT r.id, 1, r.category, r.description, r.amount, r.submitted_date::date, r.receipt_url,
+       CASE WHEN r.status IN ('approved_finance', 'completed') THEN 'accepted' ELSE 'pending' END
+FROM reimbursements r
+WHERE NOT EXISTS (SELECT 1 FROM reimbursement_lines l WHERE l.reimbursement_id = r.id);
+
+-- Versions also keep the lines of the report
+ALTER TABLE reimbursement_versions ADD COLUMN IF NOT EXISTS lines JSONB;
+
+UPDATE reimbursement_versions v SET lines = (
+    SELECT COALESCE(jsonb_agg(jsonb_build_object(
+        'line_no', l.line_no, 'category', l.category, 'description', l.description, 'amount', l.amount,
+        'expense_date', to_char(l.expense_date, 'YYYY-MM-DD'), 'receipt_url', l.receipt_url
+    ) ORDER BY l.line_no), '[]'::jsonb)
+    FROM reimbursement_lines l WHERE l.reimbursement_id = v.reimbursement_id
+) WHERE v.lines IS NULL;
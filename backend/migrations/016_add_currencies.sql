@@ -0,0 +1,36 @@
+-- Rates finance maintains to convert claims to the base currency: one unit
+-- of currency is worth rate base units from rate_date on.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id SERIAL PRIMARY KEY,
+    currency VARCHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate DECIMAL(18, 8) NOT NULL CHECK (rate > 0),
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (currency, rate_date)
+);
+
+-- Lines keep what was spent and in which currency; amount is the converted
+-- base amount. Claims made before multi-currency support are in the base
+-- currency, given as a psql variable, e.g.
+--   psql -v base_currency=IDR -f 016_add_currencies.sql
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT :'base_currency';
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS original_amount DECIMAL(12, 2);
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18, 8) NOT NULL DEFAULT 1;
+UPDATE reimbursement_lines SET original_amount = amount WHERE original_amount IS NULL;
+ALTER TABLE reimbursement_lines ALTER COLUMN original_amount SET NOT NULL;
+
+-- Reports whose lines share a currency show it; mixed reports and drafts
+-- leave these NULL
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS currency VARCHAR(3);
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS original_amount DECIMAL(12, 2);
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18, 8);
+UPDATE reimbursements r SET currency = :'base_currency', original_amount = r.amount, exchange_rate = 1
+WHERE r.currency IS NULL AND r.status <> 'draft'
+AND NOT EXISTS (SELECT 1 FROM reimbursement_lines l WHERE l.reimbursement_id = r.id AND l.currency <> :'base_currency');
+
+UPDATE reimbursement_versions SET lines = (
+    SELECT jsonb_agg(e || jsonb_build_object('currency', :'base_currency', 'original_amount', e->'amount') ORDER BY (e->>'line_no')::int)
+    FROM jsonb_array_elements(lines) e
+) WHERE jsonb_array_length(lines) > 0 AND NOT (lines->0 ? 'currency');
@@ -0,0 +1,10 @@
+-- Optional VAT (PPN) breakdown of a line, in the line's currency. The
+-- application checks that net_amount + tax_amount matches original_amount.
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS net_amount DECIMAL(12, 2) CHECK (net_amount > 0);
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS tax_rate DECIMAL(5, 2) CHECK (tax_rate BETWEEN 0 AND 100);
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(12, 2) CHECK (tax_amount >= 0);
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS seller_tax_id VARCHAR(30);
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS tax_invoice_number VARCHAR(50);
+
+-- The tax report groups lines by the month of the expense
+CREATE INDEX IF NOT EXISTS idx_reimbursement_lines_expense_date ON reimbursement_lines(expense_date);
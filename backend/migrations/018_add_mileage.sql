@@ -0,0 +1,27 @@
+-- Per-kilometre rates for trips in an employee's own vehicle, in the base
+-- currency, each in effect from effective_from until the next one.
+CREATE TABLE IF NOT EXISTS mileage_rates (
+    id SERIAL PRIMARY KEY,
+    vehicle_type VARCHAR(20) NOT NULL CHECK (vehicle_type IN ('car', 'motorcycle')),
+    rate_per_km DECIMAL(12, 2) NOT NULL CHECK (rate_per_km > 0),
+    effective_from DATE NOT NULL,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (vehicle_type, effective_from)
+);
+
+ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_category_check;
+ALTER TABLE reimbursements ADD CONSTRAINT reimbursements_category_check
+    CHECK (category IN ('transport', 'accommodation', 'meals', 'office_supply', 'mileage', 'other'));
+ALTER TABLE reimbursement_lines DROP CONSTRAINT IF EXISTS reimbursement_lines_category_check;
+ALTER TABLE reimbursement_lines ADD CONSTRAINT reimbursement_lines_category_check
+    CHECK (category IN ('transport', 'accommodation', 'meals', 'office_supply', 'mileage', 'other'));
+
+-- The trip of a mileage line and the rate per km it was priced at, so a new
+-- rate does not change claims already made
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS origin VARCHAR(200);
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS destination VARCHAR(200);
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS distance_km DECIMAL(8, 1) CHECK (distance_km > 0);
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS vehicle_type VARCHAR(20);
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS mileage_rate DECIMAL(12, 2);
@@ -0,0 +1,33 @@
+-- Daily allowances for business trips per city or country, in the base
+-- currency, each in effect from effective_from until the next one. A
+-- provided meal reduces a day's allowance by its percentage of the rate.
+CREATE TABLE IF NOT EXISTS per_diem_rates (
+    id SERIAL PRIMARY KEY,
+    location VARCHAR(100) NOT NULL,
+    daily_rate DECIMAL(12, 2) NOT NULL CHECK (daily_rate > 0),
+    breakfast_percent DECIMAL(5, 2) NOT NULL DEFAULT 0 CHECK (breakfast_percent BETWEEN 0 AND 100),
+    lunch_percent DECIMAL(5, 2) NOT NULL DEFAULT 0 CHECK (lunch_percent BETWEEN 0 AND 100),
+    dinner_percent DECIMAL(5, 2) NOT NULL DEFAULT 0 CHECK (dinner_percent BETWEEN 0 AND 100),
+    effective_from DATE NOT NULL,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Locations are matched ignoring case
+CREATE UNIQUE INDEX IF NOT EXISTS idx_per_diem_rates_location ON per_diem_rates(LOWER(location), effective_from);
+
+ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_category_check;
+ALTER TABLE reimbursements ADD CONSTRAINT reimbursements_category_check
+    CHECK (category IN ('transport', 'accommodation', 'meals', 'office_supply', 'mileage', 'per_diem', 'other'));
+ALTER TABLE reimbursement_lines DROP CONSTRAINT IF EXISTS reimbursement_lines_category_check;
+ALTER TABLE reimbursement_lines ADD CONSTRAINT reimbursement_lines_category_check
+    CHECK (category IN ('transport', 'accommodation', 'meals', 'office_supply', 'mileage', 'per_diem', 'other'));
+
+-- The trip of a per-diem line and the breakdown of its amount by day, kept so
+-- finance can audit the calculation after rates change
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS trip_start DATE;
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS trip_end DATE;
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS location VARCHAR(100);
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS provided_meals JSONB;
+ALTER TABLE reimbursement_lines ADD COLUMN IF NOT EXISTS per_diem_breakdown JSONB;
@@ -0,0 +1,37 @@
+-- Expense categories, managed by finance instead of a CHECK constraint. A
+-- deactivated category stays for the claims that use it.
+CREATE TABLE IF NOT EXISTS categories (
+    code VARCHAR(50) PRIMARY KEY,
+    display_name VARCHAR(100) NOT NULL,
+    local_display_name VARCHAR(100),
+    gl_account VARCHAR(30),
+    receipt_required BOOLEAN NOT NULL DEFAULT TRUE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO categories (code, display_name, local_display_name, receipt_required) VALUES
+    ('transport', 'Transport', 'Perjalanan', TRUE),
+    ('accommodation', 'Accommodation', 'Akomodasi', TRUE),
+    ('meals', 'Meals', 'Makanan', TRUE),
+    ('office_supply', 'Office Supplies', 'Perlengkapan Kantor', TRUE),
+    ('mileage', 'Mileage', 'Jarak Tempuh', FALSE),
+    ('per_diem', 'Per Diem', 'Uang Harian', FALSE),
+    ('other', 'Other', 'Lainnya', TRUE)
+ON CONFLICT (code) DO NOTHING;
+
+-- Approval chains were never checked; keep any code they use, inactive
+INSERT INTO categories (code, display_name, active)
+SELECT DISTINCT category, category, FALSE FROM approval_chains WHERE category IS NOT NULL
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_category_check;
+ALTER TABLE reimbursement_lines DROP CONSTRAINT IF EXISTS reimbursement_lines_category_check;
+
+ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_category_fkey;
+ALTER TABLE reimbursements ADD CONSTRAINT reimbursements_category_fkey FOREIGN KEY (category) REFERENCES categories(code);
+ALTER TABLE reimbursement_lines DROP CONSTRAINT IF EXISTS reimbursement_lines_category_fkey;
+ALTER TABLE reimbursement_lines ADD CONSTRAINT reimbursement_lines_category_fkey FOREIGN KEY (category) REFERENCES categories(code);
+ALTER TABLE approval_chains DROP CONSTRAINT IF EXISTS approval_chains_category_fkey;
+ALTER TABLE approval_chains ADD CONSTRAINT approval_chains_category_fkey FOREIGN KEY (category) REFERENCES categories(code);
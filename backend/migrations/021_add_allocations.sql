@@ -0,0 +1,41 @@
+-- Cost centers and projects expenses are charged to, and the split of each
+-- claim between them
+CREATE TABLE IF NOT EXISTS cost_centers (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(30) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS projects (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(30) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    client VARCHAR(100),
+    cost_center_id INTEGER REFERENCES cost_centers(id),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- percent is kept for allocations given as a percentage, so they follow
+-- changes to the claim's amount
+CREATE TABLE IF NOT EXISTS reimbursement_allocations (
+    id SERIAL PRIMARY KEY,
+    reimbursement_id INTEGER NOT NULL REFERENCES reimbursements(id) ON DELETE CASCADE,
+    cost_center_id INTEGER NOT NULL REFERENCES cost_centers(id),
+    project_id INTEGER REFERENCES projects(id),
+    percent DECIMAL(5, 2) CHECK (percent > 0 AND percent <= 100),
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reimbursement_allocations_reimbursement ON reimbursement_allocations(reimbursement_id);
+
+ALTER TABLE reimbursement_versions ADD COLUMN IF NOT EXISTS allocations JSONB NOT NULL DEFAULT '[]';
+
+-- A cost_center_owner step goes to the owner of the claim's main cost center,
+-- or to required_role if there is none
+ALTER TABLE approval_chain_steps ADD COLUMN IF NOT EXISTS cost_center_owner BOOLEAN NOT NULL DEFAULT FALSE;
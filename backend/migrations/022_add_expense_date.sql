@@ -0,0 +1,16 @@
+-- The date a claim's expense was spent, its earliest line's, apart from the
+-- date it was submitted, and whether it was claimed late
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS expense_date DATE;
+
+UPDATE reimbursements r
+SET expense_date = COALESCE(
+    (SELECT MIN(l.expense_date) FROM reimbursement_lines l WHERE l.reimbursement_id = r.id),
+    r.submitted_date::date)
+WHERE expense_date IS NULL;
+
+ALTER TABLE reimbursements ALTER COLUMN expense_date SET NOT NULL;
+
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS late BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS late_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_reimbursements_expense_date ON reimbursements(expense_date);
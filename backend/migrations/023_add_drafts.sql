@@ -0,0 +1,19 @@
+-- Draft claims, saved by their submitter before they enter the workflow.
+-- A draft keeps the claim as sent in draft until it is submitted, so its
+-- category, expense date and amount are only required once it leaves draft.
+ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_status_check;
+ALTER TABLE reimbursements ADD CONSTRAINT reimbursements_status_check CHECK (status IN (
+    'pending', 'approved_manager', 'rejected_manager', 'approved_finance', 'rejected_finance', 'completed',
+    'needs_revision', 'cancelled', 'draft'
+));
+
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS draft JSONB;
+
+ALTER TABLE reimbursements ALTER COLUMN category DROP NOT NULL;
+ALTER TABLE reimbursements ALTER COLUMN expense_date DROP NOT NULL;
+
+ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_amount_check;
+ALTER TABLE reimbursements DROP CONSTRAINT IF EXISTS reimbursements_submitted_check;
+ALTER TABLE reimbursements ADD CONSTRAINT reimbursements_submitted_check CHECK (
+    status = 'draft' OR (category IS NOT NULL AND expense_date IS NOT NULL AND amount > 0)
+);
@@ -0,0 +1,67 @@
+-- Cash advances paid to employees up front, and the claims filed against
+-- them. A claim with an advance_id is not paid on its own but completed when
+-- finance settles the advance; claimed_amount is recorded at settlement.
+CREATE TABLE IF NOT EXISTS cash_advances (
+    id SERIAL PRIMARY KEY,
+    employee_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    employee_name VARCHAR(100) NOT NULL,
+    purpose TEXT NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'disbursed', 'settled', 'cancelled')),
+    approver_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP,
+    decision_notes TEXT,
+    disbursed_date DATE,
+    disbursed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    disbursement_method VARCHAR(20) CHECK (disbursement_method IN ('bank_transfer', 'cash', 'payroll')),
+    disbursement_reference VARCHAR(100),
+    claimed_amount DECIMAL(12, 2),
+    settled_date DATE,
+    settled_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    settlement_method VARCHAR(20) CHECK (settlement_method IN ('bank_transfer', 'cash', 'payroll')),
+    settlement_reference VARCHAR(100),
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cash_advances_employee_id ON cash_advances(employee_id);
+CREATE INDEX IF NOT EXISTS idx_cash_advances_status ON cash_advances(status);
+
+DROP TRIGGER IF EXISTS update_cash_advances_updated_at ON cash_advances;
+CREATE TRIGGER update_cash_advances_updated_at BEFORE UPDATE ON cash_advances
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE reimbursements ADD COLUMN IF NOT EXISTS advance_id INTEGER REFERENCES cash_advances(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_reimbursements_advance_id ON reimbursements(advance_id);
+
+-- Append-only history of every change to a cash advance, written in the same
+-- transaction as the change, like reimbursement_events and for the same
+-- reasons without foreign keys.
+CREATE TABLE IF NOT EXISTS cash_advance_events (
+    id SERIAL PRIMARY KEY,
+    advance_id INTEGER NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    actor_id INTEGER,
+    actor_role VARCHAR(20),
+    from_status VARCHAR(20),
+    to_status VARCHAR(20),
+    notes TEXT,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cash_advance_events_advance_id ON cash_advance_events(advance_id);
+
+CREATE OR REPLACE FUNCTION reject_cash_advance_event_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'cash_advance_events is append-only';
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS cash_advance_events_append_only ON cash_advance_events;
+CREATE TRIGGER cash_advance_events_append_only BEFORE UPDATE OR DELETE ON cash_advance_events
+    FOR EACH ROW EXECUTE FUNCTION reject_cash_advance_event_change();
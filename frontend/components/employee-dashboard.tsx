@@ -18,24 +18,39 @@ import {
   DialogTrigger,
 } from "@/components/ui/dialog"
 import { FileText, Plus, Upload, Clock, CheckCircle, XCircle, DollarSign, LogOut, Loader2, X, Printer, Download } from "lucide-react"
-import { authAPI, reimbursementAPI, uploadAPI, type Reimbursement, type ReimbursementStats, type ReimbursementCategory } from "@/lib/api"
+import { advanceAPI, authAPI, categoryAPI, reimbursementAPI, uploadAPI, type CashAdvance, type Category, type Reimbursement, type ReimbursementStats, type ReimbursementCategory } from "@/lib/api"
+import { formatMoney } from "@/lib/utils"
 import { useToast } from "@/hooks/use-toast"
 
-const categoryMap: Record<ReimbursementCategory, string> = {
+const categoryMap: Record<string, string> = {
   transport: "Perjalanan",
   accommodation: "Akomodasi",
   meals: "Makanan",
   office_supply: "Perlengkapan Kantor",
+  mileage: "Jarak Tempuh",
+  per_diem: "Uang Harian",
   other: "Lainnya",
 }
 
 const statusMap: Record<string, string> = {
+  draft: "Draf",
   pending: "Tertunda",
   approved_manager: "Disetujui Manager",
   rejected_manager: "Ditolak Manager",
-  approved_finance: "Disetujui Finance",
+  approved_finance: "Disetujui, Menunggu Pembayaran",
   rejected_finance: "Ditolak Finance",
-  completed: "Selesai",
+  completed: "Dibayar",
+  needs_revision: "Perlu Revisi",
+  cancelled: "Dibatalkan",
+}
+
+const advanceStatusMap: Record<string, string> = {
+  pending: "Menunggu Persetujuan",
+  approved: "Disetujui, Menunggu Pencairan",
+  rejected: "Ditolak",
+  disbursed: "Dicairkan",
+  settled: "Diselesaikan",
+  cancelled: "Dibatalkan",
 }
 
 export function EmployeeDashboard() {
@@ -51,6 +66,10 @@ export function EmployeeDashboard() {
   const [filePreview, setFilePreview] = useState<string | null>(null)
   const [isUploading, setIsUploading] = useState(false)
   const [selectedReceipt, setSelectedReceipt] = useState<Reimbursement | null>(null)
+  const [categories, setCategories] = useState<Category[]>([])
+  const [advances, setAdvances] = useState<CashAdvance[]>([])
+  const [advanceOpen, setAdvanceOpen] = useState(false)
+  const [advanceForm, setAdvanceForm] = useState({ purpose: "", amount: "" })
 
   // Form state
   const [formData, setFormData] = useState({
@@ -59,7 +78,9 @@ export function EmployeeDashboard() {
     description: "",
     category: "" as ReimbursementCategory,
     amount: "",
+    expense_date: "",
     receipt_url: "",
+    advance_id: "",
   })
 
   useEffect(() => {
@@ -82,12 +103,17 @@ export function EmployeeDashboard() {
   const loadData = async () => {
     try {
       setIsLoading(true)
-      const [reimbData, statsData] = await Promise.all([
+      const [reimbData, statsData, categoryData, advanceData] = await Promise.all([
         reimbursementAPI.getAll(),
         reimbursementAPI.getStats(),
+        categoryAPI.getAll(),
+        advanceAPI.getAll(),
       ])
       setReimbursements(reimbData || [])
+      setAdvances(advanceData || [])
       setStats(statsData)
+      // Mileage and per-diem claims need a trip, which this form does not ask for
+      setCategories((categoryData || []).filter((c) => c.code !== 'mileage' && c.code !== 'per_diem'))
     } catch (error: any) {
       setReimbursements([])
       toast({
@@ -175,8 +201,10 @@ export function EmployeeDashboard() {
         title: formData.title,
         description: formData.description,
         category: formData.category,
-        amount: parseFloat(formData.amount),
+        amount: formData.amount,
+        expense_date: formData.expense_date,
         receipt_url: uploadResult.url,
+        advance_id: formData.advance_id ? Number(formData.advance_id) : undefined,
       })
 
       toast({
@@ -191,7 +219,9 @@ export function EmployeeDashboard() {
         description: "",
         category: "" as ReimbursementCategory,
         amount: "",
+        expense_date: "",
         receipt_url: "",
+        advance_id: "",
       })
       setSelectedFile(null)
       setFilePreview(null)
@@ -208,6 +238,122 @@ export function EmployeeDashboard() {
     }
   }
 
+  // A draft keeps whatever has been filled in so far; the receipt is
+  // uploaded now if one was chosen
+  const handleSaveDraft = async () => {
+    setIsSubmitting(true)
+
+    try {
+      let receiptUrl: string | undefined
+      if (selectedFile) {
+        setIsUploading(true)
+        receiptUrl = (await uploadAPI.uploadReceipt(selectedFile)).url
+        setIsUploading(false)
+      }
+
+      await reimbursementAPI.saveDraft({
+        name: formData.name,
+        title: formData.title,
+        description: formData.description,
+        category: formData.category || undefined,
+        amount: formData.amount || undefined,
+        expense_date: formData.expense_date || undefined,
+        receipt_url: receiptUrl,
+        advance_id: formData.advance_id ? Number(formData.advance_id) : undefined,
+      })
+
+      toast({
+        title: "Berhasil",
+        description: "Draf berhasil disimpan",
+      })
+
+      setOpen(false)
+      setFormData({
+        name: "",
+        title: "",
+        description: "",
+        category: "" as ReimbursementCategory,
+        amount: "",
+        expense_date: "",
+        receipt_url: "",
+        advance_id: "",
+      })
+      setSelectedFile(null)
+      setFilePreview(null)
+      loadData()
+    } catch (error: any) {
+      toast({
+        title: "Error",
+        description: error.message || "Gagal menyimpan draf",
+        variant: "destructive",
+      })
+    } finally {
+      setIsSubmitting(false)
+      setIsUploading(false)
+    }
+  }
+
+  const handleSubmitDraft = async (id: number) => {
+    try {
+      await reimbursementAPI.submit(id)
+      toast({
+        title: "Berhasil",
+        description: "Draf berhasil diajukan",
+      })
+      loadData()
+    } catch (error: any) {
+      toast({
+        title: "Error",
+        description: error.message || "Gagal mengajukan draf",
+        variant: "destructive",
+      })
+    }
+  }
+
+  const handleRequestAdvance = async (e: React.FormEvent) => {
+    e.preventDefault()
+    setIsSubmitting(true)
+
+    try {
+      await advanceAPI.create({ purpose: advanceForm.purpose, amount: advanceForm.amount })
+      toast({
+        title: "Berhasil",
+        description: "Permintaan uang muka berhasil diajukan",
+      })
+      setAdvanceOpen(false)
+      setAdvanceForm({ purpose: "", amount: "" })
+      loadData()
+    } catch (error: any) {
+      toast({
+        title: "Error",
+        description: error.message || "Gagal mengajukan uang muka",
+        variant: "destructive",
+      })
+    } finally {
+      setIsSubmitting(false)
+    }
+  }
+
+  const handleCancelAdvance = async (id: number) => {
+    try {
+      await advanceAPI.cancel(id)
+      toast({
+        title: "Berhasil",
+        description: "Uang muka dibatalkan",
+      })
+      loadData()
+    } catch (error: any) {
+      toast({
+        title: "Error",
+        description: error.message || "Gagal membatalkan uang muka",
+        variant: "destructive",
+      })
+    }
+  }
+
+  // Claims can only be filed against advances paid out and not yet settled
+  const openAdvances = advances.filter((a) => a.status === "disbursed")
+
   return (
     <div className="min-h-screen bg-background">
       {/* Header */}
@@ -282,11 +428,11 @@ export function EmployeeDashboard() {
                       <SelectValue placeholder="Pilih kategori" />
                     </SelectTrigger>
                     <SelectContent>
-                      <SelectItem value="transport">Perjalanan</SelectItem>
-                      <SelectItem value="accommodation">Akomodasi</SelectItem>
-                      <SelectItem value="meals">Makanan</SelectItem>
-                      <SelectItem value="office_supply">Perlengkapan Kantor</SelectItem>
-                      <SelectItem value="other">Lainnya</SelectItem>
+                      {categories.map((c) => (
+                        <SelectItem key={c.code} value={c.code}>
+                          {c.local_display_name || c.display_name}
+                        </SelectItem>
+                      ))}
                     </SelectContent>
                   </Select>
                 </div>
@@ -304,6 +450,43 @@ export function EmployeeDashboard() {
                     disabled={isSubmitting}
                   />
                 </div>
+                <div className="space-y-2">
+                  <Label htmlFor="expense_date">Tanggal Pengeluaran</Label>
+                  <Input
+                    id="expense_date"
+                    type="date"
+                    value={formData.expense_date}
+                    onChange={(e) => setFormData({ ...formData, expense_date: e.target.value })}
+                    max={new Date().toISOString().slice(0, 10)}
+                    required
+                    disabled={isSubmitting}
+                  />
+                </div>
+                {openAdvances.length > 0 && (
+                  <div className="space-y-2">
+                    <Label htmlFor="advance">Uang Muka (opsional)</Label>
+                    <Select
+                      value={formData.advance_id || "none"}
+                      onValueChange={(value) => setFormData({ ...formData, advance_id: value === "none" ? "" : value })}
+                      disabled={isSubmitting}
+                    >
+                      <SelectTrigger id="advance">
+                        <SelectValue placeholder="Tanpa uang muka" />
+                      </SelectTrigger>
+                      <SelectContent>
+                        <SelectItem value="none">Tanpa uang muka</SelectItem>
+                        {openAdvances.map((a) => (
+                          <SelectItem key={a.id} value={String(a.id)}>
+                            #{a.id} {a.purpose} (sisa Rp {formatMoney(a.balance)})
+                          </SelectItem>
+                        ))}
+                      </SelectContent>
+                    </Select>
+                    <p className="text-xs text-muted-foreground">
+                      Klaim dengan uang muka tidak dibayar terpisah, tetapi diperhitungkan saat uang muka diselesaikan
+                    </p>
+                  </div>
+                )}
                 <div className="space-y-2">
                   <Label htmlFor="description">Deskripsi</Label>
                   <Textarea 
@@ -370,6 +553,9 @@ export function EmployeeDashboard() {
                   <Button type="button" variant="outline" onClick={() => setOpen(false)} disabled={isSubmitting}>
                     Batal
                   </Button>
+                  <Button type="button" variant="secondary" onClick={handleSaveDraft} disabled={isSubmitting || isUploading}>
+                    Simpan Draf
+                  </Button>
                   <Button type="submit" disabled={isSubmitting || isUploading}>
                     {isSubmitting || isUploading ? (
                       <>
@@ -436,7 +622,7 @@ export function EmployeeDashboard() {
                 <DollarSign className="h-4 w-4 text-muted-foreground" />
               </CardHeader>
               <CardContent>
-                <div className="text-2xl font-bold">Rp {stats.total_amount.toLocaleString('id-ID')}</div>
+                <div className="text-2xl font-bold">Rp {formatMoney(stats.total_amount)}</div>
               </CardContent>
             </Card>
           </div>
@@ -480,9 +666,9 @@ export function EmployeeDashboard() {
                       <tr key={reimb.id} className="border-b last:border-0">
                         <td className="px-4 py-3 font-mono text-sm">#{reimb.id}</td>
                         <td className="px-4 py-3 text-sm font-medium">{reimb.title}</td>
-                        <td className="px-4 py-3 text-sm">{categoryMap[reimb.category]}</td>
-                        <td className="px-4 py-3 text-sm">{new Date(reimb.submitted_date).toLocaleDateString('id-ID')}</td>
-                        <td className="px-4 py-3 text-right text-sm font-medium">Rp {reimb.amount.toLocaleString('id-ID')}</td>
+                        <td className="px-4 py-3 text-sm">{categoryMap[reimb.category] || reimb.category}</td>
+                        <td className="px-4 py-3 text-sm">{reimb.expense_date ? new Date(reimb.expense_date).toLocaleDateString('id-ID') : '-'}</td>
+                        <td className="px-4 py-3 text-right text-sm font-medium">Rp {formatMoney(reimb.amount)}</td>
                         <td className="px-4 py-3">
                           <Badge
                             variant={
@@ -509,6 +695,15 @@ export function EmployeeDashboard() {
                           </Badge>
                         </td>
                         <td className="px-4 py-3">
+                          {reimb.status === "draft" && (
+                            <Button
+                              size="sm"
+                              variant="outline"
+                              onClick={() => handleSubmitDraft(reimb.id)}
+                            >
+                              Ajukan
+                            </Button>
+                          )}
                           {(reimb.status === "approved_finance" || reimb.status === "completed") && (
                             <Button
                               size="sm"
@@ -528,6 +723,115 @@ export function EmployeeDashboard() {
             )}
           </CardContent>
         </Card>
+
+        {/* Cash Advances */}
+        <Card className="mt-8">
+          <CardHeader className="flex flex-row items-center justify-between">
+            <div>
+              <CardTitle>Uang Muka</CardTitle>
+              <CardDescription>Minta uang muka perjalanan dan lihat sisa yang harus dikembalikan atau ditambahkan</CardDescription>
+            </div>
+            <Dialog open={advanceOpen} onOpenChange={setAdvanceOpen}>
+              <DialogTrigger asChild>
+                <Button variant="outline">
+                  <Plus className="mr-2 h-4 w-4" />
+                  Minta Uang Muka
+                </Button>
+              </DialogTrigger>
+              <DialogContent>
+                <DialogHeader>
+                  <DialogTitle>Minta Uang Muka</DialogTitle>
+                  <DialogDescription>Uang muka disetujui manager Anda lalu dicairkan oleh finance</DialogDescription>
+                </DialogHeader>
+                <form onSubmit={handleRequestAdvance} className="space-y-4">
+                  <div className="space-y-2">
+                    <Label htmlFor="advance_purpose">Keperluan</Label>
+                    <Textarea
+                      id="advance_purpose"
+                      value={advanceForm.purpose}
+                      onChange={(e) => setAdvanceForm({ ...advanceForm, purpose: e.target.value })}
+                      placeholder="Contoh: Survei lokasi dua minggu di Makassar"
+                      rows={3}
+                      required
+                      disabled={isSubmitting}
+                    />
+                  </div>
+                  <div className="space-y-2">
+                    <Label htmlFor="advance_amount">Jumlah (Rp)</Label>
+                    <Input
+                      id="advance_amount"
+                      type="number"
+                      value={advanceForm.amount}
+                      onChange={(e) => setAdvanceForm({ ...advanceForm, amount: e.target.value })}
+                      placeholder="0"
+                      step="1000"
+                      min="0"
+                      required
+                      disabled={isSubmitting}
+                    />
+                  </div>
+                  <div className="flex justify-end gap-2">
+                    <Button type="button" variant="outline" onClick={() => setAdvanceOpen(false)} disabled={isSubmitting}>
+                      Batal
+                    </Button>
+                    <Button type="submit" disabled={isSubmitting}>
+                      {isSubmitting ? <Loader2 className="mr-2 h-4 w-4 animate-spin" /> : null}
+                      Ajukan
+                    </Button>
+                  </div>
+                </form>
+              </DialogContent>
+            </Dialog>
+          </CardHeader>
+          <CardContent>
+            {advances.length === 0 ? (
+              <p className="text-sm text-muted-foreground">Belum ada uang muka</p>
+            ) : (
+              <div className="overflow-x-auto">
+                <table className="w-full">
+                  <thead>
+                    <tr className="border-b">
+                      <th className="px-4 py-3 text-left text-sm font-medium text-muted-foreground">ID</th>
+                      <th className="px-4 py-3 text-left text-sm font-medium text-muted-foreground">Keperluan</th>
+                      <th className="px-4 py-3 text-right text-sm font-medium text-muted-foreground">Jumlah</th>
+                      <th className="px-4 py-3 text-right text-sm font-medium text-muted-foreground">Diklaim</th>
+                      <th className="px-4 py-3 text-right text-sm font-medium text-muted-foreground">Sisa</th>
+                      <th className="px-4 py-3 text-left text-sm font-medium text-muted-foreground">Status</th>
+                      <th className="px-4 py-3 text-left text-sm font-medium text-muted-foreground">Aksi</th>
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {advances.map((a) => (
+                      <tr key={a.id} className="border-b last:border-0">
+                        <td className="px-4 py-3 font-mono text-sm">#{a.id}</td>
+                        <td className="px-4 py-3 text-sm">{a.purpose}</td>
+                        <td className="px-4 py-3 text-right text-sm">Rp {formatMoney(a.amount)}</td>
+                        <td className="px-4 py-3 text-right text-sm">Rp {formatMoney(a.claimed_amount)}</td>
+                        <td className="px-4 py-3 text-right text-sm">
+                          {Number(a.top_up_amount) > 0
+                            ? <span className="text-green-600">Ditambah Rp {formatMoney(a.top_up_amount)}</span>
+                            : <span>Dikembalikan Rp {formatMoney(a.return_amount)}</span>}
+                        </td>
+                        <td className="px-4 py-3">
+                          <Badge variant={a.status === "rejected" || a.status === "cancelled" ? "destructive" : "outline"}>
+                            {advanceStatusMap[a.status]}
+                          </Badge>
+                        </td>
+                        <td className="px-4 py-3">
+                          {(a.status === "pending" || a.status === "approved") && (
+                            <Button size="sm" variant="outline" onClick={() => handleCancelAdvance(a.id)}>
+                              Batalkan
+                            </Button>
+                          )}
+                        </td>
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+              </div>
+            )}
+          </CardContent>
+        </Card>
       </div>
 
       {/* Payment Receipt Dialog */}
@@ -558,7 +862,7 @@ export function EmployeeDashboard() {
               </div>
               <div className="flex justify-between">
                 <span className="font-medium">Kategori:</span>
-                <span>{selectedReceipt && categoryMap[selectedReceipt.category]}</span>
+                <span>{selectedReceipt && (categoryMap[selectedReceipt.category] || selectedReceipt.category)}</span>
               </div>
               <div className="flex justify-between">
                 <span className="font-medium">Deskripsi:</span>
@@ -571,7 +875,7 @@ export function EmployeeDashboard() {
               <div className="flex justify-between items-center pt-4 border-t">
                 <span className="text-lg font-bold">Jumlah Transfer:</span>
                 <span className="text-2xl font-bold text-green-600">
-                  Rp {selectedReceipt?.amount.toLocaleString('id-ID')}
+                  Rp {formatMoney(selectedReceipt?.amount)}
                 </span>
               </div>
             </div>
@@ -624,7 +928,7 @@ export function EmployeeDashboard() {
                     </div>
                     <div class="row">
                       <div class="label">Kategori:</div>
-                      <div class="value">${selectedReceipt && categoryMap[selectedReceipt.category]}</div>
+                      <div class="value">${selectedReceipt && (categoryMap[selectedReceipt.category] || selectedReceipt.category)}</div>
                     </div>
                     <div class="row">
                       <div class="label">Deskripsi:</div>
@@ -636,7 +940,7 @@ export function EmployeeDashboard() {
                     </div>
                     <div class="amount">
                       <div class="label">Jumlah Transfer:</div>
-                      <div>Rp ${selectedReceipt?.amount.toLocaleString('id-ID')}</div>
+                      <div>Rp ${formatMoney(selectedReceipt?.amount)}</div>
                     </div>
                   </div>
                   <div class="footer">
@@ -10,8 +10,9 @@ import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@
 import { Dialog, DialogContent, DialogDescription, DialogHeader, DialogTitle } from "@/components/ui/dialog"
 import { Textarea } from "@/components/ui/textarea"
 import { Label } from "@/components/ui/label"
-import { FileText, CheckCircle, DollarSign, TrendingUp, Download, LogOut, Filter, Loader2, XCircle, Eye, Clock, Printer } from "lucide-react"
-import { authAPI, financeAPI, reimbursementAPI, getFileUrl, type Reimbursement, type ReimbursementStats } from "@/lib/api"
+import { FileText, CheckCircle, DollarSign, TrendingUp, Download, LogOut, Filter, Loader2, XCircle, Eye, Clock, Printer, Wallet } from "lucide-react"
+import { advanceAPI, authAPI, financeAPI, reimbursementAPI, getFileUrl, type AdvanceReport, type CashAdvance, type PaymentMethod, type Reimbursement, type ReimbursementStats } from "@/lib/api"
+import { formatMoney } from "@/lib/utils"
 import { useToast } from "@/hooks/use-toast"
 
 const categoryMap: Record<string, string> = {
@@ -19,6 +20,8 @@ const categoryMap: Record<string, string> = {
   accommodation: "Akomodasi",
   meals: "Makanan",
   office_supply: "Perlengkapan Kantor",
+  mileage: "Jarak Tempuh",
+  per_diem: "Uang Harian",
   other: "Lainnya",
 }
 
@@ -31,6 +34,12 @@ const statusMap: Record<string, string> = {
   completed: "Selesai",
 }
 
+const advanceStatusMap: Record<string, string> = {
+  pending: "Menunggu Persetujuan",
+  approved: "Menunggu Pencairan",
+  disbursed: "Berjalan",
+}
+
 export function FinanceDashboard() {
   const router = useRouter()
   const { toast } = useToast()
@@ -45,7 +54,12 @@ export function FinanceDashboard() {
   const [isSubmitting, setIsSubmitting] = useState(false)
   const [user, setUser] = useState<any>(null)
   const [rejectNotes, setRejectNotes] = useState("")
-  const [activeTab, setActiveTab] = useState<'pending' | 'history'>('pending')
+  const [activeTab, setActiveTab] = useState<'pending' | 'history' | 'advances'>('pending')
+  const [advances, setAdvances] = useState<CashAdvance[]>([])
+  const [advanceReport, setAdvanceReport] = useState<AdvanceReport | null>(null)
+  const [advanceAction, setAdvanceAction] = useState<{ advance: CashAdvance; kind: 'disburse' | 'settle' } | null>(null)
+  const [advanceMethod, setAdvanceMethod] = useState<PaymentMethod>('bank_transfer')
+  const [advanceReference, setAdvanceReference] = useState("")
   const [showReceiptDialog, setShowReceiptDialog] = useState(false)
   const [receiptClaim, setReceiptClaim] = useState<Reimbursement | null>(null)
 
@@ -69,12 +83,20 @@ export function FinanceDashboard() {
   const loadData = async () => {
     try {
       setIsLoading(true)
-      const [pendingData, allData, statsData] = await Promise.all([
+      const [pendingData, allData, statsData, advanceData, advanceReportData] = await Promise.all([
         financeAPI.getPending(),
         reimbursementAPI.getAll(),
         reimbursementAPI.getStats(),
+        advanceAPI.getAll(),
+        financeAPI.getAdvanceReport(),
       ])
       setApprovedClaims(pendingData || [])
+      // Advances finance still has to act on: those without a manager to
+      // approve them, those to pay out and those to settle
+      setAdvances((advanceData || []).filter(
+        (a) => (a.status === 'pending' && !a.approver_id) || a.status === 'approved' || a.status === 'disbursed'
+      ))
+      setAdvanceReport(advanceReportData)
       // Filter claims that have been reviewed by finance
       const reviewedClaims = (allData || []).filter(
         (claim) => claim.status === 'approved_finance' || claim.status === 'rejected_finance' || claim.status === 'completed'
@@ -99,10 +121,10 @@ export function FinanceDashboard() {
     router.push('/login')
   }
 
-  const handleApprove = async (id: number) => {
+  const handleApprove = async (id: number, version: number) => {
     try {
       setIsSubmitting(true)
-      await financeAPI.approve(id, { action: 'approve' })
+      await financeAPI.approve(id, { action: 'approve', version })
       toast({
         title: "Berhasil",
         description: "Klaim berhasil divalidasi",
@@ -127,6 +149,7 @@ export function FinanceDashboard() {
       setIsSubmitting(true)
       await financeAPI.approve(selectedClaim.id, {
         action: 'reject',
+        version: selectedClaim.version,
         notes: rejectNotes,
       })
       toast({
@@ -195,7 +218,7 @@ export function FinanceDashboard() {
           </div>
           <div class="row">
             <div class="label">Kategori:</div>
-            <div class="value">${receiptClaim && categoryMap[receiptClaim.category]}</div>
+            <div class="value">${receiptClaim && (categoryMap[receiptClaim.category] || receiptClaim.category)}</div>
           </div>
           <div class="row">
             <div class="label">Deskripsi:</div>
@@ -211,7 +234,7 @@ export function FinanceDashboard() {
           </div>
           <div class="amount">
             <div class="label">Jumlah Transfer:</div>
-            <div>Rp ${receiptClaim?.amount.toLocaleString('id-ID')}</div>
+            <div>Rp ${formatMoney(receiptClaim?.amount)}</div>
           </div>
         </div>
         <div class="footer">
@@ -238,6 +261,67 @@ export function FinanceDashboard() {
     }, 250)
   }
 
+  const handleDecideAdvance = async (id: number, action: 'approve' | 'reject') => {
+    try {
+      setIsSubmitting(true)
+      await advanceAPI.decide(id, action)
+      toast({
+        title: "Berhasil",
+        description: action === 'approve' ? "Uang muka berhasil disetujui" : "Uang muka berhasil ditolak",
+      })
+      loadData()
+    } catch (error: any) {
+      toast({
+        title: "Error",
+        description: error.message || "Gagal memproses uang muka",
+        variant: "destructive",
+      })
+    } finally {
+      setIsSubmitting(false)
+    }
+  }
+
+  // Disbursing pays the advance out; settling closes it against its claims,
+  // recording how the balance was returned or topped up
+  const handleAdvanceAction = async (e: React.FormEvent) => {
+    e.preventDefault()
+    if (!advanceAction) return
+
+    const today = new Date().toISOString().slice(0, 10)
+    try {
+      setIsSubmitting(true)
+      if (advanceAction.kind === 'disburse') {
+        await advanceAPI.disburse(advanceAction.advance.id, {
+          disbursed_date: today,
+          method: advanceMethod,
+          reference: advanceReference,
+        })
+      } else {
+        const settled = Number(advanceAction.advance.balance) === 0
+        await advanceAPI.settle(advanceAction.advance.id, {
+          settled_date: today,
+          method: settled ? undefined : advanceMethod,
+          reference: settled ? undefined : advanceReference,
+        })
+      }
+      toast({
+        title: "Berhasil",
+        description: advanceAction.kind === 'disburse' ? "Uang muka berhasil dicairkan" : "Uang muka berhasil diselesaikan",
+      })
+      setAdvanceAction(null)
+      setAdvanceReference("")
+      loadData()
+    } catch (error: any) {
+      toast({
+        title: "Error",
+        description: error.message || "Gagal memproses uang muka",
+        variant: "destructive",
+      })
+    } finally {
+      setIsSubmitting(false)
+    }
+  }
+
   return (
     <div className="min-h-screen bg-background">
       {/* Header */}
@@ -321,7 +405,7 @@ export function FinanceDashboard() {
                 <DollarSign className="h-4 w-4 text-muted-foreground" />
               </CardHeader>
               <CardContent>
-                <div className="text-2xl font-bold">Rp {stats.total_amount.toLocaleString('id-ID')}</div>
+                <div className="text-2xl font-bold">Rp {formatMoney(stats.total_amount)}</div>
               </CardContent>
             </Card>
           </div>
@@ -343,6 +427,13 @@ export function FinanceDashboard() {
             <FileText className="mr-2 h-4 w-4" />
             Riwayat ({allClaims.length})
           </Button>
+          <Button
+            variant={activeTab === 'advances' ? 'default' : 'outline'}
+            onClick={() => setActiveTab('advances')}
+          >
+            <Wallet className="mr-2 h-4 w-4" />
+            Uang Muka ({advances.length})
+          </Button>
         </div>
 
         {/* Pending Claims Table */}
@@ -388,10 +479,15 @@ export function FinanceDashboard() {
                       <tr key={claim.id} className="border-b last:border-0">
                         <td className="px-4 py-3 font-mono text-sm">#{claim.id}</td>
                         <td className="px-4 py-3 text-sm font-medium">{claim.employee_name}</td>
-                        <td className="px-4 py-3 text-sm">{categoryMap[claim.category]}</td>
+                        <td className="px-4 py-3 text-sm">{categoryMap[claim.category] || claim.category}</td>
                         <td className="px-4 py-3 text-sm">{new Date(claim.submitted_date).toLocaleDateString('id-ID')}</td>
-                        <td className="px-4 py-3 text-right text-sm font-medium">Rp {claim.amount.toLocaleString('id-ID')}</td>
+                        <td className="px-4 py-3 text-right text-sm font-medium">Rp {formatMoney(claim.amount)}</td>
                         <td className="px-4 py-3">
+                          {claim.late && (
+                            <Badge variant="destructive" className="mr-2" title={claim.late_reason}>
+                              Terlambat
+                            </Badge>
+                          )}
                           <Badge variant="outline">
                             {statusMap[claim.status]}
                           </Badge>
@@ -410,7 +506,7 @@ export function FinanceDashboard() {
                             <Button 
                               size="sm" 
                               className="bg-green-500 text-white hover:bg-green-600"
-                              onClick={() => handleApprove(claim.id)}
+                              onClick={() => handleApprove(claim.id, claim.version)}
                               disabled={isSubmitting}
                             >
                               {isSubmitting ? (
@@ -483,9 +579,9 @@ export function FinanceDashboard() {
                         <tr key={claim.id} className="border-b last:border-0">
                           <td className="px-4 py-3 font-mono text-sm">#{claim.id}</td>
                           <td className="px-4 py-3 text-sm font-medium">{claim.employee_name}</td>
-                          <td className="px-4 py-3 text-sm">{categoryMap[claim.category]}</td>
+                          <td className="px-4 py-3 text-sm">{categoryMap[claim.category] || claim.category}</td>
                           <td className="px-4 py-3 text-sm">{new Date(claim.submitted_date).toLocaleDateString('id-ID')}</td>
-                          <td className="px-4 py-3 text-right text-sm font-medium">Rp {claim.amount.toLocaleString('id-ID')}</td>
+                          <td className="px-4 py-3 text-right text-sm font-medium">Rp {formatMoney(claim.amount)}</td>
                           <td className="px-4 py-3">
                             <Badge
                               variant={
@@ -527,8 +623,151 @@ export function FinanceDashboard() {
             </CardContent>
           </Card>
         )}
+
+        {/* Cash Advances */}
+        {activeTab === 'advances' && (
+          <Card>
+            <CardHeader>
+              <CardTitle>Uang Muka</CardTitle>
+              <CardDescription>
+                {advanceReport
+                  ? `${advanceReport.rows.length} uang muka berjalan, Rp ${formatMoney(advanceReport.total_amount)} dicairkan, sisa di karyawan Rp ${formatMoney(advanceReport.total_balance)}`
+                  : 'Uang muka yang perlu disetujui, dicairkan atau diselesaikan'}
+              </CardDescription>
+            </CardHeader>
+            <CardContent>
+              {advances.length === 0 ? (
+                <div className="text-center py-12">
+                  <Wallet className="mx-auto h-12 w-12 text-muted-foreground/50" />
+                  <h3 className="mt-4 text-lg font-semibold">Tidak ada uang muka yang perlu diproses</h3>
+                </div>
+              ) : (
+                <div className="overflow-x-auto">
+                  <table className="w-full">
+                    <thead>
+                      <tr className="border-b">
+                        <th className="px-4 py-3 text-left text-sm font-medium text-muted-foreground">ID</th>
+                        <th className="px-4 py-3 text-left text-sm font-medium text-muted-foreground">Karyawan</th>
+                        <th className="px-4 py-3 text-left text-sm font-medium text-muted-foreground">Keperluan</th>
+                        <th className="px-4 py-3 text-right text-sm font-medium text-muted-foreground">Jumlah</th>
+                        <th className="px-4 py-3 text-right text-sm font-medium text-muted-foreground">Diklaim</th>
+                        <th className="px-4 py-3 text-right text-sm font-medium text-muted-foreground">Kembali / Tambahan</th>
+                        <th className="px-4 py-3 text-left text-sm font-medium text-muted-foreground">Status</th>
+                        <th className="px-4 py-3 text-left text-sm font-medium text-muted-foreground">Aksi</th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {advances.map((advance) => (
+                        <tr key={advance.id} className="border-b last:border-0">
+                          <td className="px-4 py-3 font-mono text-sm">#{advance.id}</td>
+                          <td className="px-4 py-3 text-sm">{advance.employee_name}</td>
+                          <td className="px-4 py-3 text-sm">{advance.purpose}</td>
+                          <td className="px-4 py-3 text-right text-sm">Rp {formatMoney(advance.amount)}</td>
+                          <td className="px-4 py-3 text-right text-sm">Rp {formatMoney(advance.claimed_amount)}</td>
+                          <td className="px-4 py-3 text-right text-sm">
+                            {Number(advance.top_up_amount) > 0
+                              ? <span className="text-green-600">+Rp {formatMoney(advance.top_up_amount)}</span>
+                              : <span>-Rp {formatMoney(advance.return_amount)}</span>}
+                          </td>
+                          <td className="px-4 py-3">
+                            <Badge variant="outline">{advanceStatusMap[advance.status]}</Badge>
+                          </td>
+                          <td className="px-4 py-3">
+                            <div className="flex gap-2">
+                              {advance.status === 'pending' && (
+                                <>
+                                  <Button size="sm" onClick={() => handleDecideAdvance(advance.id, 'approve')} disabled={isSubmitting}>
+                                    Setujui
+                                  </Button>
+                                  <Button size="sm" variant="destructive" onClick={() => handleDecideAdvance(advance.id, 'reject')} disabled={isSubmitting}>
+                                    Tolak
+                                  </Button>
+                                </>
+                              )}
+                              {advance.status === 'approved' && (
+                                <>
+                                  <Button size="sm" onClick={() => setAdvanceAction({ advance, kind: 'disburse' })} disabled={isSubmitting}>
+                                    Cairkan
+                                  </Button>
+                                  <Button size="sm" variant="destructive" onClick={() => handleDecideAdvance(advance.id, 'reject')} disabled={isSubmitting}>
+                                    Tolak
+                                  </Button>
+                                </>
+                              )}
+                              {advance.status === 'disbursed' && (
+                                <Button size="sm" variant="outline" onClick={() => setAdvanceAction({ advance, kind: 'settle' })} disabled={isSubmitting}>
+                                  Selesaikan
+                                </Button>
+                              )}
+                            </div>
+                          </td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
+                </div>
+              )}
+            </CardContent>
+          </Card>
+        )}
       </div>
 
+      {/* Cash Advance Disburse/Settle Dialog */}
+      <Dialog open={advanceAction !== null} onOpenChange={(open) => !open && setAdvanceAction(null)}>
+        <DialogContent>
+          <DialogHeader>
+            <DialogTitle>
+              {advanceAction?.kind === 'disburse' ? 'Cairkan Uang Muka' : 'Selesaikan Uang Muka'} #{advanceAction?.advance.id}
+            </DialogTitle>
+            <DialogDescription>
+              {advanceAction?.kind === 'disburse'
+                ? `Catat pencairan Rp ${formatMoney(advanceAction?.advance.amount)} kepada ${advanceAction?.advance.employee_name}`
+                : Number(advanceAction?.advance.top_up_amount) > 0
+                  ? `Klaim melebihi uang muka: bayarkan tambahan Rp ${formatMoney(advanceAction?.advance.top_up_amount)}`
+                  : `Karyawan mengembalikan sisa Rp ${formatMoney(advanceAction?.advance.return_amount)}`}
+            </DialogDescription>
+          </DialogHeader>
+          <form onSubmit={handleAdvanceAction} className="space-y-4">
+            {(advanceAction?.kind === 'disburse' || Number(advanceAction?.advance.balance) !== 0) && (
+              <>
+                <div className="space-y-2">
+                  <Label htmlFor="advance_method">Metode</Label>
+                  <Select value={advanceMethod} onValueChange={(value) => setAdvanceMethod(value as PaymentMethod)}>
+                    <SelectTrigger id="advance_method">
+                      <SelectValue />
+                    </SelectTrigger>
+                    <SelectContent>
+                      <SelectItem value="bank_transfer">Transfer Bank</SelectItem>
+                      <SelectItem value="cash">Tunai</SelectItem>
+                      <SelectItem value="payroll">Payroll</SelectItem>
+                    </SelectContent>
+                  </Select>
+                </div>
+                <div className="space-y-2">
+                  <Label htmlFor="advance_reference">Referensi</Label>
+                  <Input
+                    id="advance_reference"
+                    value={advanceReference}
+                    onChange={(e) => setAdvanceReference(e.target.value)}
+                    placeholder="Contoh: TRF-20240104-0007"
+                    required
+                  />
+                </div>
+              </>
+            )}
+            <div className="flex justify-end gap-2">
+              <Button type="button" variant="outline" onClick={() => setAdvanceAction(null)} disabled={isSubmitting}>
+                Batal
+              </Button>
+              <Button type="submit" disabled={isSubmitting}>
+                {isSubmitting && <Loader2 className="mr-2 h-4 w-4 animate-spin" />}
+                Simpan
+              </Button>
+            </div>
+          </form>
+        </DialogContent>
+      </Dialog>
+
       {/* View Receipt Dialog */}
       <Dialog
         open={selectedClaim !== null && !showRejectDialog}
@@ -538,7 +777,7 @@ export function FinanceDashboard() {
           <DialogHeader>
             <DialogTitle>Detail Klaim - #{selectedClaim?.id}</DialogTitle>
             <DialogDescription>
-              {selectedClaim?.employee_name} • {selectedClaim && categoryMap[selectedClaim.category]} • Rp {selectedClaim?.amount.toLocaleString('id-ID')}
+              {selectedClaim?.employee_name} • {selectedClaim && (categoryMap[selectedClaim.category] || selectedClaim.category)} • Rp {formatMoney(selectedClaim?.amount)}
             </DialogDescription>
           </DialogHeader>
           <div className="space-y-4">
@@ -674,7 +913,7 @@ export function FinanceDashboard() {
               </div>
               <div className="flex justify-between">
                 <span className="font-medium">Kategori:</span>
-                <span>{receiptClaim && categoryMap[receiptClaim.category]}</span>
+                <span>{receiptClaim && (categoryMap[receiptClaim.category] || receiptClaim.category)}</span>
               </div>
               <div className="flex justify-between">
                 <span className="font-medium">Tanggal Pengajuan:</span>
@@ -687,7 +926,7 @@ export function FinanceDashboard() {
               <div className="flex justify-between items-center pt-4 border-t">
                 <span className="text-lg font-bold">Jumlah Transfer:</span>
                 <span className="text-2xl font-bold text-green-600">
-                  Rp {receiptClaim?.amount.toLocaleString('id-ID')}
+                  Rp {formatMoney(receiptClaim?.amount)}
                 </span>
               </div>
             </div>
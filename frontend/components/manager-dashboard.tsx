@@ -8,8 +8,9 @@ import { Badge } from "@/components/ui/badge"
 import { Dialog, DialogContent, DialogDescription, DialogHeader, DialogTitle } from "@/components/ui/dialog"
 import { Textarea } from "@/components/ui/textarea"
 import { Label } from "@/components/ui/label"
-import { FileText, CheckCircle, XCircle, Clock, Users, DollarSign, LogOut, Eye, Loader2 } from "lucide-react"
-import { authAPI, managerAPI, reimbursementAPI, getFileUrl, type Reimbursement, type ReimbursementStats, type ReimbursementCategory } from "@/lib/api"
+import { FileText, CheckCircle, XCircle, Clock, Users, DollarSign, LogOut, Eye, Loader2, Wallet } from "lucide-react"
+import { advanceAPI, authAPI, managerAPI, reimbursementAPI, getFileUrl, type CashAdvance, type Reimbursement, type ReimbursementStats, type ReimbursementCategory } from "@/lib/api"
+import { formatMoney } from "@/lib/utils"
 import { useToast } from "@/hooks/use-toast"
 
 const categoryMap: Record<string, string> = {
@@ -17,6 +18,8 @@ const categoryMap: Record<string, string> = {
   accommodation: "Akomodasi",
   meals: "Makanan",
   office_supply: "Perlengkapan Kantor",
+  mileage: "Jarak Tempuh",
+  per_diem: "Uang Harian",
   other: "Lainnya",
 }
 
@@ -32,7 +35,8 @@ export function ManagerDashboard() {
   const [isSubmitting, setIsSubmitting] = useState(false)
   const [user, setUser] = useState<any>(null)
   const [rejectNotes, setRejectNotes] = useState("")
-  const [activeTab, setActiveTab] = useState<'pending' | 'history'>('pending')
+  const [activeTab, setActiveTab] = useState<'pending' | 'history' | 'advances'>('pending')
+  const [pendingAdvances, setPendingAdvances] = useState<CashAdvance[]>([])
 
   useEffect(() => {
     // Check authentication
@@ -54,12 +58,15 @@ export function ManagerDashboard() {
   const loadData = async () => {
     try {
       setIsLoading(true)
-      const [pendingData, allData, statsData] = await Promise.all([
+      const [pendingData, allData, statsData, advanceData] = await Promise.all([
         managerAPI.getPending(),
         reimbursementAPI.getAll(),
         reimbursementAPI.getStats(),
+        advanceAPI.getAll('pending'),
       ])
       setPendingClaims(pendingData || [])
+      // Only the advances waiting on this manager can be decided here
+      setPendingAdvances((advanceData || []).filter((a) => a.approver_id === authAPI.getCurrentUser()?.id))
       // Filter claims that have been reviewed by manager
       const reviewedClaims = (allData || []).filter(
         (claim) => claim.status !== 'pending'
@@ -84,10 +91,10 @@ export function ManagerDashboard() {
     router.push('/login')
   }
 
-  const handleApprove = async (id: number) => {
+  const handleApprove = async (id: number, version: number) => {
     try {
       setIsSubmitting(true)
-      await managerAPI.approve(id, { action: 'approve' })
+      await managerAPI.approve(id, { action: 'approve', version })
       toast({
         title: "Berhasil",
         description: "Klaim berhasil disetujui",
@@ -112,6 +119,7 @@ export function ManagerDashboard() {
       setIsSubmitting(true)
       await managerAPI.approve(selectedClaim.id, {
         action: 'reject',
+        version: selectedClaim.version,
         notes: rejectNotes,
       })
       toast({
@@ -133,6 +141,26 @@ export function ManagerDashboard() {
     }
   }
 
+  const handleDecideAdvance = async (id: number, action: 'approve' | 'reject') => {
+    try {
+      setIsSubmitting(true)
+      await advanceAPI.decide(id, action)
+      toast({
+        title: "Berhasil",
+        description: action === 'approve' ? "Uang muka berhasil disetujui" : "Uang muka berhasil ditolak",
+      })
+      loadData()
+    } catch (error: any) {
+      toast({
+        title: "Error",
+        description: error.message || "Gagal memproses uang muka",
+        variant: "destructive",
+      })
+    } finally {
+      setIsSubmitting(false)
+    }
+  }
+
   return (
     <div className="min-h-screen bg-background">
       {/* Header */}
@@ -210,7 +238,7 @@ export function ManagerDashboard() {
                 <Users className="h-4 w-4 text-muted-foreground" />
               </CardHeader>
               <CardContent>
-                <div className="text-2xl font-bold">Rp {stats.total_amount.toLocaleString('id-ID')}</div>
+                <div className="text-2xl font-bold">Rp {formatMoney(stats.total_amount)}</div>
               </CardContent>
             </Card>
           </div>
@@ -232,6 +260,13 @@ export function ManagerDashboard() {
             <FileText className="mr-2 h-4 w-4" />
             Riwayat ({allClaims.length})
           </Button>
+          <Button
+            variant={activeTab === 'advances' ? 'default' : 'outline'}
+            onClick={() => setActiveTab('advances')}
+          >
+            <Wallet className="mr-2 h-4 w-4" />
+            Uang Muka ({pendingAdvances.length})
+          </Button>
         </div>
 
         {/* Pending Claims */}
@@ -266,13 +301,18 @@ export function ManagerDashboard() {
                             <Clock className="mr-1 h-3 w-3" />
                             Tertunda
                           </Badge>
+                          {claim.late && (
+                            <Badge variant="destructive" title={claim.late_reason}>
+                              Terlambat
+                            </Badge>
+                          )}
                         </div>
                         <p className="font-medium">{claim.employee_name}</p>
                         <p className="text-sm text-muted-foreground">
-                          {new Date(claim.submitted_date).toLocaleDateString('id-ID')} • {categoryMap[claim.category]}
+                          {new Date(claim.expense_date ?? claim.submitted_date).toLocaleDateString('id-ID')} • {categoryMap[claim.category] || claim.category}
                         </p>
                       </div>
-                      <p className="text-xl font-bold">Rp {claim.amount.toLocaleString('id-ID')}</p>
+                      <p className="text-xl font-bold">Rp {formatMoney(claim.amount)}</p>
                     </div>
                     <p className="mb-4 text-sm text-muted-foreground">{claim.description}</p>
                     <div className="flex gap-2">
@@ -283,7 +323,7 @@ export function ManagerDashboard() {
                       <Button 
                         size="sm" 
                         className="bg-green-500 text-white hover:bg-green-600"
-                        onClick={() => handleApprove(claim.id)}
+                        onClick={() => handleApprove(claim.id, claim.version)}
                         disabled={isSubmitting}
                       >
                         {isSubmitting ? (
@@ -353,9 +393,9 @@ export function ManagerDashboard() {
                         <tr key={claim.id} className="border-b last:border-0">
                           <td className="px-4 py-3 font-mono text-sm">#{claim.id}</td>
                           <td className="px-4 py-3 text-sm font-medium">{claim.employee_name}</td>
-                          <td className="px-4 py-3 text-sm">{categoryMap[claim.category]}</td>
+                          <td className="px-4 py-3 text-sm">{categoryMap[claim.category] || claim.category}</td>
                           <td className="px-4 py-3 text-sm">{new Date(claim.submitted_date).toLocaleDateString('id-ID')}</td>
-                          <td className="px-4 py-3 text-right text-sm font-medium">Rp {claim.amount.toLocaleString('id-ID')}</td>
+                          <td className="px-4 py-3 text-right text-sm font-medium">Rp {formatMoney(claim.amount)}</td>
                           <td className="px-4 py-3">
                             <Badge
                               variant={
@@ -392,6 +432,62 @@ export function ManagerDashboard() {
             </CardContent>
           </Card>
         )}
+
+        {/* Cash Advances */}
+        {activeTab === 'advances' && (
+          <Card>
+            <CardHeader>
+              <CardTitle>Permintaan Uang Muka</CardTitle>
+              <CardDescription>Uang muka yang menunggu persetujuan Anda sebelum dicairkan oleh finance</CardDescription>
+            </CardHeader>
+            <CardContent>
+              {pendingAdvances.length === 0 ? (
+                <div className="text-center py-12">
+                  <Wallet className="mx-auto h-12 w-12 text-muted-foreground/50" />
+                  <h3 className="mt-4 text-lg font-semibold">Tidak ada permintaan uang muka</h3>
+                </div>
+              ) : (
+                <div className="space-y-4">
+                  {pendingAdvances.map((advance) => (
+                    <div key={advance.id} className="rounded-lg border bg-card p-4">
+                      <div className="mb-3 flex items-start justify-between">
+                        <div>
+                          <span className="font-mono text-sm font-medium">#{advance.id}</span>
+                          <p className="font-medium">{advance.employee_name}</p>
+                          <p className="text-sm text-muted-foreground">
+                            {new Date(advance.created_at).toLocaleDateString('id-ID')}
+                          </p>
+                        </div>
+                        <p className="text-xl font-bold">Rp {formatMoney(advance.amount)}</p>
+                      </div>
+                      <p className="mb-4 text-sm text-muted-foreground">{advance.purpose}</p>
+                      <div className="flex gap-2">
+                        <Button
+                          size="sm"
+                          className="bg-green-500 text-white hover:bg-green-600"
+                          onClick={() => handleDecideAdvance(advance.id, 'approve')}
+                          disabled={isSubmitting}
+                        >
+                          <CheckCircle className="mr-2 h-4 w-4" />
+                          Setujui
+                        </Button>
+                        <Button
+                          variant="destructive"
+                          size="sm"
+                          onClick={() => handleDecideAdvance(advance.id, 'reject')}
+                          disabled={isSubmitting}
+                        >
+                          <XCircle className="mr-2 h-4 w-4" />
+                          Tolak
+                        </Button>
+                      </div>
+                    </div>
+                  ))}
+                </div>
+              )}
+            </CardContent>
+          </Card>
+        )}
       </div>
 
       {/* View Receipt Dialog */}
@@ -403,7 +499,7 @@ export function ManagerDashboard() {
           <DialogHeader>
             <DialogTitle>Kwitansi - #{selectedClaim?.id}</DialogTitle>
             <DialogDescription>
-              {selectedClaim?.employee_name} • {selectedClaim && categoryMap[selectedClaim.category]} • Rp {selectedClaim?.amount.toLocaleString('id-ID')}
+              {selectedClaim?.employee_name} • {selectedClaim && (categoryMap[selectedClaim.category] || selectedClaim.category)} • Rp {formatMoney(selectedClaim?.amount)}
             </DialogDescription>
           </DialogHeader>
           <div className="rounded-lg border bg-muted p-4">
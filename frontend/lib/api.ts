@@ -5,20 +5,22 @@ const BACKEND_URL = process.env.NEXT_PUBLIC_BACKEND_URL || 'http://localhost:808
 // Types
 export type UserRole = 'employee' | 'manager' | 'finance';
 
+// Amounts are exact decimal strings such as "1250000.50"
+export type Money = string;
+
 export type ReimbursementStatus = 
+  | 'draft'
   | 'pending' 
   | 'approved_manager' 
   | 'rejected_manager' 
   | 'approved_finance' 
   | 'rejected_finance' 
-  | 'completed';
+  | 'completed'
+  | 'needs_revision'
+  | 'cancelled';
 
-export type ReimbursementCategory = 
-  | 'transport' 
-  | 'accommodation' 
-  | 'meals' 
-  | 'office_supply' 
-  | 'other';
+// Category code, e.g. 'transport'; see categoryAPI for the managed list
+export type ReimbursementCategory = string;
 
 export interface User {
   id: number;
@@ -26,6 +28,7 @@ export interface User {
   full_name: string;
   email: string;
   role: UserRole;
+  manager_id?: number;
   created_at: string;
   updated_at: string;
 }
@@ -37,20 +40,267 @@ export interface Reimbursement {
   title: string;
   description: string;
   category: ReimbursementCategory;
-  amount: number;
+  amount: Money;
+  currency?: string;
+  original_amount?: Money;
+  exchange_rate?: number;
+  approved_amount?: Money;
+  amount_adjustment_reason?: string;
   receipt_url: string;
   status: ReimbursementStatus;
+  expense_date?: string;
   submitted_date: string;
+  late: boolean;
+  late_reason?: string;
+  advance_id?: number;
+  approval_chain_id?: number;
+  current_step: number;
+  current_approver_id?: number;
+  revision_count: number;
+  version: number;
+  step_entered_at: string;
+  overdue: boolean;
+  cancel_reason?: string;
+  cancelled_at?: string;
+  approvals?: Approval[];
+  lines?: ReimbursementLine[];
+  allocations?: Allocation[];
+  draft?: CreateReimbursementRequest;
   manager_id?: number;
+  manager_on_behalf_of_id?: number;
   manager_notes?: string;
   manager_approved?: string;
   finance_id?: number;
+  finance_on_behalf_of_id?: number;
   finance_notes?: string;
   finance_approved?: string;
   created_at: string;
   updated_at: string;
 }
 
+export type PaymentMethod = 'bank_transfer' | 'cash' | 'payroll';
+
+export interface Payment {
+  id: number;
+  reimbursement_id: number;
+  amount: Money;
+  method: PaymentMethod;
+  reference: string;
+  payment_date: string;
+  paid_by?: number;
+  reversed_at?: string;
+  reversed_by?: number;
+  reversal_reason?: string;
+  created_at: string;
+}
+
+export type NotificationKind = 'reminder' | 'escalation' | 'overdue';
+
+export interface Notification {
+  id: number;
+  reimbursement_id: number;
+  recipient_id?: number;
+  recipient_role?: UserRole;
+  kind: NotificationKind;
+  message: string;
+  step_order: number;
+  step_entered_at: string;
+  created_at: string;
+}
+
+export interface DutyRule {
+  code: string;
+  description: string;
+  enabled: boolean;
+  updated_at: string;
+}
+
+export interface DutyViolation {
+  rule: string;
+  reimbursement_id: number;
+  user_id: number;
+  details: string;
+  decided_at?: string;
+}
+
+export interface Comment {
+  id: number;
+  reimbursement_id: number;
+  author_id: number;
+  author_name: string;
+  author_role: UserRole;
+  body: string;
+  attachments: string[];
+  created_at: string;
+}
+
+export interface CreateCommentRequest {
+  body: string;
+  attachments?: string[];
+}
+
+export interface ReimbursementEvent {
+  id: number;
+  reimbursement_id: number;
+  action: string;
+  actor_id?: number;
+  actor_role?: UserRole;
+  from_status?: ReimbursementStatus;
+  to_status?: ReimbursementStatus;
+  notes?: string;
+  ip_address?: string;
+  user_agent?: string;
+  created_at: string;
+}
+
+export type LineStatus = 'pending' | 'accepted' | 'rejected';
+
+// Optional VAT (PPN) breakdown of an expense, in its currency
+export interface TaxDetails {
+  net_amount?: Money;
+  tax_rate?: number;
+  tax_amount?: Money;
+  seller_tax_id?: string;
+  tax_invoice_number?: string;
+}
+
+export type VehicleType = 'car' | 'motorcycle';
+
+// The trip of a mileage line; its amount is computed by the server
+export interface MileageDetails {
+  origin?: string;
+  destination?: string;
+  distance_km?: number;
+  vehicle_type?: VehicleType;
+}
+
+export interface ProvidedMeals {
+  date: string;
+  breakfast: boolean;
+  lunch: boolean;
+  dinner: boolean;
+}
+
+// The trip of a per-diem line; its amount is computed by the server
+export interface PerDiemDetails {
+  trip_start?: string;
+  trip_end?: string;
+  location?: string;
+  provided_meals?: ProvidedMeals[];
+}
+
+export interface PerDiemDay extends ProvidedMeals {
+  reduction: Money;
+  amount: Money;
+}
+
+export interface PerDiemBreakdown {
+  rate_id: number;
+  location: string;
+  daily_rate: Money;
+  breakfast_percent: number;
+  lunch_percent: number;
+  dinner_percent: number;
+  days: PerDiemDay[];
+  total: Money;
+}
+
+export interface ReimbursementLine extends TaxDetails, MileageDetails, PerDiemDetails {
+  id: number;
+  reimbursement_id: number;
+  line_no: number;
+  category: ReimbursementCategory;
+  description: string;
+  amount: Money;
+  currency: string;
+  original_amount: Money;
+  exchange_rate: number;
+  expense_date: string;
+  receipt_url: string;
+  status: LineStatus;
+  rejection_reason?: string;
+  decided_by?: number;
+  decided_at?: string;
+  mileage_rate?: Money;
+  per_diem?: PerDiemBreakdown;
+}
+
+export interface CreateLineRequest extends TaxDetails, MileageDetails, PerDiemDetails {
+  category: ReimbursementCategory;
+  description: string;
+  amount?: Money;
+  currency?: string;
+  expense_date?: string;
+  receipt_url?: string;
+}
+
+export interface LineDecision {
+  id: number;
+  action: 'accept' | 'reject';
+  reason?: string;
+}
+
+export interface VersionLine extends TaxDetails, MileageDetails, PerDiemDetails {
+  line_no: number;
+  category: ReimbursementCategory;
+  description: string;
+  amount: Money;
+  currency: string;
+  original_amount: Money;
+  expense_date: string;
+  receipt_url: string;
+}
+
+export interface ReimbursementVersion {
+  id: number;
+  reimbursement_id: number;
+  version: number;
+  name: string;
+  title: string;
+  description: string;
+  category: ReimbursementCategory;
+  amount: Money;
+  receipt_url: string;
+  lines: VersionLine[];
+  allocations: VersionAllocation[];
+  edited_by?: number;
+  created_at: string;
+}
+
+export interface FieldChange {
+  field: string;
+  from: string | number | VersionLine[];
+  to: string | number | VersionLine[];
+}
+
+export interface VersionDiff {
+  reimbursement_id: number;
+  from: number;
+  to: number;
+  changes: FieldChange[];
+}
+
+export interface MarkPaidRequest {
+  payment_date: string;
+  method: PaymentMethod;
+  reference: string;
+}
+
+export interface Approval {
+  id: number;
+  reimbursement_id: number;
+  step_order: number;
+  step_name: string;
+  approver_id?: number;
+  on_behalf_of_id?: number;
+  action: 'approve' | 'reject' | 'revise';
+  notes?: string;
+  approved_amount?: Money;
+  adjustment_reason?: string;
+  version?: number;
+  created_at: string;
+}
+
 export interface LoginRequest {
   username: string;
   password: string;
@@ -61,25 +311,60 @@ export interface LoginResponse {
   user: User;
 }
 
-export interface CreateReimbursementRequest {
+export interface CreateReimbursementRequest extends TaxDetails, MileageDetails, PerDiemDetails {
   title: string;
   description: string;
-  category: ReimbursementCategory;
-  amount: number;
-  receipt_url: string;
+  category?: ReimbursementCategory;
+  amount?: Money;
+  currency?: string;
+  expense_date?: string;
+  receipt_url?: string;
+  lines?: CreateLineRequest[];
+  allocations?: AllocationRequest[];
+  advance_id?: number;
 }
 
-export interface UpdateReimbursementRequest {
+export interface UpdateReimbursementRequest extends TaxDetails, MileageDetails, PerDiemDetails {
   title?: string;
   description?: string;
   category?: ReimbursementCategory;
-  amount?: number;
+  amount?: Money;
+  currency?: string;
+  expense_date?: string;
   receipt_url?: string;
+  lines?: CreateLineRequest[];
+  allocations?: AllocationRequest[];
 }
 
 export interface ApprovalRequest {
-  action: 'approve' | 'reject';
+  action: 'approve' | 'reject' | 'revise';
   notes?: string;
+  approved_amount?: Money;
+  justification?: string;
+  lines?: LineDecision[];
+  version: number;
+}
+
+export interface BulkApprovalRequest {
+  ids: number[];
+  action: 'approve' | 'reject' | 'revise';
+  notes?: string;
+  item_notes?: Record<number, string>;
+  versions: Record<number, number>;
+}
+
+export interface BulkApprovalResult {
+  id: number;
+  success: boolean;
+  status: number;
+  error?: string;
+  reimbursement?: Reimbursement;
+}
+
+export interface BulkApprovalResponse {
+  results: BulkApprovalResult[];
+  succeeded: number;
+  failed: number;
 }
 
 export interface ReimbursementStats {
@@ -87,7 +372,295 @@ export interface ReimbursementStats {
   total_approved: number;
   total_rejected: number;
   total_pending: number;
-  total_amount: number;
+  total_needs_revision: number;
+  total_awaiting_payment: number;
+  total_paid: number;
+  total_amount: Money;
+  total_claimed_amount: Money;
+  currency: string;
+}
+
+export interface ExchangeRate {
+  id: number;
+  currency: string;
+  rate_date: string;
+  rate: number;
+  created_by?: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface TaxReportRow {
+  month: string;
+  category: ReimbursementCategory;
+  lines: number;
+  net_amount: Money;
+  tax_amount: Money;
+}
+
+export interface TaxReport {
+  from?: string;
+  to?: string;
+  currency: string;
+  rows: TaxReportRow[];
+  total_net_amount: Money;
+  total_tax_amount: Money;
+}
+
+export interface AllocationReportRow {
+  month: string;
+  cost_center_code: string | null;
+  cost_center_name?: string;
+  project_code?: string;
+  project_name?: string;
+  claims: number;
+  amount: Money;
+}
+
+export interface AllocationReport {
+  from?: string;
+  to?: string;
+  currency: string;
+  rows: AllocationReportRow[];
+  total_amount: Money;
+}
+
+export type AdvanceStatus = 'pending' | 'approved' | 'rejected' | 'disbursed' | 'settled' | 'cancelled';
+
+export interface AdvanceEvent {
+  id: number;
+  advance_id: number;
+  action: string;
+  actor_id?: number;
+  actor_role?: UserRole;
+  from_status?: AdvanceStatus;
+  to_status?: AdvanceStatus;
+  notes?: string;
+  ip_address?: string;
+  user_agent?: string;
+  created_at: string;
+}
+
+export interface AdvanceClaim {
+  id: number;
+  title: string;
+  status: ReimbursementStatus;
+  expense_date?: string;
+  payable_amount: Money;
+}
+
+// Balance is amount less claimed_amount; until the advance is settled the
+// return and top-up are what a settlement would record today
+export interface CashAdvance {
+  id: number;
+  employee_id: number;
+  employee_name: string;
+  purpose: string;
+  amount: Money;
+  status: AdvanceStatus;
+  approver_id?: number;
+  decided_by?: number;
+  decided_at?: string;
+  decision_notes?: string;
+  disbursed_date?: string;
+  disbursed_by?: number;
+  disbursement_method?: PaymentMethod;
+  disbursement_reference?: string;
+  settled_date?: string;
+  settled_by?: number;
+  settlement_method?: PaymentMethod;
+  settlement_reference?: string;
+  cancelled_at?: string;
+  created_at: string;
+  updated_at: string;
+  claimed_amount: Money;
+  balance: Money;
+  return_amount: Money;
+  top_up_amount: Money;
+  claims?: AdvanceClaim[];
+}
+
+export interface CreateAdvanceRequest {
+  purpose: string;
+  amount: Money;
+}
+
+export interface DisburseAdvanceRequest {
+  disbursed_date: string;
+  method: PaymentMethod;
+  reference: string;
+}
+
+export interface SettleAdvanceRequest {
+  settled_date: string;
+  method?: PaymentMethod;
+  reference?: string;
+}
+
+export interface AdvanceReportRow {
+  id: number;
+  employee_id: number;
+  employee_name: string;
+  purpose: string;
+  disbursed_date: string;
+  days_open: number;
+  amount: Money;
+  claimed_amount: Money;
+  in_approval: Money;
+  balance: Money;
+}
+
+export interface AdvanceReport {
+  currency: string;
+  rows: AdvanceReportRow[];
+  total_amount: Money;
+  total_claimed: Money;
+  total_balance: Money;
+}
+
+export interface MileageRate {
+  id: number;
+  vehicle_type: VehicleType;
+  rate_per_km: Money;
+  effective_from: string;
+  created_by?: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PerDiemRate {
+  id: number;
+  location: string;
+  daily_rate: Money;
+  breakfast_percent: number;
+  lunch_percent: number;
+  dinner_percent: number;
+  effective_from: string;
+  created_by?: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface SavePerDiemRateRequest {
+  location: string;
+  daily_rate: Money;
+  breakfast_percent: number;
+  lunch_percent: number;
+  dinner_percent: number;
+  effective_from: string;
+}
+
+export interface SaveMileageRateRequest {
+  vehicle_type: VehicleType;
+  rate_per_km: Money;
+  effective_from: string;
+}
+
+export interface CostCenter {
+  id: number;
+  code: string;
+  name: string;
+  owner_id?: number;
+  active: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreateCostCenterRequest {
+  code: string;
+  name: string;
+  owner_id?: number;
+}
+
+export interface UpdateCostCenterRequest {
+  name?: string;
+  owner_id?: number;
+  active?: boolean;
+}
+
+export interface Project {
+  id: number;
+  code: string;
+  name: string;
+  client?: string;
+  cost_center_id?: number;
+  active: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreateProjectRequest {
+  code: string;
+  name: string;
+  client?: string;
+  cost_center_id?: number;
+}
+
+export interface UpdateProjectRequest {
+  name?: string;
+  client?: string;
+  cost_center_id?: number;
+  active?: boolean;
+}
+
+// Part of a claim charged to a cost center and project
+export interface Allocation {
+  id: number;
+  reimbursement_id: number;
+  cost_center_id: number;
+  cost_center_code: string;
+  project_id?: number;
+  project_code?: string;
+  percent?: number;
+  amount: Money;
+}
+
+// Give either percent or amount, the same for every allocation of a claim
+export interface AllocationRequest {
+  cost_center_id?: number;
+  project_id?: number;
+  percent?: number;
+  amount?: Money;
+}
+
+export interface VersionAllocation {
+  cost_center_id: number;
+  project_id?: number;
+  percent?: number;
+  amount: Money;
+}
+
+export interface Category {
+  code: ReimbursementCategory;
+  display_name: string;
+  local_display_name?: string;
+  gl_account?: string;
+  receipt_required: boolean;
+  active: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreateCategoryRequest {
+  code: string;
+  display_name: string;
+  local_display_name?: string;
+  gl_account?: string;
+  receipt_required?: boolean;
+}
+
+export interface UpdateCategoryRequest {
+  display_name?: string;
+  local_display_name?: string;
+  gl_account?: string;
+  receipt_required?: boolean;
+  active?: boolean;
+}
+
+export interface SaveExchangeRateRequest {
+  currency: string;
+  rate_date: string;
+  rate: number;
 }
 
 // API Error
@@ -200,12 +773,40 @@ export const uploadAPI = {
 
     return response.json();
   },
+
+  uploadAttachment: async (file: File): Promise<{ url: string; filename: string; size: number }> => {
+    const token = getAuthToken();
+    const formData = new FormData();
+    formData.append('attachment', file);
+
+    const headers: Record<string, string> = {};
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+
+    const response = await fetch(`${API_BASE_URL}/upload/attachment`, {
+      method: 'POST',
+      headers,
+      body: formData,
+    });
+
+    if (!response.ok) {
+      const error = await response.json().catch(() => ({ error: 'Upload failed' }));
+      throw new APIError(response.status, error.error || 'Upload failed');
+    }
+
+    return response.json();
+  },
 };
 
 // Reimbursement API
 export const reimbursementAPI = {
-  getAll: (): Promise<Reimbursement[]> => {
-    return apiRequest<Reimbursement[]>('/reimbursements');
+  getAll: (from?: string, to?: string): Promise<Reimbursement[]> => {
+    const params = new URLSearchParams();
+    if (from) params.set('from', from);
+    if (to) params.set('to', to);
+    const query = params.toString() ? `?${params}` : '';
+    return apiRequest<Reimbursement[]>(`/reimbursements${query}`);
   },
 
   getById: (id: number): Promise<Reimbursement> => {
@@ -226,25 +827,89 @@ export const reimbursementAPI = {
     });
   },
 
-  delete: (id: number): Promise<{ message: string }> => {
-    return apiRequest<{ message: string }>(`/reimbursements/${id}`, {
-      method: 'DELETE',
+  // Drafts may leave out required fields; update replaces a draft as a whole
+  saveDraft: (data: Partial<CreateReimbursementRequest>): Promise<Reimbursement> => {
+    return apiRequest<Reimbursement>('/reimbursements/drafts', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  submit: (id: number): Promise<Reimbursement> => {
+    return apiRequest<Reimbursement>(`/reimbursements/${id}/submit`, {
+      method: 'POST',
+    });
+  },
+
+  cancel: (id: number, reason?: string): Promise<Reimbursement> => {
+    return apiRequest<Reimbursement>(`/reimbursements/${id}/cancel`, {
+      method: 'POST',
+      body: JSON.stringify({ reason }),
     });
   },
 
   getStats: (): Promise<ReimbursementStats> => {
     return apiRequest<ReimbursementStats>('/reimbursements/stats');
   },
+
+  getPayments: (id: number): Promise<Payment[]> => {
+    return apiRequest<Payment[]>(`/reimbursements/${id}/payments`);
+  },
+
+  getHistory: (id: number): Promise<ReimbursementEvent[]> => {
+    return apiRequest<ReimbursementEvent[]>(`/reimbursements/${id}/history`);
+  },
+
+  getVersions: (id: number): Promise<ReimbursementVersion[]> => {
+    return apiRequest<ReimbursementVersion[]>(`/reimbursements/${id}/versions`);
+  },
+
+  getVersionDiff: (id: number, from?: number, to?: number): Promise<VersionDiff> => {
+    const params = new URLSearchParams();
+    if (from !== undefined) params.set('from', String(from));
+    if (to !== undefined) params.set('to', String(to));
+    const query = params.toString();
+    return apiRequest<VersionDiff>(`/reimbursements/${id}/versions/diff${query ? `?${query}` : ''}`);
+  },
+
+  getComments: (id: number): Promise<Comment[]> => {
+    return apiRequest<Comment[]>(`/reimbursements/${id}/comments`);
+  },
+
+  addComment: (id: number, data: CreateCommentRequest): Promise<Comment> => {
+    return apiRequest<Comment>(`/reimbursements/${id}/comments`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  getUnreadCommentCount: (id: number): Promise<{ reimbursement_id: number; unread: number }> => {
+    return apiRequest<{ reimbursement_id: number; unread: number }>(`/reimbursements/${id}/comments/unread-count`);
+  },
+
+  resubmit: (id: number): Promise<Reimbursement> => {
+    return apiRequest<Reimbursement>(`/reimbursements/${id}/resubmit`, {
+      method: 'POST',
+    });
+  },
 };
 
 // Manager API
 export const managerAPI = {
-  getPending: (): Promise<Reimbursement[]> => {
-    return apiRequest<Reimbursement[]>('/manager/pending');
+  getPending: (overdue?: boolean): Promise<Reimbursement[]> => {
+    const query = overdue === undefined ? '' : `?overdue=${overdue}`;
+    return apiRequest<Reimbursement[]>(`/manager/pending${query}`);
   },
 
   approve: (id: number, data: ApprovalRequest): Promise<Reimbursement> => {
-    return apiRequest<Reimbursement>(`/manager/reimbursements/${id}/approve`, {
+    return apiRequest<Reimbursement>(`/reimbursements/${id}/approve`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  bulkApprove: (data: BulkApprovalRequest): Promise<BulkApprovalResponse> => {
+    return apiRequest<BulkApprovalResponse>('/reimbursements/bulk-approve', {
       method: 'POST',
       body: JSON.stringify(data),
     });
@@ -253,23 +918,314 @@ export const managerAPI = {
 
 // Finance API
 export const financeAPI = {
-  getPending: (): Promise<Reimbursement[]> => {
-    return apiRequest<Reimbursement[]>('/finance/pending');
+  getPending: (overdue?: boolean): Promise<Reimbursement[]> => {
+    const query = overdue === undefined ? '' : `?overdue=${overdue}`;
+    return apiRequest<Reimbursement[]>(`/finance/pending${query}`);
   },
 
   approve: (id: number, data: ApprovalRequest): Promise<Reimbursement> => {
-    return apiRequest<Reimbursement>(`/finance/reimbursements/${id}/approve`, {
+    return apiRequest<Reimbursement>(`/reimbursements/${id}/approve`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  bulkApprove: (data: BulkApprovalRequest): Promise<BulkApprovalResponse> => {
+    return apiRequest<BulkApprovalResponse>('/reimbursements/bulk-approve', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  getAwaitingPayment: (): Promise<Reimbursement[]> => {
+    return apiRequest<Reimbursement[]>('/finance/awaiting-payment');
+  },
+
+  markPaid: (id: number, data: MarkPaidRequest): Promise<Payment> => {
+    return apiRequest<Payment>(`/finance/reimbursements/${id}/pay`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  reversePayment: (id: number, reason: string): Promise<Reimbursement> => {
+    return apiRequest<Reimbursement>(`/finance/reimbursements/${id}/reverse-payment`, {
+      method: 'POST',
+      body: JSON.stringify({ reason }),
+    });
+  },
+
+  getTaxReport: (from?: string, to?: string): Promise<TaxReport> => {
+    const params = new URLSearchParams();
+    if (from) params.set('from', from);
+    if (to) params.set('to', to);
+    const query = params.toString() ? `?${params}` : '';
+    return apiRequest<TaxReport>(`/finance/reports/tax${query}`);
+  },
+
+  getAllocationReport: (from?: string, to?: string): Promise<AllocationReport> => {
+    const params = new URLSearchParams();
+    if (from) params.set('from', from);
+    if (to) params.set('to', to);
+    const query = params.toString() ? `?${params}` : '';
+    return apiRequest<AllocationReport>(`/finance/reports/allocations${query}`);
+  },
+
+  getAdvanceReport: (): Promise<AdvanceReport> => {
+    return apiRequest<AdvanceReport>('/finance/reports/advances');
+  },
+};
+
+// Cash advances API
+export const advanceAPI = {
+  getAll: (status?: AdvanceStatus): Promise<CashAdvance[]> => {
+    const query = status ? `?status=${status}` : '';
+    return apiRequest<CashAdvance[]>(`/cash-advances${query}`);
+  },
+
+  getById: (id: number): Promise<CashAdvance> => {
+    return apiRequest<CashAdvance>(`/cash-advances/${id}`);
+  },
+
+  getHistory: (id: number): Promise<AdvanceEvent[]> => {
+    return apiRequest<AdvanceEvent[]>(`/cash-advances/${id}/history`);
+  },
+
+  create: (data: CreateAdvanceRequest): Promise<CashAdvance> => {
+    return apiRequest<CashAdvance>('/cash-advances', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  cancel: (id: number): Promise<{ message: string }> => {
+    return apiRequest<{ message: string }>(`/cash-advances/${id}/cancel`, {
+      method: 'POST',
+    });
+  },
+
+  decide: (id: number, action: 'approve' | 'reject', notes?: string): Promise<CashAdvance> => {
+    return apiRequest<CashAdvance>(`/cash-advances/${id}/approve`, {
+      method: 'POST',
+      body: JSON.stringify({ action, notes }),
+    });
+  },
+
+  disburse: (id: number, data: DisburseAdvanceRequest): Promise<CashAdvance> => {
+    return apiRequest<CashAdvance>(`/finance/cash-advances/${id}/disburse`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  settle: (id: number, data: SettleAdvanceRequest): Promise<CashAdvance> => {
+    return apiRequest<CashAdvance>(`/finance/cash-advances/${id}/settle`, {
       method: 'POST',
       body: JSON.stringify(data),
     });
   },
 };
 
+// Exchange rates API
+export const exchangeRateAPI = {
+  getAll: (currency?: string): Promise<ExchangeRate[]> => {
+    const query = currency ? `?currency=${encodeURIComponent(currency)}` : '';
+    return apiRequest<ExchangeRate[]>(`/exchange-rates${query}`);
+  },
+
+  save: (data: SaveExchangeRateRequest): Promise<ExchangeRate> => {
+    return apiRequest<ExchangeRate>('/exchange-rates', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  importCSV: async (file: File): Promise<{ imported: number }> => {
+    const token = getAuthToken();
+    const formData = new FormData();
+    formData.append('file', file);
+
+    const headers: Record<string, string> = {};
+    if (token) {
+      headers['Authorization'] = `Bearer ${token}`;
+    }
+
+    const response = await fetch(`${API_BASE_URL}/exchange-rates/import`, {
+      method: 'POST',
+      headers,
+      body: formData,
+    });
+
+    if (!response.ok) {
+      const error = await response.json().catch(() => ({ error: 'Import failed' }));
+      const details = Array.isArray(error.details) ? `: ${error.details.join('; ')}` : '';
+      throw new APIError(response.status, (error.error || 'Import failed') + details);
+    }
+
+    return response.json();
+  },
+
+  delete: (id: number): Promise<{ message: string }> => {
+    return apiRequest<{ message: string }>(`/exchange-rates/${id}`, {
+      method: 'DELETE',
+    });
+  },
+};
+
+// Mileage and per-diem rates API
+export const mileageRateAPI = {
+  getAll: (): Promise<MileageRate[]> => {
+    return apiRequest<MileageRate[]>('/mileage-rates');
+  },
+
+  save: (data: SaveMileageRateRequest): Promise<MileageRate> => {
+    return apiRequest<MileageRate>('/mileage-rates', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  delete: (id: number): Promise<{ message: string }> => {
+    return apiRequest<{ message: string }>(`/mileage-rates/${id}`, {
+      method: 'DELETE',
+    });
+  },
+};
+
+export const perDiemRateAPI = {
+  getAll: (): Promise<PerDiemRate[]> => {
+    return apiRequest<PerDiemRate[]>('/per-diem-rates');
+  },
+
+  save: (data: SavePerDiemRateRequest): Promise<PerDiemRate> => {
+    return apiRequest<PerDiemRate>('/per-diem-rates', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  delete: (id: number): Promise<{ message: string }> => {
+    return apiRequest<{ message: string }>(`/per-diem-rates/${id}`, {
+      method: 'DELETE',
+    });
+  },
+};
+
+// Categories, cost centers and projects API
+export const categoryAPI = {
+  getAll: (all?: boolean): Promise<Category[]> => {
+    return apiRequest<Category[]>(all ? '/categories?all=true' : '/categories');
+  },
+
+  create: (data: CreateCategoryRequest): Promise<Category> => {
+    return apiRequest<Category>('/categories', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  update: (code: string, data: UpdateCategoryRequest): Promise<Category> => {
+    return apiRequest<Category>(`/categories/${code}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  },
+
+  deactivate: (code: string): Promise<{ message: string }> => {
+    return apiRequest<{ message: string }>(`/categories/${code}`, {
+      method: 'DELETE',
+    });
+  },
+};
+
+export const costCenterAPI = {
+  getAll: (all?: boolean): Promise<CostCenter[]> => {
+    return apiRequest<CostCenter[]>(all ? '/cost-centers?all=true' : '/cost-centers');
+  },
+
+  create: (data: CreateCostCenterRequest): Promise<CostCenter> => {
+    return apiRequest<CostCenter>('/cost-centers', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  update: (id: number, data: UpdateCostCenterRequest): Promise<CostCenter> => {
+    return apiRequest<CostCenter>(`/cost-centers/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  },
+
+  deactivate: (id: number): Promise<{ message: string }> => {
+    return apiRequest<{ message: string }>(`/cost-centers/${id}`, {
+      method: 'DELETE',
+    });
+  },
+};
+
+export const projectAPI = {
+  getAll: (all?: boolean): Promise<Project[]> => {
+    return apiRequest<Project[]>(all ? '/projects?all=true' : '/projects');
+  },
+
+  create: (data: CreateProjectRequest): Promise<Project> => {
+    return apiRequest<Project>('/projects', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  },
+
+  update: (id: number, data: UpdateProjectRequest): Promise<Project> => {
+    return apiRequest<Project>(`/projects/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  },
+
+  deactivate: (id: number): Promise<{ message: string }> => {
+    return apiRequest<{ message: string }>(`/projects/${id}`, {
+      method: 'DELETE',
+    });
+  },
+};
+
+// Separation-of-duties API
+export const dutyRuleAPI = {
+  getAll: (): Promise<DutyRule[]> => {
+    return apiRequest<DutyRule[]>('/duty-rules');
+  },
+
+  setEnabled: (code: string, enabled: boolean): Promise<{ message: string }> => {
+    return apiRequest<{ message: string }>(`/duty-rules/${code}`, {
+      method: 'PUT',
+      body: JSON.stringify({ enabled }),
+    });
+  },
+
+  getViolations: (): Promise<DutyViolation[]> => {
+    return apiRequest<DutyViolation[]>('/finance/duty-violations');
+  },
+};
+
+// Notifications API
+export const notificationAPI = {
+  getMine: (): Promise<Notification[]> => {
+    return apiRequest<Notification[]>('/notifications');
+  },
+};
+
 // Admin API
 export const adminAPI = {
   getAllUsers: (): Promise<User[]> => {
     return apiRequest<User[]>('/users');
   },
+
+  purgeReimbursement: (id: number): Promise<{ message: string }> => {
+    return apiRequest<{ message: string }>(`/admin/reimbursements/${id}`, {
+      method: 'DELETE',
+    });
+  },
 };
 
 // Health check
@@ -4,3 +4,14 @@ import { twMerge } from 'tailwind-merge'
 export function cn(...inputs: ClassValue[]) {
   return twMerge(clsx(inputs))
 }
+
+// formatMoney formats an API amount (a decimal string such as "1250000.50")
+// for display in id-ID style, without going through a float.
+export function formatMoney(amount: string | undefined): string {
+  if (!amount) return '0'
+  const negative = amount.startsWith('-')
+  const [whole, fraction = ''] = amount.replace('-', '').split('.')
+  const grouped = whole.replace(/\B(?=(\d{3})+(?!\d))/g, '.')
+  const cents = fraction.replace(/0+$/, '')
+  return `${negative ? '-' : ''}${grouped}${cents ? `,${cents.padEnd(2, '0')}` : ''}`
+}